@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,24 +23,291 @@ type Address struct {
 	Country string `json:"country"`
 }
 
+// Service is a delivery service offered by Mailform.
+// The enum and the domestic-only rule on MailSpec list the services in provider.MailformServices, which a test checks.
+// +kubebuilder:validation:Enum=FEDEX_OVERNIGHT;USPS_PRIORITY_EXPRESS;USPS_PRIORITY;USPS_CERTIFIED_PHYSICAL_RECEIPT;USPS_CERTIFIED_RECEIPT;USPS_CERTIFIED;USPS_FIRST_CLASS;USPS_STANDARD;USPS_POSTCARD
+type Service string
+
+const (
+	// ServiceFedexOvernight is FedEx overnight delivery within the US.
+	ServiceFedexOvernight Service = "FEDEX_OVERNIGHT"
+	// ServiceUSPSPriorityExpress is USPS Priority Mail Express.
+	ServiceUSPSPriorityExpress Service = "USPS_PRIORITY_EXPRESS"
+	// ServiceUSPSPriority is USPS Priority Mail.
+	ServiceUSPSPriority Service = "USPS_PRIORITY"
+	// ServiceUSPSCertifiedPhysicalReceipt is USPS Certified Mail with a physical return receipt.
+	ServiceUSPSCertifiedPhysicalReceipt Service = "USPS_CERTIFIED_PHYSICAL_RECEIPT"
+	// ServiceUSPSCertifiedReceipt is USPS Certified Mail with an electronic return receipt.
+	ServiceUSPSCertifiedReceipt Service = "USPS_CERTIFIED_RECEIPT"
+	// ServiceUSPSCertified is USPS Certified Mail.
+	ServiceUSPSCertified Service = "USPS_CERTIFIED"
+	// ServiceUSPSFirstClass is USPS First-Class Mail.
+	ServiceUSPSFirstClass Service = "USPS_FIRST_CLASS"
+	// ServiceUSPSStandard is USPS Marketing Mail within the US.
+	ServiceUSPSStandard Service = "USPS_STANDARD"
+	// ServiceUSPSPostcard mails a postcard with USPS.
+	ServiceUSPSPostcard Service = "USPS_POSTCARD"
+)
+
+// OrderLostPolicy is what to do when a Mail's order no longer exists in Mailform.
+// +kubebuilder:validation:Enum=GiveUp;Recreate
+type OrderLostPolicy string
+
+const (
+	// OrderLostPolicyGiveUp marks the Mail as lost and stops reconciling it.
+	OrderLostPolicyGiveUp OrderLostPolicy = "GiveUp"
+	// OrderLostPolicyRecreate places a new order for the Mail.
+	OrderLostPolicyRecreate OrderLostPolicy = "Recreate"
+)
+
+// Envelope is a window envelope that a cover page is laid out for.
+// +kubebuilder:validation:Enum=US10;C5;DL
+type Envelope string
+
+const (
+	// EnvelopeUS10 is a US #10 double window envelope with a US Letter page.
+	EnvelopeUS10 Envelope = "US10"
+	// EnvelopeC5 is a C5 window envelope with an A4 page.
+	EnvelopeC5 Envelope = "C5"
+	// EnvelopeDL is a DL window envelope with an A4 page.
+	EnvelopeDL Envelope = "DL"
+)
+
+// CoverPage is an address page added in front of the document for window envelopes.
+type CoverPage struct {
+	// Envelope the page is laid out for. Defaults to US10 for mail to the US and DL otherwise.
+	// +optional
+	Envelope Envelope `json:"envelope,omitempty"`
+	// QRCode adds a QR code of the Mail's UID and customerReference to identify returned mail.
+	// +optional
+	QRCode bool `json:"qrCode,omitempty"`
+}
+
+// BodyFormat is the markup a letter body is written in.
+// +kubebuilder:validation:Enum=Markdown;Text;HTML
+type BodyFormat string
+
+const (
+	// BodyFormatMarkdown supports headings, paragraphs, emphasis, code, links, lists and rules.
+	BodyFormatMarkdown BodyFormat = "Markdown"
+	// BodyFormatText is plain text, laid out line by line.
+	BodyFormatText BodyFormat = "Text"
+	// BodyFormatHTML is the subset of HTML that Markdown supports.
+	BodyFormatHTML BodyFormat = "HTML"
+)
+
+// BodyFont is the standard font family a letter body is set in.
+// +kubebuilder:validation:Enum=Helvetica;Times;Courier
+type BodyFont string
+
+// PageSize is the size of the pages a letter body is rendered on.
+// +kubebuilder:validation:Enum=Letter;A4
+type PageSize string
+
+// LetterheadAlign is how the letterhead is aligned.
+// +kubebuilder:validation:Enum=Left;Center;Right
+type LetterheadAlign string
+
+// Letterhead is printed at the top of the first page of a letter body.
+type Letterhead struct {
+	// Lines of the letterhead, e.g. the sender's name and address. The first line is printed larger and in bold.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=8
+	Lines []string `json:"lines"`
+	// Align defaults to Left.
+	// +optional
+	Align LetterheadAlign `json:"align,omitempty"`
+}
+
+// Body is a letter written in the Mail that the operator renders to a PDF.
+type Body struct {
+	// Content of the letter.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=262144
+	Content string `json:"content"`
+	// Format of the content. Defaults to Markdown.
+	// +optional
+	Format BodyFormat `json:"format,omitempty"`
+	// Letterhead is printed at the top of the first page above a rule.
+	// +optional
+	Letterhead *Letterhead `json:"letterhead,omitempty"`
+	// Font defaults to Helvetica. Code is always set in Courier.
+	// +optional
+	Font BodyFont `json:"font,omitempty"`
+	// FontSize in points. Defaults to 11.
+	// +kubebuilder:validation:Minimum=6
+	// +kubebuilder:validation:Maximum=24
+	// +optional
+	FontSize int32 `json:"fontSize,omitempty"`
+	// MarginPoints is the margin on every side of the page in points. Defaults to 72, one inch.
+	// +kubebuilder:validation:Minimum=18
+	// +kubebuilder:validation:Maximum=144
+	// +optional
+	MarginPoints int32 `json:"marginPoints,omitempty"`
+	// PageSize defaults to Letter for mail to the US and A4 otherwise, or the page size of the cover page's envelope.
+	// +optional
+	PageSize PageSize `json:"pageSize,omitempty"`
+}
+
+// DocumentSource is one of the documents merged into the mailing. Exactly one source must be set.
+// +kubebuilder:validation:XValidation:rule="[has(self.url), has(self.configMapRef), has(self.secretRef), has(self.template)].filter(x, x).size() == 1",message="exactly one of url, configMapRef, secretRef or template must be set"
+type DocumentSource struct {
+	// URL of a PDF document.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// ConfigMapRef selects a PDF document stored in a ConfigMap in the Mail's namespace.
+	// The document is read from binaryData, falling back to data.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+	// SecretRef selects a PDF document stored in a Secret in the Mail's namespace.
+	// +optional
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+	// Template is a Go template rendered as plain text pages. The Mail is the template's data, e.g. {{ .Spec.To.Name }}.
+	// +optional
+	Template string `json:"template,omitempty"`
+}
+
+// Check is a check drawn on one of the account's Mailform bank accounts.
+type Check struct {
+	// BankAccount is the identifier of the bank account the check is drawn on.
+	// +kubebuilder:validation:MinLength=1
+	BankAccount string `json:"bankAccount"`
+	// AmountCents is the amount of the check in cents.
+	// +kubebuilder:validation:Minimum=1
+	AmountCents int32 `json:"amountCents"`
+	// Name is who the check is payable to.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Number of the check.
+	// +kubebuilder:validation:Minimum=1
+	Number int32 `json:"number"`
+	// Memo is printed on the memo line of the check.
+	// +optional
+	Memo string `json:"memo,omitempty"`
+}
+
 // MailSpec defines the desired state of Mail
+// +kubebuilder:validation:XValidation:rule="has(self.adoptOrderID) || has(self.to)",message="to is required unless adopting an existing order",fieldPath=".to",reason=FieldValueRequired
+// +kubebuilder:validation:XValidation:rule="has(self.adoptOrderID) || has(self.from)",message="from is required unless adopting an existing order",fieldPath=".from",reason=FieldValueRequired
+// +kubebuilder:validation:XValidation:rule="!has(self.to) || !(self.service in ['FEDEX_OVERNIGHT', 'USPS_CERTIFIED_PHYSICAL_RECEIPT', 'USPS_CERTIFIED_RECEIPT', 'USPS_CERTIFIED', 'USPS_STANDARD']) || self.to.country.upperAscii() == 'US'",message="service only delivers within the US",fieldPath=".to.country"
+// +kubebuilder:validation:XValidation:rule="has(self.adoptOrderID) == has(oldSelf.adoptOrderID) && (!has(self.adoptOrderID) || self.adoptOrderID == oldSelf.adoptOrderID)",message="adoptOrderID is immutable"
 type MailSpec struct {
 	FilePath          string `json:"filePath,omitempty"`
 	URL               string `json:"url,omitempty"`
 	CustomerReference string `json:"customerReference,omitempty"`
+	// Service is the delivery service used to mail the document.
 	// +kubebuilder:validation:Required
-	Service string `json:"service"`
+	Service Service `json:"service"`
+	// Webhook receives notifications about updates to the order.
 	Webhook string `json:"webhook,omitempty"`
+	// Company the order is associated with.
 	Company string `json:"company,omitempty"`
-	Simplex bool   `json:"simplex,omitempty"`
-	Color   bool   `json:"color,omitempty"`
-	Flat    bool   `json:"flat,omitempty"`
-	Stamp   bool   `json:"stamp,omitempty"`
+	// Simplex prints the document one page to a sheet instead of on both sides.
+	Simplex bool `json:"simplex,omitempty"`
+	// Color prints the document in color instead of black and white.
+	Color bool `json:"color,omitempty"`
+	// Flat mails the document in a flat envelope instead of folded.
+	Flat bool `json:"flat,omitempty"`
+	// Stamp mails the document with a real postage stamp instead of metered postage or an imprint.
+	Stamp bool `json:"stamp,omitempty"`
+	// Message is printed on the non-picture side of a postcard.
 	Message string `json:"message,omitempty"`
-	// +kubebuilder:validation:Required
+	// Check is printed and mailed with the document. Checks can't be mailed with postcards.
+	// +optional
+	Check *Check `json:"check,omitempty"`
+	// To is required unless adopting an existing order.
+	// +optional
 	To *Address `json:"to,omitempty"`
-	// +kubebuilder:validation:Required
+	// From is required unless adopting an existing order.
+	// +optional
 	From *Address `json:"from,omitempty"`
+	// ConfigMapRef selects a PDF document stored in a ConfigMap in the Mail's namespace instead of a url or filePath.
+	// The document is read from binaryData, falling back to data.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+	// Documents are merged in order into a single document to mail instead of a url, filePath or configMapRef.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Documents []DocumentSource `json:"documents,omitempty"`
+	// Body is a letter rendered to a PDF by the operator instead of a url, filePath, configMapRef or documents.
+	// +optional
+	Body *Body `json:"body,omitempty"`
+	// DuplexAlignment adds a blank page after each of the documents with an odd number of pages,
+	// so every document starts on a new sheet when printed double-sided. Ignored when simplex is set.
+	// +optional
+	DuplexAlignment bool `json:"duplexAlignment,omitempty"`
+	// CoverPage adds a page in front of the document with the to and from addresses positioned for a window envelope.
+	// +optional
+	CoverPage *CoverPage `json:"coverPage,omitempty"`
+	// DocumentSHA256 pins the document to the hex encoded SHA-256 of its content.
+	// The document is verified against it before the order is created and the verified content is uploaded
+	// to Mailform instead of the url, so the document can't change between review and sending.
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	// +optional
+	DocumentSHA256 string `json:"documentSHA256,omitempty"`
+	// AdoptOrderID is the ID of an existing Mailform order to manage instead of creating a new one.
+	// The order's status is synced and it is cancelled on delete like any other order.
+	// An order can only be adopted by a single Mail.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	AdoptOrderID string `json:"adoptOrderID,omitempty"`
+	// OrderLostPolicy is what to do if the order can no longer be found in Mailform.
+	// Adopted orders can't be recreated so they always give up.
+	// Overrides the manager-wide default.
+	// +optional
+	OrderLostPolicy OrderLostPolicy `json:"orderLostPolicy,omitempty"`
+	// SyncIntervalSeconds is how often the order is checked for updates.
+	// When set, it replaces the manager's adaptive polling for this Mail.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SyncIntervalSeconds *int32 `json:"syncIntervalSeconds,omitempty"`
+	// TTLSecondsAfterFinished limits the lifetime of a Mail once its order has been fulfilled or cancelled.
+	// When set, the Mail is deleted this many seconds after it finished.
+	// Overrides the manager-wide default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// DocumentStatus describes a verified document.
+type DocumentStatus struct {
+	// SHA256 is the hex encoded SHA-256 of the document.
+	SHA256 string `json:"sha256"`
+	// Pages is the number of pages in the document.
+	Pages int `json:"pages"`
+	// Size is the size of the document in bytes.
+	Size int64 `json:"size"`
+	// Parts describes each document in the merged document, starting with the cover page if there is one.
+	// +optional
+	Parts []DocumentPartStatus `json:"parts,omitempty"`
+	// Pinned is true when the document matched spec.documentSHA256 and its verified content was uploaded.
+	// +optional
+	Pinned bool `json:"pinned,omitempty"`
+	// ArchiveLocation is where a copy of the verified document was archived when the order was created.
+	// +optional
+	ArchiveLocation string `json:"archiveLocation,omitempty"`
+}
+
+// DocumentPartStatus describes one of the documents in the merged document.
+type DocumentPartStatus struct {
+	// Pages is the number of pages from the document.
+	Pages int `json:"pages"`
+	// BlankPages is the number of blank pages added after the document for duplex alignment.
+	// +optional
+	BlankPages int `json:"blankPages,omitempty"`
+}
+
+// PreviewStatus references the rendered preview of the mail piece.
+type PreviewStatus struct {
+	// ConfigMapName is the ConfigMap in the Mail's namespace that holds the preview.
+	ConfigMapName string `json:"configMapName"`
+	// Key is the key of the PNG in the ConfigMap's binaryData.
+	Key string `json:"key"`
+	// Pages is the number of pages in the preview.
+	Pages int `json:"pages"`
+	// SHA256 is the hex encoded SHA-256 of the document the preview was rendered from.
+	SHA256 string `json:"sha256"`
 }
 
 // MailStatus defines the observed state of Mail.
@@ -53,12 +321,29 @@ type MailStatus struct {
 	Modified           metav1.Time `json:"modified,omitempty"`
 	Cancelled          metav1.Time `json:"cancelled,omitempty"`
 	CancellationReason string      `json:"cancellationReason,omitempty"`
+	// ArchiveLocation is where the record of the finished mail and a copy of its document were archived.
+	// +optional
+	ArchiveLocation string `json:"archiveLocation,omitempty"`
+	// Document describes the document that was verified before the order was created.
+	// +optional
+	Document *DocumentStatus `json:"document,omitempty"`
+	// Preview references a PNG of the first pages of the verified document with the envelope windows outlined.
+	// +optional
+	Preview *PreviewStatus `json:"preview,omitempty"`
+	// ObservedResyncRequest is the value of the resync-requested annotation last handled by the controller.
+	// +optional
+	ObservedResyncRequest string `json:"observedResyncRequest,omitempty"`
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.service`
+// +kubebuilder:printcolumn:name="Country",type=string,JSONPath=`.spec.to.country`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Order ID",type=string,JSONPath=`.status.id`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Mail is the Schema for the mails API
 type Mail struct {
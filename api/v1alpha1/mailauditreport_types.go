@@ -0,0 +1,88 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OrphanedOrder is a Mailform order that isn't owned by any Mail.
+type OrphanedOrder struct {
+	ID                string      `json:"id"`
+	State             string      `json:"state,omitempty"`
+	CustomerReference string      `json:"customerReference,omitempty"`
+	Total             int         `json:"total,omitempty"`
+	Created           metav1.Time `json:"created,omitempty"`
+	// Cancelled is true if the order was cancelled by the auditor.
+	// +optional
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// DriftedMail is a Mail whose stored status disagrees with Mailform.
+type DriftedMail struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	OrderID   string `json:"orderID,omitempty"`
+	// Reason is why the Mail is considered drifted, e.g. StateMismatch, OrderNotFound or OrderIDLost.
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// MailAuditReportStatus defines the observed state of MailAuditReport.
+type MailAuditReportStatus struct {
+	// LastAuditTime is when the account was last audited.
+	// +optional
+	LastAuditTime metav1.Time `json:"lastAuditTime,omitempty"`
+	// OrdersAudited is the number of orders listed from the Mailform account.
+	// +optional
+	OrdersAudited int `json:"ordersAudited,omitempty"`
+	// MailAudited is the number of Mail objects compared against the account.
+	// +optional
+	MailAudited int `json:"mailAudited,omitempty"`
+	// OrphanCount is the number of orphaned orders.
+	// +optional
+	OrphanCount int `json:"orphanCount,omitempty"`
+	// DriftCount is the number of drifted Mail.
+	// +optional
+	DriftCount int `json:"driftCount,omitempty"`
+	// Orphans are unfinished orders in the account with no owning Mail.
+	// +optional
+	Orphans []OrphanedOrder `json:"orphans,omitempty"`
+	// Drifted are Mail whose stored status disagrees with Mailform.
+	// +optional
+	Drifted []DriftedMail `json:"drifted,omitempty"`
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Orphans",type=integer,JSONPath=`.status.orphanCount`
+// +kubebuilder:printcolumn:name="Drifted",type=integer,JSONPath=`.status.driftCount`
+// +kubebuilder:printcolumn:name="Last Audit",type=date,JSONPath=`.status.lastAuditTime`
+
+// MailAuditReport is the result of comparing Mail objects with the orders in the Mailform account.
+// It is written by the operator's auditor.
+type MailAuditReport struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// status defines the observed state of MailAuditReport
+	// +optional
+	Status MailAuditReportStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// MailAuditReportList contains a list of MailAuditReport
+type MailAuditReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MailAuditReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MailAuditReport{}, &MailAuditReportList{})
+}
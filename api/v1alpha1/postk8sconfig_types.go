@@ -0,0 +1,151 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeletionPolicy is what happens to an unfinished order when its Mail is deleted.
+// +kubebuilder:validation:Enum=Cancel;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyCancel cancels the order before the Mail is deleted.
+	DeletionPolicyCancel DeletionPolicy = "Cancel"
+	// DeletionPolicyOrphan leaves the order in Mailform when the Mail is deleted.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// Provider is a mail provider that orders are placed with.
+// +kubebuilder:validation:Enum=mailform
+type Provider string
+
+const (
+	// ProviderMailform places orders with mailform.io.
+	ProviderMailform Provider = "mailform"
+)
+
+// PollingConfig configures how often orders are checked for updates.
+type PollingConfig struct {
+	// SyncIntervalSeconds is the longest time between checks of an order.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SyncIntervalSeconds *int32 `json:"syncIntervalSeconds,omitempty"`
+	// MinSyncIntervalSeconds is how often new orders are checked.
+	// Zero, or at least syncIntervalSeconds, checks every order every syncIntervalSeconds.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSyncIntervalSeconds *int32 `json:"minSyncIntervalSeconds,omitempty"`
+	// SyncJitterPercent is how much each interval is randomly adjusted by.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=99
+	// +optional
+	SyncJitterPercent *int32 `json:"syncJitterPercent,omitempty"`
+}
+
+// DeletionConfig configures what happens to Mail once it finishes or is deleted.
+type DeletionConfig struct {
+	// Policy is the default for unfinished orders of deleted Mail.
+	// The skip-cancellation-on-delete annotation overrides it for a single Mail.
+	// +optional
+	Policy DeletionPolicy `json:"policy,omitempty"`
+	// TTLSecondsAfterFinished is the default time to keep fulfilled or cancelled Mail. Zero keeps them.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	// OrderLostPolicy is the default for Mail whose order can no longer be found.
+	// +optional
+	OrderLostPolicy OrderLostPolicy `json:"orderLostPolicy,omitempty"`
+}
+
+// ProviderConfig configures where orders are placed.
+type ProviderConfig struct {
+	// Name is the default provider for new orders.
+	// +optional
+	Name Provider `json:"name,omitempty"`
+}
+
+// RateLimitConfig configures how quickly requests are made to the provider.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the rate of requests shared by all Mail, e.g. 5 or 500m. Zero disables rate limiting.
+	// +optional
+	RequestsPerSecond *resource.Quantity `json:"requestsPerSecond,omitempty"`
+	// Burst is the number of requests that can be made at once.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst *int32 `json:"burst,omitempty"`
+}
+
+// FeatureConfig turns optional features on or off.
+type FeatureConfig struct {
+	// Archive records finished orders if an archive backend is configured.
+	// +optional
+	Archive *bool `json:"archive,omitempty"`
+	// KillSwitch halts new orders while the kill switch ConfigMap is set.
+	// +optional
+	KillSwitch *bool `json:"killSwitch,omitempty"`
+}
+
+// PostK8sConfigSpec defines the desired configuration of the operator.
+// Unset fields keep the values from the manager's flags.
+type PostK8sConfigSpec struct {
+	// +optional
+	Polling *PollingConfig `json:"polling,omitempty"`
+	// +optional
+	Deletion *DeletionConfig `json:"deletion,omitempty"`
+	// +optional
+	Provider *ProviderConfig `json:"provider,omitempty"`
+	// +optional
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+	// +optional
+	Features *FeatureConfig `json:"features,omitempty"`
+}
+
+// PostK8sConfigStatus defines the observed state of PostK8sConfig.
+type PostK8sConfigStatus struct {
+	// ObservedGeneration is the generation of the spec that was last applied or rejected.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Active is the configuration the operator is running with, including values from flags.
+	// +optional
+	Active *PostK8sConfigSpec `json:"active,omitempty"`
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PostK8sConfig is the runtime configuration of the operator.
+// Only the PostK8sConfig with the name the manager is configured with is applied.
+type PostK8sConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the desired configuration of the operator
+	// +optional
+	Spec PostK8sConfigSpec `json:"spec,omitempty,omitzero"`
+
+	// status defines the observed state of PostK8sConfig
+	// +optional
+	Status PostK8sConfigStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// PostK8sConfigList contains a list of PostK8sConfig
+type PostK8sConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PostK8sConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PostK8sConfig{}, &PostK8sConfigList{})
+}
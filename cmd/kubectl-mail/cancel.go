@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// runCancel deletes a Mail. The operator cancels the order before the Mail is removed unless it was already sent.
+func runCancel(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var waitForRemoval bool
+	var timeout time.Duration
+
+	fs := newFlagSet("cancel", "cancel NAME [flags]")
+	kube.bind(fs)
+	fs.BoolVar(&waitForRemoval, "wait", true, "Wait for the order to be cancelled and the Mail removed.")
+	fs.DurationVar(&timeout, "timeout", time.Minute, "How long to wait for the Mail to be removed.")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	name, err := requireOneName(fs, positional)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := kube.ns()
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	mail := &mailformv1alpha1.Mail{}
+
+	err = c.Get(ctx, key, mail)
+	if err != nil {
+		return err
+	}
+
+	if mail.Status.Sent {
+		return fmt.Errorf("mail/%s has already been sent and cannot be cancelled", name)
+	}
+
+	err = c.Delete(ctx, mail)
+	if err != nil {
+		return err
+	}
+
+	if waitForRemoval {
+		err = wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+			err := c.Get(ctx, key, &mailformv1alpha1.Mail{})
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			return fmt.Errorf("waiting for mail/%s to be removed: %w", name, err)
+		}
+	}
+
+	fmt.Fprintf(out, "mail/%s cancelled\n", name)
+
+	return nil
+}
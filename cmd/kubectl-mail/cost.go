@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// costSummary is the total cost of mail in a namespace.
+type costSummary struct {
+	namespace string
+	count     int
+	total     int
+}
+
+// runCost sums the cost of mail ordered within a time range.
+func runCost(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var allNamespaces bool
+	var since, until string
+
+	fs := newFlagSet("cost", "cost [flags]")
+	kube.bind(fs)
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "Sum mail in all namespaces.")
+	fs.BoolVar(&allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	fs.StringVar(&since, "since", "",
+		"Only include orders created after this time. Either a duration such as 720h or an RFC3339 timestamp.")
+	fs.StringVar(&until, "until", "",
+		"Only include orders created before this time. Either a duration such as 24h or an RFC3339 timestamp.")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	if len(positional) != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	now := time.Now()
+
+	sinceTime, err := parseTime(since, now)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+
+	untilTime, err := parseTime(until, now)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	namespace := ""
+	if !allNamespaces {
+		namespace, err = kube.ns()
+		if err != nil {
+			return err
+		}
+	}
+
+	mailList := &mailformv1alpha1.MailList{}
+	err = c.List(ctx, mailList, client.InNamespace(namespace))
+	if err != nil {
+		return err
+	}
+
+	return printCost(out, sumCost(mailList.Items, sinceTime, untilTime))
+}
+
+// parseTime parses either a duration relative to now or an RFC3339 timestamp. Empty values return the zero time.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// sumCost totals the cost of mail per namespace for orders created within [since, until).
+// Zero times leave that side of the range open.
+func sumCost(mails []mailformv1alpha1.Mail, since, until time.Time) []costSummary {
+	byNamespace := map[string]*costSummary{}
+
+	for _, mail := range mails {
+		// Nothing has been charged without an order
+		if mail.Status.ID == "" {
+			continue
+		}
+
+		created := mail.Status.Created.Time
+		if created.IsZero() {
+			created = mail.CreationTimestamp.Time
+		}
+
+		if !since.IsZero() && created.Before(since) {
+			continue
+		}
+
+		if !until.IsZero() && !created.Before(until) {
+			continue
+		}
+
+		summary, ok := byNamespace[mail.Namespace]
+		if !ok {
+			summary = &costSummary{namespace: mail.Namespace}
+			byNamespace[mail.Namespace] = summary
+		}
+
+		summary.count++
+		summary.total += mail.Status.Total
+	}
+
+	summaries := make([]costSummary, 0, len(byNamespace))
+	for _, summary := range byNamespace {
+		summaries = append(summaries, *summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].namespace < summaries[j].namespace
+	})
+
+	return summaries
+}
+
+// printCost writes a table of cost per namespace and the overall total to out.
+func printCost(out io.Writer, summaries []costSummary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "NAMESPACE\tORDERS\tTOTAL")
+
+	count, total := 0, 0
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", summary.namespace, summary.count, formatCents(summary.total))
+		count += summary.count
+		total += summary.total
+	}
+
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\n", count, formatCents(total))
+
+	return w.Flush()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/bulk"
+)
+
+// runImport creates a Mail for each row of a CSV or JSON file.
+// Every row is validated, and then checked by the API server with a dry run, before anything is created so a bad row
+// doesn't leave a partial import.
+func runImport(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var format, namePrefix, output string
+	var dryRun bool
+	var ttl int
+	template := &mailformv1alpha1.Mail{
+		Spec: mailformv1alpha1.MailSpec{
+			To:   &mailformv1alpha1.Address{},
+			From: &mailformv1alpha1.Address{},
+		},
+	}
+
+	fs := newFlagSet("import", "import FILE [--service SERVICE] [--url URL] [--from-* ...] [flags]")
+	kube.bind(fs)
+	specFlags(fs, &template.Spec, &ttl)
+	addressFlags(fs, "from", template.Spec.From)
+	fs.StringVar(&format, "format", "", "The format of FILE, one of csv or json. Detected from the extension if unset.")
+	fs.StringVar(&namePrefix, "name-prefix", bulk.DefaultNamePrefix,
+		"Prefix for the names of mail in rows without a name column, followed by the row number.")
+	fs.StringVar(&output, "output", "", "Print the Mail manifests instead of creating them. Only yaml is supported.")
+	fs.StringVar(&output, "o", "", "Shorthand for --output.")
+	fs.BoolVar(&dryRun, "dry-run", false, "Validate every row without creating any mail.")
+	fs.Usage = importUsage(fs)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	path, err := requireOneName(fs, positional)
+	if err != nil {
+		return err
+	}
+
+	if output != "" && output != "yaml" {
+		return fmt.Errorf("unsupported output %q, must be yaml", output)
+	}
+
+	if format == "" {
+		f, err := bulk.FormatFromPath(path)
+		if err != nil {
+			return fmt.Errorf("%w; set --format", err)
+		}
+		format = string(f)
+	}
+
+	namespace, err := kube.ns()
+	if err != nil {
+		return err
+	}
+
+	template.Namespace = namespace
+	setTTL(&template.Spec, ttl)
+
+	mails, err := readMails(path, bulk.Format(format), &bulk.Config{
+		Template:   template,
+		NamePrefix: namePrefix,
+	})
+	if err != nil {
+		var errs bulk.Errors
+		if errors.As(err, &errs) {
+			printReport(out, errs)
+			return fmt.Errorf("%d problem(s) found in %s", len(errs), path)
+		}
+		return err
+	}
+
+	switch {
+	case dryRun:
+		fmt.Fprintf(out, "%d mail validated\n", len(mails))
+		return nil
+	case output != "":
+		for i, mail := range mails {
+			if i > 0 {
+				fmt.Fprintln(out, "---")
+			}
+
+			err = printDryRun(out, mail, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	// The API server can still reject mail that passed validation, e.g. when its name is taken
+	rejected := 0
+	for _, mail := range mails {
+		err = c.Create(ctx, mail.DeepCopy(), client.DryRunAll)
+		if err != nil {
+			rejected++
+			fmt.Fprintf(out, "mail/%s rejected: %s\n", mail.Name, err)
+		}
+	}
+
+	if rejected > 0 {
+		return fmt.Errorf("%d mail rejected, none were created", rejected)
+	}
+
+	for _, mail := range mails {
+		err = c.Create(ctx, mail)
+		if err != nil {
+			return fmt.Errorf("creating mail/%s: %w", mail.Name, err)
+		}
+
+		fmt.Fprintf(out, "mail/%s created\n", mail.Name)
+	}
+
+	return nil
+}
+
+// readMails reads and validates the mail in an import file. "-" reads from stdin.
+func readMails(path string, format bulk.Format, c *bulk.Config) ([]*mailformv1alpha1.Mail, error) {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close() // nolint:errcheck
+		r = f
+	}
+
+	rows, err := bulk.Read(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return bulk.Build(rows, c)
+}
+
+// printReport writes a table of the problems found in an import file.
+func printReport(out io.Writer, errs bulk.Errors) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "ROW\tCOLUMN\tERROR")
+	for _, err := range errs {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", err.Row, valueOrNone(err.Column), err.Message)
+	}
+
+	w.Flush() // nolint:errcheck
+}
+
+// importUsage adds the supported columns to the import usage.
+func importUsage(fs *flag.FlagSet) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintln(w, "Usage:\n  kubectl mail import FILE [--service SERVICE] [--url URL] [--from-* ...] [flags]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "FILE is a CSV file with a header row or a JSON array of objects, keyed by these columns:")
+		for _, name := range bulk.Columns() {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Values in a row override the flags below, which are shared by every row.")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Flags:")
+		fs.PrintDefaults()
+	}
+}
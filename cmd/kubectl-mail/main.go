@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+var (
+	scheme = runtime.NewScheme()
+
+	// errUsage is returned when a command is invoked incorrectly. The usage has already been printed.
+	errUsage = errors.New("invalid usage")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(mailformv1alpha1.AddToScheme(scheme))
+}
+
+// command is a kubectl-mail subcommand.
+type command struct {
+	name  string
+	short string
+	run   func(ctx context.Context, args []string, out io.Writer) error
+}
+
+// commands are the supported subcommands.
+var commands = []command{
+	{name: "send", short: "Create a Mail from flags, uploading a local PDF via a ConfigMap if needed", run: runSend},
+	{name: "import", short: "Create a Mail for each row of a CSV or JSON file", run: runImport},
+	{name: "status", short: "Show the state, cost and order ID of mail", run: runStatus},
+	{name: "cancel", short: "Cancel mail by deleting it, which cancels the order if it hasn't been sent", run: runCancel},
+	{name: "wait", short: "Wait until mail has been sent", run: runWait},
+	{name: "preview", short: "Save the preview of what will print for a Mail as a PNG", run: runPreview},
+	{name: "cost", short: "Sum the cost of mail across namespaces and time ranges", run: runCost},
+	{name: "services", short: "List the delivery services and what they support", run: runServices},
+}
+
+func main() {
+	err := run(ctrl.SetupSignalHandler(), os.Args[1:], os.Stdout)
+	if err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run dispatches args to the matching subcommand.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(os.Stderr)
+		if len(args) == 0 {
+			return errUsage
+		}
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(ctx, args[1:], out)
+		}
+	}
+
+	usage(os.Stderr)
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// usage prints the list of subcommands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Create and inspect postk8s mail.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  kubectl mail <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use \"kubectl mail <command> --help\" for more information about a command.")
+}
+
+// kubeFlags are the connection flags shared by all subcommands.
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+// bind registers the connection flags on fs.
+func (k *kubeFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&k.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use.")
+	fs.StringVar(&k.context, "context", "", "The name of the kubeconfig context to use.")
+	fs.StringVar(&k.namespace, "namespace", "", "The namespace to use. Defaults to the namespace of the current context.")
+	fs.StringVar(&k.namespace, "n", "", "Shorthand for --namespace.")
+}
+
+// clientConfig returns the kubeconfig loader honoring the connection flags.
+func (k *kubeFlags) clientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = k.kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: k.context,
+	}
+	overrides.Context.Namespace = k.namespace
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+// newClient returns a client for the cluster configured by the connection flags.
+// Overridden in tests.
+var newClient = func(k *kubeFlags) (client.Client, error) {
+	cfg, err := k.clientConfig().ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// ns returns the namespace to use.
+func (k *kubeFlags) ns() (string, error) {
+	if k.namespace != "" {
+		return k.namespace, nil
+	}
+
+	namespace, _, err := k.clientConfig().Namespace()
+	return namespace, err
+}
+
+// parseArgs parses flags that may be interleaved with positional arguments, e.g. "send NAME --service USPS_STANDARD".
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand that prints its usage to stderr.
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage:\n  kubectl mail %s\n\nFlags:\n", usageLine)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// handleParseErr treats explicit help requests as success.
+func handleParseErr(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	return err
+}
+
+// requireOneName ensures exactly one mail name was given.
+func requireOneName(fs *flag.FlagSet, positional []string) (string, error) {
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		fs.Usage()
+		return "", errUsage
+	}
+
+	return positional[0], nil
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+const testNamespace = "default"
+
+var addressArgs = []string{
+	"--to-name", "Recipient Name", "--to-address1", "456 Recipient Ave", "--to-city", "Receivertown",
+	"--to-state", "NY", "--to-postcode", "10001", "--to-country", "US",
+	"--from-name", "Sender Name", "--from-address1", "123 Sender St", "--from-city", "Senderville",
+	"--from-state", "CA", "--from-postcode", "94016", "--from-country", "US",
+}
+
+func newTestMail(namespace, name string, status mailformv1alpha1.MailStatus) *mailformv1alpha1.Mail {
+	return &mailformv1alpha1.Mail{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Spec: mailformv1alpha1.MailSpec{
+			Service: "USPS_STANDARD",
+			URL:     "https://pdfobject.com/pdf/sample.pdf",
+		},
+		Status: status,
+	}
+}
+
+var _ = Describe("kubectl-mail", func() {
+	var (
+		ctx        context.Context
+		fakeClient client.Client
+		out        *bytes.Buffer
+		objects    []client.Object
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		out = &bytes.Buffer{}
+		objects = nil
+	})
+
+	JustBeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		newClient = func(_ *kubeFlags) (client.Client, error) {
+			return fakeClient, nil
+		}
+	})
+
+	Context("send", func() {
+		It("should create a mail referencing a url", func() {
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD",
+				"--url", "https://pdfobject.com/pdf/sample.pdf", "--ttl", "60"}, addressArgs...)
+			Expect(run(ctx, append([]string{"send"}, args...), out)).To(Succeed())
+			Expect(out.String()).To(Equal("mail/letter created\n"))
+
+			mail := &mailformv1alpha1.Mail{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "letter"}, mail)).To(Succeed())
+			Expect(mail.Spec.URL).To(Equal("https://pdfobject.com/pdf/sample.pdf"))
+			Expect(mail.Spec.To.City).To(Equal("Receivertown"))
+			Expect(mail.Spec.From.Postcode).To(Equal("94016"))
+			Expect(*mail.Spec.TTLSecondsAfterFinished).To(Equal(int32(60)))
+			Expect(mail.Spec.ConfigMapRef).To(BeNil())
+		})
+
+		It("should upload a local document to a configmap owned by the mail", func() {
+			file := filepath.Join(GinkgoT().TempDir(), "letter.pdf")
+			Expect(os.WriteFile(file, []byte("%PDF-1.4 test document"), 0o600)).To(Succeed())
+
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD", "--file", file}, addressArgs...)
+			Expect(run(ctx, append([]string{"send"}, args...), out)).To(Succeed())
+
+			mail := &mailformv1alpha1.Mail{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "letter"}, mail)).To(Succeed())
+			Expect(mail.Spec.ConfigMapRef.Name).To(Equal("letter-document"))
+			Expect(mail.Spec.ConfigMapRef.Key).To(Equal(documentKey))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "letter-document"}, configMap)).
+				To(Succeed())
+			Expect(configMap.BinaryData[documentKey]).To(Equal([]byte("%PDF-1.4 test document")))
+			Expect(configMap.OwnerReferences).To(HaveLen(1))
+			Expect(configMap.OwnerReferences[0].Name).To(Equal("letter"))
+		})
+
+		It("should not upload the document when the mail can't be created", func() {
+			Expect(fakeClient.Create(ctx, newTestMail(testNamespace, "letter", mailformv1alpha1.MailStatus{}))).To(Succeed())
+			file := filepath.Join(GinkgoT().TempDir(), "letter.pdf")
+			Expect(os.WriteFile(file, []byte("%PDF-1.4 test document"), 0o600)).To(Succeed())
+
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD", "--file", file}, addressArgs...)
+			err := run(ctx, append([]string{"send"}, args...), out)
+			Expect(apierrors.IsAlreadyExists(err)).To(BeTrue())
+
+			key := types.NamespacedName{Namespace: testNamespace, Name: "letter-document"}
+			Expect(apierrors.IsNotFound(fakeClient.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue())
+		})
+
+		It("should delete the mail when the document can't be uploaded", func() {
+			file := filepath.Join(GinkgoT().TempDir(), "letter.pdf")
+			Expect(os.WriteFile(file, []byte("%PDF-1.4 test document"), 0o600)).To(Succeed())
+
+			newClient = func(_ *kubeFlags) (client.Client, error) {
+				return interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+					Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+						if _, ok := obj.(*corev1.ConfigMap); ok {
+							return errors.New("connection refused")
+						}
+						return c.Create(ctx, obj, opts...)
+					},
+				}), nil
+			}
+
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD", "--file", file}, addressArgs...)
+			err := run(ctx, append([]string{"send"}, args...), out)
+			Expect(err).To(MatchError("uploading document: connection refused"))
+
+			key := types.NamespacedName{Namespace: testNamespace, Name: "letter"}
+			Expect(apierrors.IsNotFound(fakeClient.Get(ctx, key, &mailformv1alpha1.Mail{}))).To(BeTrue())
+		})
+
+		It("should report when the mail can't be deleted", func() {
+			file := filepath.Join(GinkgoT().TempDir(), "letter.pdf")
+			Expect(os.WriteFile(file, []byte("%PDF-1.4 test document"), 0o600)).To(Succeed())
+			Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "letter-document"},
+			})).To(Succeed())
+
+			newClient = func(_ *kubeFlags) (client.Client, error) {
+				return interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+					Delete: func(context.Context, client.WithWatch, client.Object, ...client.DeleteOption) error {
+						return errors.New("connection refused")
+					},
+				}), nil
+			}
+
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD", "--file", file}, addressArgs...)
+			err := run(ctx, append([]string{"send"}, args...), out)
+			Expect(apierrors.IsAlreadyExists(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("deleting mail letter: connection refused")))
+		})
+
+		It("should print the mail without creating it on dry run", func() {
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD",
+				"--url", "https://pdfobject.com/pdf/sample.pdf", "--dry-run"}, addressArgs...)
+			Expect(run(ctx, append([]string{"send"}, args...), out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("kind: Mail"))
+			Expect(out.String()).To(ContainSubstring("service: USPS_STANDARD"))
+
+			err := fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "letter"}, &mailformv1alpha1.Mail{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should reject both a file and url", func() {
+			args := append([]string{"letter", "-n", testNamespace, "--service", "USPS_STANDARD",
+				"--url", "https://pdfobject.com/pdf/sample.pdf", "--file", "letter.pdf"}, addressArgs...)
+			Expect(run(ctx, append([]string{"send"}, args...), out)).To(MatchError(ContainSubstring("cannot both be provided")))
+		})
+	})
+
+	Context("import", func() {
+		var file string
+
+		sharedArgs := []string{"-n", testNamespace,
+			"--service", "USPS_STANDARD", "--url", "https://pdfobject.com/pdf/sample.pdf",
+			"--from-name", "Sender Name", "--from-address1", "123 Sender St", "--from-city", "Senderville",
+			"--from-state", "CA", "--from-postcode", "94016", "--from-country", "US"}
+
+		writeFile := func(name, contents string) {
+			file = filepath.Join(GinkgoT().TempDir(), name)
+			Expect(os.WriteFile(file, []byte(contents), 0o600)).To(Succeed())
+		}
+
+		It("should create mail for every row", func() {
+			writeFile("recipients.csv", `name,to_name,to_address1,to_city,to_state,to_postcode,to_country
+alice,Alice,1 Main St,Springfield,IL,62701,US
+bob,Bob,2 Main St,Springfield,IL,62702,US
+`)
+			Expect(run(ctx, append([]string{"import", file}, sharedArgs...), out)).To(Succeed())
+			Expect(out.String()).To(Equal("mail/alice created\nmail/bob created\n"))
+
+			mail := &mailformv1alpha1.Mail{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "bob"}, mail)).To(Succeed())
+			Expect(mail.Spec.To.Postcode).To(Equal("62702"))
+			Expect(mail.Spec.From.Name).To(Equal("Sender Name"))
+		})
+
+		It("should not create any mail if the API server rejects a row", func() {
+			Expect(fakeClient.Create(ctx, newTestMail(testNamespace, "bob", mailformv1alpha1.MailStatus{}))).To(Succeed())
+			newClient = func(_ *kubeFlags) (client.Client, error) {
+				return interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+					// The fake client doesn't check objects on dry run
+					Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+						if obj.GetName() == "bob" {
+							return apierrors.NewAlreadyExists(mailformv1alpha1.GroupVersion.WithResource("mails").GroupResource(), "bob")
+						}
+						return c.Create(ctx, obj, opts...)
+					},
+				}), nil
+			}
+
+			writeFile("recipients.csv", `name,to_name,to_address1,to_city,to_state,to_postcode,to_country
+alice,Alice,1 Main St,Springfield,IL,62701,US
+bob,Bob,2 Main St,Springfield,IL,62702,US
+`)
+			Expect(run(ctx, append([]string{"import", file}, sharedArgs...), out)).
+				To(MatchError("1 mail rejected, none were created"))
+			Expect(out.String()).To(Equal("mail/bob rejected: mails.mailform.circa10a.github.io \"bob\" already exists\n"))
+
+			err := fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "alice"}, &mailformv1alpha1.Mail{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should validate without creating mail on dry run", func() {
+			writeFile("recipients.json", `[{"name": "alice", "to_name": "Alice", "to_address1": "1 Main St",
+				"to_city": "Springfield", "to_state": "IL", "to_postcode": "62701", "to_country": "US"}]`)
+			Expect(run(ctx, append([]string{"import", file, "--dry-run"}, sharedArgs...), out)).To(Succeed())
+			Expect(out.String()).To(Equal("1 mail validated\n"))
+
+			err := fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "alice"}, &mailformv1alpha1.Mail{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should report problems by row and column without creating any mail", func() {
+			writeFile("recipients.csv", `name,to_name,to_address1,to_city,to_state,to_postcode,to_country
+alice,Alice,1 Main St,Springfield,IL,62701,US
+bob,Bob,2 Main St,Springfield,IL,,US
+`)
+			err := run(ctx, append([]string{"import", file}, sharedArgs...), out)
+			Expect(err).To(MatchError(ContainSubstring("1 problem(s) found")))
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(strings.Fields(lines[1])[:2]).To(Equal([]string{"3", "to_postcode"}))
+
+			err = fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "alice"}, &mailformv1alpha1.Mail{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("status", func() {
+		BeforeEach(func() {
+			objects = []client.Object{
+				newTestMail(testNamespace, "letter", mailformv1alpha1.MailStatus{
+					ID:    "order-1",
+					State: mailform.StatusQueued,
+					Total: 1234,
+				}),
+				newTestMail("other", "invoice", mailformv1alpha1.MailStatus{}),
+			}
+		})
+
+		It("should print mail in the namespace", func() {
+			Expect(run(ctx, []string{"status", "-n", testNamespace}, out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(strings.Fields(lines[0])).To(Equal([]string{"NAME", "STATE", "SENT", "TOTAL", "ORDER", "ID", "AGE"}))
+			Expect(strings.Fields(lines[1])).
+				To(Equal([]string{"letter", mailform.StatusQueued, "false", "$12.34", "order-1", "60m"}))
+		})
+
+		It("should print mail in all namespaces", func() {
+			Expect(run(ctx, []string{"status", "-A"}, out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(HavePrefix("NAMESPACE"))
+			Expect(strings.Fields(lines[2])[:3]).To(Equal([]string{"other", "invoice", "<none>"}))
+		})
+	})
+
+	Context("cancel", func() {
+		BeforeEach(func() {
+			objects = []client.Object{
+				newTestMail(testNamespace, "letter", mailformv1alpha1.MailStatus{ID: "order-1"}),
+				newTestMail(testNamespace, "sent", mailformv1alpha1.MailStatus{ID: "order-2", Sent: true}),
+			}
+		})
+
+		It("should delete mail that has not been sent", func() {
+			Expect(run(ctx, []string{"cancel", "letter", "-n", testNamespace}, out)).To(Succeed())
+			Expect(out.String()).To(Equal("mail/letter cancelled\n"))
+
+			err := fakeClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "letter"}, &mailformv1alpha1.Mail{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should refuse to cancel mail that has been sent", func() {
+			Expect(run(ctx, []string{"cancel", "sent", "-n", testNamespace}, out)).
+				To(MatchError(ContainSubstring("already been sent")))
+		})
+	})
+
+	Context("wait", func() {
+		BeforeEach(func() {
+			objects = []client.Object{
+				newTestMail(testNamespace, "sent", mailformv1alpha1.MailStatus{Sent: true}),
+				newTestMail(testNamespace, "cancelled", mailformv1alpha1.MailStatus{
+					State:              mailform.StatusCancelled,
+					CancellationReason: "bad address",
+				}),
+				newTestMail(testNamespace, "queued", mailformv1alpha1.MailStatus{State: mailform.StatusQueued}),
+				newTestMail(testNamespace, "lost", mailformv1alpha1.MailStatus{
+					ID: "order-1",
+					Conditions: []metav1.Condition{{
+						Type:    orderLostCondition,
+						Status:  metav1.ConditionTrue,
+						Reason:  "NotFound",
+						Message: "Order order-1 was not found",
+					}},
+				}),
+			}
+		})
+
+		It("should return once mail has been sent", func() {
+			Expect(run(ctx, []string{"wait", "sent", "-n", testNamespace}, out)).To(Succeed())
+			Expect(out.String()).To(Equal("mail/sent sent\n"))
+		})
+
+		It("should fail if mail is cancelled", func() {
+			Expect(run(ctx, []string{"wait", "cancelled", "-n", testNamespace}, out)).
+				To(MatchError(ContainSubstring("bad address")))
+		})
+
+		It("should fail if the order of mail is lost", func() {
+			Expect(run(ctx, []string{"wait", "lost", "-n", testNamespace}, out)).
+				To(MatchError("mail/lost's order was lost: Order order-1 was not found"))
+		})
+
+		It("should time out if mail is not sent", func() {
+			args := []string{"wait", "queued", "-n", testNamespace, "--timeout", "50ms", "--interval", "10ms"}
+			Expect(run(ctx, args, out)).NotTo(Succeed())
+		})
+	})
+
+	Context("preview", func() {
+		png := []byte("\x89PNG\r\n\x1a\n")
+
+		BeforeEach(func() {
+			previewed := newTestMail(testNamespace, "letter", mailformv1alpha1.MailStatus{
+				Preview: &mailformv1alpha1.PreviewStatus{
+					ConfigMapName: "letter-preview",
+					Key:           "preview.png",
+					Pages:         2,
+				},
+			})
+			objects = []client.Object{
+				previewed,
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "letter-preview", Namespace: testNamespace},
+					BinaryData: map[string][]byte{"preview.png": png},
+				},
+				newTestMail(testNamespace, "unverified", mailformv1alpha1.MailStatus{}),
+			}
+		})
+
+		It("should save the preview to a file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "letter.png")
+			Expect(run(ctx, []string{"preview", "letter", "-n", testNamespace, "-o", path}, out)).To(Succeed())
+			Expect(out.String()).To(Equal("mail/letter preview of 2 pages saved to " + path + "\n"))
+			Expect(os.ReadFile(path)).To(Equal(png))
+		})
+
+		It("should write the preview to stdout", func() {
+			Expect(run(ctx, []string{"preview", "letter", "-n", testNamespace, "-o", "-"}, out)).To(Succeed())
+			Expect(out.Bytes()).To(Equal(png))
+		})
+
+		It("should fail if mail has no preview", func() {
+			Expect(run(ctx, []string{"preview", "unverified", "-n", testNamespace}, out)).
+				To(MatchError(ContainSubstring("has no preview")))
+		})
+	})
+
+	Context("cost", func() {
+		BeforeEach(func() {
+			now := time.Now()
+			objects = []client.Object{
+				newTestMail(testNamespace, "recent", mailformv1alpha1.MailStatus{
+					ID:      "order-1",
+					Total:   150,
+					Created: metav1.NewTime(now.Add(-time.Hour)),
+				}),
+				newTestMail(testNamespace, "old", mailformv1alpha1.MailStatus{
+					ID:      "order-2",
+					Total:   1000,
+					Created: metav1.NewTime(now.Add(-48 * time.Hour)),
+				}),
+				newTestMail("other", "recent", mailformv1alpha1.MailStatus{
+					ID:      "order-3",
+					Total:   275,
+					Created: metav1.NewTime(now.Add(-time.Hour)),
+				}),
+				newTestMail("other", "unordered", mailformv1alpha1.MailStatus{Total: 999}),
+			}
+		})
+
+		It("should sum mail in the namespace", func() {
+			Expect(run(ctx, []string{"cost", "-n", testNamespace}, out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{testNamespace, "2", "$11.50"}))
+			Expect(strings.Fields(lines[2])).To(Equal([]string{"TOTAL", "2", "$11.50"}))
+		})
+
+		It("should sum mail across namespaces within a time range", func() {
+			Expect(run(ctx, []string{"cost", "-A", "--since", "24h"}, out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{testNamespace, "1", "$1.50"}))
+			Expect(strings.Fields(lines[2])).To(Equal([]string{"other", "1", "$2.75"}))
+			Expect(strings.Fields(lines[3])).To(Equal([]string{"TOTAL", "2", "$4.25"}))
+		})
+
+		It("should reject an invalid time", func() {
+			Expect(run(ctx, []string{"cost", "--since", "yesterday"}, out)).To(MatchError(ContainSubstring("invalid --since")))
+		})
+	})
+
+	Context("services", func() {
+		It("should list the services that deliver to a country", func() {
+			Expect(run(ctx, []string{"services"}, out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(len(provider.MailformServices) + 1))
+			Expect(strings.Fields(lines[0])).
+				To(Equal([]string{"SERVICE", "INTERNATIONAL", "CERTIFIED", "TRACKING", "PRICE", "CLASS"}))
+
+			out.Reset()
+			Expect(run(ctx, []string{"services", "--country", "DE"}, out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("USPS_FIRST_CLASS"))
+			Expect(out.String()).NotTo(ContainSubstring("USPS_STANDARD"))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// runPreview saves the preview the operator rendered of a Mail's document.
+func runPreview(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var output string
+
+	fs := newFlagSet("preview", "preview NAME [flags]")
+	kube.bind(fs)
+	fs.StringVar(&output, "output", "", "File to save the PNG to. Defaults to NAME-preview.png. Use '-' for stdout.")
+	fs.StringVar(&output, "o", "", "Shorthand for --output.")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	name, err := requireOneName(fs, positional)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := kube.ns()
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	mail := &mailformv1alpha1.Mail{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, mail)
+	if err != nil {
+		return err
+	}
+
+	preview := mail.Status.Preview
+	if preview == nil {
+		return fmt.Errorf("mail/%s has no preview, previews are rendered when the document is verified", name)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: preview.ConfigMapName}, configMap)
+	if err != nil {
+		return err
+	}
+
+	png, ok := configMap.BinaryData[preview.Key]
+	if !ok {
+		return fmt.Errorf("configmap/%s has no key %q", preview.ConfigMapName, preview.Key)
+	}
+
+	if output == "-" {
+		_, err = out.Write(png)
+		return err
+	}
+
+	if output == "" {
+		output = name + "-preview.png"
+	}
+
+	err = os.WriteFile(output, png, 0o644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "mail/%s preview of %d pages saved to %s\n", name, preview.Pages, output)
+
+	return nil
+}
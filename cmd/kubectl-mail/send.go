@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+const (
+	// documentKey is the ConfigMap key uploaded documents are stored under.
+	documentKey = "document.pdf"
+	// maxConfigMapDocumentSize leaves room for metadata within the 1MiB ConfigMap limit.
+	maxConfigMapDocumentSize = 1000 * 1024
+)
+
+// addressFlags binds the flags for an Address with the given prefix, e.g. --to-name.
+func addressFlags(fs *flag.FlagSet, prefix string, address *mailformv1alpha1.Address) {
+	fs.StringVar(&address.Name, prefix+"-name", "", "The name of the "+prefix+" address. Required.")
+	fs.StringVar(&address.Organization, prefix+"-organization", "", "The organization of the "+prefix+" address.")
+	fs.StringVar(&address.Address1, prefix+"-address1", "", "The street of the "+prefix+" address. Required.")
+	fs.StringVar(&address.Address2, prefix+"-address2", "", "The suite or room number of the "+prefix+" address.")
+	fs.StringVar(&address.City, prefix+"-city", "", "The city of the "+prefix+" address. Required.")
+	fs.StringVar(&address.State, prefix+"-state", "", "The state of the "+prefix+" address. Required.")
+	fs.StringVar(&address.Postcode, prefix+"-postcode", "", "The postcode of the "+prefix+" address. Required.")
+	fs.StringVar(&address.Country, prefix+"-country", "", "The country of the "+prefix+" address. Required.")
+}
+
+// specFlags binds the flags for the MailSpec options shared by send and import.
+func specFlags(fs *flag.FlagSet, spec *mailformv1alpha1.MailSpec, ttl *int) {
+	fs.StringVar((*string)(&spec.Service), "service", "",
+		"The delivery service to use, e.g. USPS_STANDARD. Run services to list them. Required.")
+	fs.StringVar(&spec.URL, "url", "", "The URL of the PDF to mail.")
+	fs.StringVar(&spec.CustomerReference, "customer-reference", "", "A customer reference to attach to the order.")
+	fs.StringVar(&spec.Webhook, "webhook", "", "A webhook to receive notifications about the order.")
+	fs.StringVar(&spec.Company, "company", "", "The company the order should be associated with.")
+	fs.StringVar(&spec.Message, "message", "", "The message printed on the non-picture side of a postcard.")
+	fs.BoolVar(&spec.Simplex, "simplex", false, "Print one page to a sheet.")
+	fs.BoolVar(&spec.Color, "color", false, "Print in color.")
+	fs.BoolVar(&spec.Flat, "flat", false, "Mail in a flat envelope.")
+	fs.BoolVar(&spec.Stamp, "stamp", false, "Use a real postage stamp.")
+	fs.IntVar(ttl, "ttl", -1,
+		"Seconds to keep the Mail after it's fulfilled or cancelled. Uses the operator default if unset.")
+}
+
+// setTTL sets the spec's TTL if one was provided.
+func setTTL(spec *mailformv1alpha1.MailSpec, ttl int) {
+	if ttl >= 0 {
+		ttlSeconds := int32(ttl)
+		spec.TTLSecondsAfterFinished = &ttlSeconds
+	}
+}
+
+// runSend creates a Mail, uploading a local document to a ConfigMap owned by the Mail.
+func runSend(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var file string
+	var dryRun bool
+	var ttl int
+	mail := &mailformv1alpha1.Mail{
+		Spec: mailformv1alpha1.MailSpec{
+			To:   &mailformv1alpha1.Address{},
+			From: &mailformv1alpha1.Address{},
+		},
+	}
+
+	fs := newFlagSet("send", "send NAME --service SERVICE (--url URL | --file PATH) --to-* ... --from-* ... [flags]")
+	kube.bind(fs)
+	specFlags(fs, &mail.Spec, &ttl)
+	fs.StringVar(&file, "file", "", "Path to a local PDF to mail. It is uploaded to a ConfigMap owned by the Mail.")
+	fs.BoolVar(&dryRun, "dry-run", false, "Print the Mail instead of creating it.")
+	addressFlags(fs, "to", mail.Spec.To)
+	addressFlags(fs, "from", mail.Spec.From)
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	name, err := requireOneName(fs, positional)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := kube.ns()
+	if err != nil {
+		return err
+	}
+
+	mail.Name = name
+	mail.Namespace = namespace
+	setTTL(&mail.Spec, ttl)
+
+	var configMap *corev1.ConfigMap
+	if file != "" {
+		if mail.Spec.URL != "" {
+			return fmt.Errorf("--file and --url cannot both be provided")
+		}
+
+		configMap, err = documentConfigMap(mail, file)
+		if err != nil {
+			return err
+		}
+
+		mail.Spec.ConfigMapRef = &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+			Key:                  documentKey,
+		}
+	}
+
+	if dryRun {
+		return printDryRun(out, mail, configMap)
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	err = c.Create(ctx, mail)
+	if err != nil {
+		return err
+	}
+
+	// The document is uploaded owned by the Mail so it's garbage collected with it, even if this fails partway.
+	// The operator retries reading the document until it exists.
+	if configMap != nil {
+		err = controllerutil.SetOwnerReference(mail, configMap, c.Scheme())
+		if err == nil {
+			err = c.Create(ctx, configMap)
+		}
+		if err != nil {
+			err = fmt.Errorf("uploading document: %w", err)
+
+			// The Mail can't be ordered without its document so it's removed
+			deleteErr := c.Delete(ctx, mail)
+			if deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+				return errors.Join(err, fmt.Errorf("deleting mail %s: %w", mail.Name, deleteErr))
+			}
+
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "mail/%s created\n", mail.Name)
+
+	return nil
+}
+
+// documentConfigMap returns a ConfigMap containing the document at path.
+func documentConfigMap(mail *mailformv1alpha1.Mail, path string) (*corev1.ConfigMap, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > maxConfigMapDocumentSize {
+		return nil, fmt.Errorf("%s is %d bytes, documents uploaded via ConfigMap must be under %d bytes; use --url instead",
+			filepath.Base(path), len(b), maxConfigMapDocumentSize)
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mail.Name + "-document",
+			Namespace: mail.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "kubectl-mail",
+			},
+		},
+		BinaryData: map[string][]byte{
+			documentKey: b,
+		},
+	}, nil
+}
+
+// printDryRun prints the objects that would be created as YAML.
+func printDryRun(out io.Writer, mail *mailformv1alpha1.Mail, configMap *corev1.ConfigMap) error {
+	mail.APIVersion = mailformv1alpha1.GroupVersion.String()
+	mail.Kind = "Mail"
+
+	objects := []any{}
+	if configMap != nil {
+		objects = append(objects, configMap)
+	}
+	objects = append(objects, mail)
+
+	for i, obj := range objects {
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		fmt.Fprint(out, string(b))
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+// runServices prints a table of the delivery services and what they support.
+func runServices(_ context.Context, args []string, out io.Writer) error {
+	var country string
+
+	fs := newFlagSet("services", "services [flags]")
+	fs.StringVar(&country, "country", "", "Only show services that deliver to this country, e.g. US.")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	if len(positional) != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return printServices(out, provider.MailformServices, country)
+}
+
+// printServices writes a table of the services in the catalog that deliver to country, or all of them, to out.
+func printServices(out io.Writer, catalog provider.Catalog, country string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tINTERNATIONAL\tCERTIFIED\tTRACKING\tPRICE CLASS")
+
+	for _, service := range catalog {
+		if country != "" && catalog.CheckDestination(service.Code, country) != nil {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			service.Code,
+			strconv.FormatBool(service.International),
+			strconv.FormatBool(service.Certified),
+			strconv.FormatBool(service.Tracking),
+			service.PriceClass,
+		)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// runStatus prints a table of mail and the state of their orders.
+func runStatus(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var allNamespaces bool
+
+	fs := newFlagSet("status", "status [NAME...] [flags]")
+	kube.bind(fs)
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "Show mail in all namespaces.")
+	fs.BoolVar(&allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	namespace := ""
+	if !allNamespaces {
+		namespace, err = kube.ns()
+		if err != nil {
+			return err
+		}
+	}
+
+	mails, err := getMails(ctx, c, namespace, names)
+	if err != nil {
+		return err
+	}
+
+	return printStatus(out, mails, allNamespaces, time.Now())
+}
+
+// getMails returns the named mail, or all mail in the namespace if no names are given.
+func getMails(ctx context.Context, c client.Client, namespace string, names []string) ([]mailformv1alpha1.Mail, error) {
+	if len(names) == 0 {
+		mailList := &mailformv1alpha1.MailList{}
+		err := c.List(ctx, mailList, client.InNamespace(namespace))
+		if err != nil {
+			return nil, err
+		}
+
+		return mailList.Items, nil
+	}
+
+	mails := make([]mailformv1alpha1.Mail, 0, len(names))
+	for _, name := range names {
+		mail := mailformv1alpha1.Mail{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &mail)
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, mail)
+	}
+
+	return mails, nil
+}
+
+// printStatus writes a table of mail to out.
+func printStatus(out io.Writer, mails []mailformv1alpha1.Mail, withNamespace bool, now time.Time) error {
+	if len(mails) == 0 {
+		fmt.Fprintln(out, "No mail found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	if withNamespace {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tSTATE\tSENT\tTOTAL\tORDER ID\tAGE")
+
+	for _, mail := range mails {
+		if withNamespace {
+			fmt.Fprintf(w, "%s\t", mail.Namespace)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			mail.Name,
+			valueOrNone(mail.Status.State),
+			strconv.FormatBool(mail.Status.Sent),
+			formatCents(mail.Status.Total),
+			valueOrNone(mail.Status.ID),
+			duration.HumanDuration(now.Sub(mail.CreationTimestamp.Time)),
+		)
+	}
+
+	return w.Flush()
+}
+
+// valueOrNone returns s or "<none>" if empty, matching kubectl.
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
+
+// formatCents formats a Mailform total, which is in cents, as dollars.
+func formatCents(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s$%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// orderLostCondition is the condition the operator sets on mail whose order can no longer be found.
+const orderLostCondition = "OrderLost"
+
+// runWait blocks until mail has been sent, failing if it is cancelled or its order is lost first.
+func runWait(ctx context.Context, args []string, out io.Writer) error {
+	var kube kubeFlags
+	var timeout, interval time.Duration
+
+	fs := newFlagSet("wait", "wait NAME [flags]")
+	kube.bind(fs)
+	fs.DurationVar(&timeout, "timeout", 7*24*time.Hour, "How long to wait for the mail to be sent.")
+	fs.DurationVar(&interval, "interval", 30*time.Second, "How often to check the mail's status.")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return handleParseErr(err)
+	}
+
+	name, err := requireOneName(fs, positional)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := kube.ns()
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(&kube)
+	if err != nil {
+		return err
+	}
+
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	mail := &mailformv1alpha1.Mail{}
+
+	err = wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		err := c.Get(ctx, key, mail)
+		if err != nil {
+			return false, err
+		}
+
+		if mail.Status.State == mailform.StatusCancelled {
+			return false, fmt.Errorf("mail/%s was cancelled: %s", name, valueOrNone(mail.Status.CancellationReason))
+		}
+
+		// Lost orders are only retried by creating a new order, which resets the condition
+		lost := meta.FindStatusCondition(mail.Status.Conditions, orderLostCondition)
+		if lost != nil && lost.Status == metav1.ConditionTrue {
+			return false, fmt.Errorf("mail/%s's order was lost: %s", name, lost.Message)
+		}
+
+		return mail.Status.Sent, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "mail/%s sent\n", name)
+
+	return nil
+}
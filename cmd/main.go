@@ -11,10 +11,15 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -23,7 +28,14 @@ import (
 
 	mailform "github.com/circa10a/go-mailform"
 	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/archive"
+	"github.com/circa10a/postk8s/internal/audit"
 	"github.com/circa10a/postk8s/internal/controller"
+	"github.com/circa10a/postk8s/internal/document"
+	"github.com/circa10a/postk8s/internal/killswitch"
+	"github.com/circa10a/postk8s/internal/polling"
+	"github.com/circa10a/postk8s/internal/provider"
+	"github.com/circa10a/postk8s/internal/runtimeconfig"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -32,8 +44,17 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 
 	// Environment variables
-	mailformApiTokenEnvVar     = "MAILFORM_API_TOKEN"
-	mailformSyncIntervalEnvVar = "MAILFORM_SYNC_INTERVAL"
+	mailformApiTokenEnvVar         = "MAILFORM_API_TOKEN"
+	mailformSyncIntervalEnvVar     = "MAILFORM_SYNC_INTERVAL"
+	mailformTTLAfterFinishedEnvVar = "MAILFORM_TTL_AFTER_FINISHED"
+	podNamespaceEnvVar             = "POD_NAMESPACE"
+	awsAccessKeyIDEnvVar           = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKeyEnvVar       = "AWS_SECRET_ACCESS_KEY"
+	awsSessionTokenEnvVar          = "AWS_SESSION_TOKEN"
+
+	// Archive backends
+	archiveBackendFilesystem = "filesystem"
+	archiveBackendS3         = "s3"
 )
 
 func init() {
@@ -44,8 +65,29 @@ func init() {
 
 // nolint:gocyclo
 func main() {
-	var mailformAPIToken string
-	var syncInterval string
+	var mailformAPIToken, mailformAPITokenFile string
+	var syncInterval, minSyncInterval string
+	var syncJitter float64
+	var startupSyncRate float64
+	var startupSyncBurst, maxConcurrentReconciles int
+	var ttlAfterFinished string
+	var archiveBackend, archivePath string
+	var archiveS3Endpoint, archiveS3Bucket, archiveS3Region string
+	var archiveS3PathStyle bool
+	var auditInterval, auditMinOrphanAge string
+	var auditAutoCancelOrphans bool
+	var orderLostPolicy string
+	var killSwitchConfigMap, killSwitchNamespace string
+	var runtimeConfigName string
+	var documentPreflight bool
+	var documentMaxBytes int64
+	var documentFetchTimeout string
+	var documentMaxPages int
+	var documentPreviewPages int
+	var mailformCredentialProbeInterval string
+	var mailformRequestsPerSecond float64
+	var mailformBurst, mailformCircuitFailureThreshold int
+	var mailformCircuitOpenTimeout string
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
@@ -56,8 +98,76 @@ func main() {
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&mailformAPIToken, "mailform-api-token", getEnv(mailformApiTokenEnvVar, ""),
 		fmt.Sprintf("Mailform API token."+"Defaults to '%s' environment variable.", mailformApiTokenEnvVar))
+	flag.StringVar(&mailformAPITokenFile, "mailform-api-token-file", "",
+		"File containing the Mailform API token, such as a mounted Secret. "+
+			"The token is reloaded when the file changes. Overrides --mailform-api-token.")
 	flag.StringVar(&syncInterval, "sync-interval", getEnv(mailformSyncIntervalEnvVar, "12h"),
 		"Interval to check for mail updates."+"Defaults to '12h'.")
+	flag.StringVar(&minSyncInterval, "min-sync-interval", "5m",
+		"Interval to check new orders for updates. Orders are checked less often as they age, up to --sync-interval. "+
+			"Set to '0', or at least --sync-interval, to always use --sync-interval.")
+	flag.Float64Var(&syncJitter, "sync-jitter", polling.DefaultJitter,
+		"Fraction of each sync interval randomly added or removed to spread out checks for updates.")
+	flag.Float64Var(&startupSyncRate, "startup-sync-rate", 2,
+		"Maximum rate mail starts checking for updates after the manager starts. Set to '0' to start all mail at once.")
+	flag.IntVar(&startupSyncBurst, "startup-sync-burst", 20,
+		"Number of mail that can start checking for updates at once after the manager starts.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Maximum number of mail reconciled at the same time.")
+	flag.StringVar(&ttlAfterFinished, "ttl-after-finished", getEnv(mailformTTLAfterFinishedEnvVar, "0"),
+		"Default time to keep fulfilled/cancelled mail before deleting it. "+
+			"Can be overridden per mail with spec.ttlSecondsAfterFinished. Defaults to '0' which disables cleanup.")
+	flag.StringVar(&archiveBackend, "archive-backend", "",
+		fmt.Sprintf("Where to archive records of fulfilled/cancelled mail. One of '%s' or '%s'. Disabled if empty.",
+			archiveBackendFilesystem, archiveBackendS3))
+	flag.StringVar(&archivePath, "archive-path", "/archive",
+		"The directory to archive mail to when using the filesystem archive backend.")
+	flag.StringVar(&archiveS3Endpoint, "archive-s3-endpoint", "",
+		"The S3-compatible endpoint to archive mail to. Defaults to AWS S3 for the configured region.")
+	flag.StringVar(&archiveS3Bucket, "archive-s3-bucket", "",
+		"The bucket to archive mail to when using the s3 archive backend.")
+	flag.StringVar(&archiveS3Region, "archive-s3-region", archive.DefaultS3Region,
+		"The region of the archive bucket.")
+	flag.BoolVar(&archiveS3PathStyle, "archive-s3-path-style", false,
+		"If set, address the archive bucket in the URL path instead of the host. Required by most S3-compatible stores.")
+	flag.StringVar(&auditInterval, "audit-interval", "0",
+		"Interval to audit the Mailform account for orphaned orders and drifted mail. "+
+			"Defaults to '0' which disables auditing.")
+	flag.StringVar(&auditMinOrphanAge, "audit-min-orphan-age", audit.DefaultMinOrphanAge.String(),
+		"How old an order without a mail must be before it's reported as orphaned.")
+	flag.BoolVar(&auditAutoCancelOrphans, "audit-auto-cancel-orphans", false,
+		"If set, the auditor cancels orphaned orders postk8s created for mail without a customer reference.")
+	flag.Float64Var(&mailformRequestsPerSecond, "mailform-requests-per-second", 5,
+		"Maximum rate of requests to Mailform shared by all mail. Set to '0' to disable rate limiting.")
+	flag.IntVar(&mailformBurst, "mailform-burst", 10,
+		"Maximum number of requests to Mailform that can be made at once.")
+	flag.IntVar(&mailformCircuitFailureThreshold, "mailform-circuit-failure-threshold", 5,
+		"Consecutive failed requests to Mailform before requests are paused. Set to '0' to disable circuit breaking.")
+	flag.StringVar(&mailformCircuitOpenTimeout, "mailform-circuit-open-timeout", provider.DefaultOpenTimeout.String(),
+		"How long requests to Mailform are paused before a trial request is made.")
+	flag.StringVar(&mailformCredentialProbeInterval, "mailform-credential-probe-interval",
+		provider.DefaultProbeInterval.String(),
+		"How often the Mailform API token is checked for the readiness probe. Set to '0' to disable the check.")
+	flag.StringVar(&orderLostPolicy, "order-lost-policy", string(mailformv1alpha1.OrderLostPolicyGiveUp),
+		fmt.Sprintf("What to do with mail whose order can no longer be found. One of '%s' or '%s'. "+
+			"Can be overridden per mail with spec.orderLostPolicy.",
+			mailformv1alpha1.OrderLostPolicyGiveUp, mailformv1alpha1.OrderLostPolicyRecreate))
+	flag.StringVar(&killSwitchConfigMap, "kill-switch-configmap", killswitch.DefaultName,
+		"Name of the ConfigMap that halts new orders while it sets 'halted' to 'true'. Set to '' to disable.")
+	flag.StringVar(&killSwitchNamespace, "kill-switch-namespace", getEnv(podNamespaceEnvVar, "postk8s-system"),
+		"Namespace of the kill switch ConfigMap.")
+	flag.StringVar(&runtimeConfigName, "runtime-config", runtimeconfig.DefaultName,
+		"Name of the cluster-scoped PostK8sConfig that overrides these flags at runtime. Set to '' to disable.")
+	flag.BoolVar(&documentPreflight, "document-preflight", true,
+		"Download and verify each document is a PDF before its order is created.")
+	flag.Int64Var(&documentMaxBytes, "document-max-bytes", document.DefaultMaxSize,
+		"Largest document that passes preflight.")
+	flag.StringVar(&documentFetchTimeout, "document-fetch-timeout", document.DefaultTimeout.String(),
+		"How long downloading a document for preflight may take.")
+	flag.IntVar(&documentMaxPages, "document-max-pages", 0,
+		"Most pages a document may have to pass preflight. Set to '0' to allow any number of pages.")
+	flag.IntVar(&documentPreviewPages, "document-preview-pages", document.DefaultPreviewPages,
+		"Number of pages in the preview rendered for each document that passes preflight. Set to '0' to disable previews.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081",
@@ -96,6 +206,57 @@ func main() {
 		os.Exit(1)
 	}
 
+	minSyncIntervalDuration, err := time.ParseDuration(minSyncInterval)
+	if err != nil {
+		setupLog.Error(err, "invalid min-sync-interval value", "min-sync-interval", minSyncInterval)
+		os.Exit(1)
+	}
+
+	var startLimiter *rate.Limiter
+	if startupSyncRate > 0 {
+		startLimiter = rate.NewLimiter(rate.Limit(startupSyncRate), max(startupSyncBurst, 1))
+	}
+
+	ttlAfterFinishedDuration, err := time.ParseDuration(ttlAfterFinished)
+	if err != nil {
+		setupLog.Error(err, "invalid ttl-after-finished value", "ttl-after-finished", ttlAfterFinished)
+		os.Exit(1)
+	}
+
+	auditIntervalDuration, err := time.ParseDuration(auditInterval)
+	if err != nil {
+		setupLog.Error(err, "invalid audit-interval value", "audit-interval", auditInterval)
+		os.Exit(1)
+	}
+
+	auditMinOrphanAgeDuration, err := time.ParseDuration(auditMinOrphanAge)
+	if err != nil {
+		setupLog.Error(err, "invalid audit-min-orphan-age value", "audit-min-orphan-age", auditMinOrphanAge)
+		os.Exit(1)
+	}
+
+	mailformCircuitOpenTimeoutDuration, err := time.ParseDuration(mailformCircuitOpenTimeout)
+	if err != nil {
+		setupLog.Error(err, "invalid mailform-circuit-open-timeout value",
+			"mailform-circuit-open-timeout", mailformCircuitOpenTimeout)
+		os.Exit(1)
+	}
+
+	mailformCredentialProbeIntervalDuration, err := time.ParseDuration(mailformCredentialProbeInterval)
+	if err != nil {
+		setupLog.Error(err, "invalid mailform-credential-probe-interval value",
+			"mailform-credential-probe-interval", mailformCredentialProbeInterval)
+		os.Exit(1)
+	}
+
+	// mailform.New accepts an empty token but every request would fail
+	if mailformAPIToken == "" && mailformAPITokenFile == "" {
+		err = fmt.Errorf("set --mailform-api-token-file, --mailform-api-token or the %s environment variable",
+			mailformApiTokenEnvVar)
+		setupLog.Error(err, "mailform API token is required")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -164,7 +325,21 @@ func main() {
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
+		Scheme: scheme,
+		// The kill switch is the only ConfigMap watched, so it's the only one cached instead of every ConfigMap in the
+		// cluster. The client reads other ConfigMaps, such as documents and previews, straight from the API server.
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}: {
+					Namespaces: map[string]cache.Config{
+						killSwitchNamespace: {FieldSelector: fields.OneTermEqualSelector("metadata.name", killSwitchConfigMap)},
+					},
+				},
+			},
+		},
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.ConfigMap{}}},
+		},
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -187,25 +362,194 @@ func main() {
 		os.Exit(1)
 	}
 
-	mailformClient, err := mailform.New(&mailform.Config{
-		Token: mailformAPIToken,
+	var credentialProbe *provider.Probe
+	var unguardedMailformClient provider.Client
+	mailformAPITokenFunc := func() string { return mailformAPIToken }
+
+	if mailformAPITokenFile != "" {
+		tokenFileClient, err := provider.NewTokenFileClient(&provider.TokenFileClientConfig{
+			Path: mailformAPITokenFile,
+			// Check a rotated token right away instead of waiting for the next probe
+			OnReload: func() {
+				if credentialProbe != nil {
+					credentialProbe.Probe() // nolint:errcheck
+				}
+			},
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create mailform client", "mailform-api-token-file", mailformAPITokenFile)
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(tokenFileClient); err != nil {
+			setupLog.Error(err, "unable to add mailform API token reloader")
+			os.Exit(1)
+		}
+
+		unguardedMailformClient = tokenFileClient
+		mailformAPITokenFunc = tokenFileClient.Token
+	} else {
+		unguardedMailformClient, err = mailform.New(&mailform.Config{
+			Token: mailformAPIToken,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create mailform client")
+			os.Exit(1)
+		}
+	}
+
+	// Look up orders whose requests fail, so they're only treated as lost when Mailform responds 404
+	statusMailformClient, err := provider.NewStatusClient(&provider.StatusClientConfig{
+		Client:    unguardedMailformClient,
+		TokenFunc: mailformAPITokenFunc,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create mailform client")
+		os.Exit(1)
+	}
+
+	// Share one rate limiter and circuit breaker between everything that calls Mailform
+	mailformClient, err := provider.NewGuardedClient(&provider.GuardedClientConfig{
+		Client:            statusMailformClient,
+		RequestsPerSecond: mailformRequestsPerSecond,
+		Burst:             mailformBurst,
+		FailureThreshold:  mailformCircuitFailureThreshold,
+		OpenTimeout:       mailformCircuitOpenTimeoutDuration,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create mailform client")
 		os.Exit(1)
 	}
 
+	// Flags are the defaults for anything the PostK8sConfig doesn't set
+	runtimeConfig, err := runtimeconfig.New(&runtimeconfig.Config{
+		Defaults: runtimeconfig.Settings{
+			SyncInterval:      syncIntervalDuration,
+			MinSyncInterval:   minSyncIntervalDuration,
+			SyncJitter:        syncJitter,
+			TTLAfterFinished:  ttlAfterFinishedDuration,
+			OrderLostPolicy:   mailformv1alpha1.OrderLostPolicy(orderLostPolicy),
+			RequestsPerSecond: mailformRequestsPerSecond,
+			Burst:             max(mailformBurst, 1),
+			Archive:           true,
+			KillSwitch:        true,
+		},
+		OnChange: func(settings runtimeconfig.Settings) {
+			mailformClient.SetRateLimit(settings.RequestsPerSecond, settings.Burst)
+		},
+	})
+	if err != nil {
+		setupLog.Error(err, "invalid configuration", "sync-interval", syncInterval, "min-sync-interval", minSyncInterval,
+			"sync-jitter", syncJitter, "order-lost-policy", orderLostPolicy)
+		os.Exit(1)
+	}
+
+	var archiver controller.ArchiverIface
+	if archiveBackend != "" {
+		archiver, err = newArchiver(mgr.GetClient(), archiveBackend, archivePath, &archive.S3Config{
+			Endpoint:        archiveS3Endpoint,
+			Bucket:          archiveS3Bucket,
+			Region:          archiveS3Region,
+			AccessKeyID:     os.Getenv(awsAccessKeyIDEnvVar),
+			SecretAccessKey: os.Getenv(awsSecretAccessKeyEnvVar),
+			SessionToken:    os.Getenv(awsSessionTokenEnvVar),
+			PathStyle:       archiveS3PathStyle,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create archiver", "archive-backend", archiveBackend)
+			os.Exit(1)
+		}
+	}
+
+	var killSwitch controller.KillSwitchIface
+	if killSwitchConfigMap != "" {
+		killSwitch, err = killswitch.New(&killswitch.Config{
+			Client:    mgr.GetCache(),
+			Namespace: killSwitchNamespace,
+			Name:      killSwitchConfigMap,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create kill switch")
+			os.Exit(1)
+		}
+	}
+
+	var preflight *document.Preflight
+	if documentPreflight {
+		documentFetchTimeoutDuration, err := time.ParseDuration(documentFetchTimeout)
+		if err != nil {
+			setupLog.Error(err, "invalid document-fetch-timeout value", "document-fetch-timeout", documentFetchTimeout)
+			os.Exit(1)
+		}
+
+		preflight, err = document.NewPreflight(&document.PreflightConfig{
+			MaxSize:  documentMaxBytes,
+			Timeout:  documentFetchTimeoutDuration,
+			MaxPages: documentMaxPages,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create document preflight")
+			os.Exit(1)
+		}
+	}
+
 	if err := (&controller.MailReconciler{
-		Client:         mgr.GetClient(),
-		MailformClient: mailformClient,
-		SyncInterval:   syncIntervalDuration,
-		Scheme:         mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		APIReader:               mgr.GetAPIReader(),
+		MailformClient:          mailformClient,
+		RuntimeConfig:           runtimeConfig,
+		Archiver:                archiver,
+		StartLimiter:            startLimiter,
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		KillSwitch:              killSwitch,
+		Preflight:               preflight,
+		PreviewPages:            documentPreviewPages,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Mail")
 		os.Exit(1)
 	}
+	if runtimeConfigName != "" {
+		if err := (&controller.PostK8sConfigReconciler{
+			Client:        mgr.GetClient(),
+			Scheme:        mgr.GetScheme(),
+			RuntimeConfig: runtimeConfig,
+			Name:          runtimeConfigName,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PostK8sConfig")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if auditIntervalDuration > 0 {
+		lister, err := audit.NewHTTPLister(&audit.HTTPListerConfig{
+			TokenFunc: mailformAPITokenFunc,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create order lister")
+			os.Exit(1)
+		}
+
+		auditor, err := audit.New(&audit.Config{
+			Client:            mgr.GetClient(),
+			Lister:            lister,
+			MailformClient:    mailformClient,
+			Interval:          auditIntervalDuration,
+			MinOrphanAge:      auditMinOrphanAgeDuration,
+			AutoCancelOrphans: auditAutoCancelOrphans,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create auditor")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(auditor); err != nil {
+			setupLog.Error(err, "unable to add auditor")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -215,6 +559,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	if mailformCredentialProbeIntervalDuration > 0 {
+		// The probe calls Mailform directly so it isn't limited or tripped by the guarded client
+		credentialProbe, err = provider.NewProbe(&provider.ProbeConfig{
+			TokenFunc: mailformAPITokenFunc,
+			Interval:  mailformCredentialProbeIntervalDuration,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create mailform credential probe")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(credentialProbe); err != nil {
+			setupLog.Error(err, "unable to add mailform credential probe")
+			os.Exit(1)
+		}
+
+		if err := mgr.AddReadyzCheck("mailform-credentials", credentialProbe.Check); err != nil {
+			setupLog.Error(err, "unable to set up mailform credential check")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
@@ -222,6 +588,29 @@ func main() {
 	}
 }
 
+// newArchiver creates an archiver for the given backend.
+func newArchiver(c client.Reader, backend, path string, s3Config *archive.S3Config) (*archive.Archiver, error) {
+	var archiveBackend archive.Backend
+	var err error
+
+	switch backend {
+	case archiveBackendFilesystem:
+		archiveBackend, err = archive.NewFilesystemBackend(path)
+	case archiveBackendS3:
+		archiveBackend, err = archive.NewS3Backend(s3Config)
+	default:
+		return nil, fmt.Errorf("unsupported archive backend: %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return archive.New(&archive.Config{
+		Backend: archiveBackend,
+		Client:  c,
+	})
+}
+
 // getEnv retrieves the value of the environment variable named by the key.
 // If the variable is not present in the environment, then the default value is returned.
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,239 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/document"
+)
+
+const (
+	// DefaultMaxDocumentSize is the largest document that will be copied into the archive.
+	DefaultMaxDocumentSize = 50 << 20
+	// DefaultTimeout is the default timeout for downloading documents to archive.
+	DefaultTimeout = time.Second * 30
+	// recordName is the name of the archived record within a mail's archive location.
+	recordName = "record.json"
+	// documentName is the name of the archived document within a mail's archive location.
+	documentName = "document.pdf"
+)
+
+var (
+	// ErrNilBackend is returned when a nil backend is passed to New().
+	ErrNilBackend = errors.New("archive backend cannot be nil")
+	// ErrDocumentTooLarge is returned when a document exceeds the max document size.
+	ErrDocumentTooLarge = errors.New("document exceeds max archive size")
+	// ErrDocumentNotArchived is noted in records of mail whose document was built by the operator but wasn't archived
+	// when its order was created. Built documents can't be read again since they depend on when they were built.
+	ErrDocumentNotArchived = errors.New("document was built for the order and not archived when it was created")
+)
+
+// Backend is the storage that archived objects are written to.
+type Backend interface {
+	// Put writes the object at key and returns its location.
+	Put(ctx context.Context, key string, body []byte, contentType string) (string, error)
+	// Location returns the location of key without writing anything.
+	Location(key string) string
+}
+
+// Config is the configuration used to create an Archiver.
+type Config struct {
+	Backend    Backend
+	HTTPClient *http.Client
+	// Client is used to read documents stored in ConfigMaps.
+	Client          client.Reader
+	MaxDocumentSize int64
+}
+
+// Archiver writes records of finished mail to a storage backend.
+type Archiver struct {
+	backend         Backend
+	httpClient      *http.Client
+	client          client.Reader
+	maxDocumentSize int64
+}
+
+// ResolvedAddress is the address of a line item as formatted by Mailform.
+type ResolvedAddress struct {
+	LineItemID string `json:"lineItemID,omitempty"`
+	To         string `json:"to"`
+	From       string `json:"from"`
+}
+
+// Record is what is archived for each mail.
+type Record struct {
+	Name              string                      `json:"name"`
+	Namespace         string                      `json:"namespace"`
+	UID               string                      `json:"uid"`
+	OrderID           string                      `json:"orderID"`
+	Spec              mailformv1alpha1.MailSpec   `json:"spec"`
+	Status            mailformv1alpha1.MailStatus `json:"status"`
+	ResolvedAddresses []ResolvedAddress           `json:"resolvedAddresses,omitempty"`
+	Document          string                      `json:"document,omitempty"`
+	// DocumentError is why the document couldn't be archived, e.g. its URL no longer exists.
+	DocumentError string    `json:"documentError,omitempty"`
+	ArchivedAt    time.Time `json:"archivedAt"`
+}
+
+// New returns a new Archiver.
+func New(c *Config) (*Archiver, error) {
+	if c == nil || c.Backend == nil {
+		return nil, ErrNilBackend
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	maxDocumentSize := int64(DefaultMaxDocumentSize)
+	if c.MaxDocumentSize > 0 {
+		maxDocumentSize = c.MaxDocumentSize
+	}
+
+	return &Archiver{
+		backend:         c.Backend,
+		httpClient:      httpClient,
+		client:          c.Client,
+		maxDocumentSize: maxDocumentSize,
+	}, nil
+}
+
+// ArchiveDocument writes a copy of the document submitted with the mail's order to the backend.
+// Returns the location of the document, which Archive references once the mail's DocumentStatus records it.
+func (a *Archiver) ArchiveDocument(ctx context.Context, mail *mailformv1alpha1.Mail, document []byte) (string, error) {
+	if int64(len(document)) > a.maxDocumentSize {
+		return "", ErrDocumentTooLarge
+	}
+
+	location, err := a.backend.Put(ctx, path.Join(Prefix(mail), documentName), document, "application/pdf")
+	if err != nil {
+		return "", fmt.Errorf("archiving document: %w", err)
+	}
+
+	return location, nil
+}
+
+// Archive writes a record of the mail to the backend, along with a copy of its document if it wasn't archived
+// when the order was created. Documents that can't be read are noted in the record rather than failing,
+// since they may have changed or gone away long after the order was sent.
+// Returns the location of the archive.
+func (a *Archiver) Archive(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) (string, error) {
+	prefix := Prefix(mail)
+
+	record := &Record{
+		Name:       mail.Name,
+		Namespace:  mail.Namespace,
+		UID:        string(mail.UID),
+		OrderID:    mail.Status.ID,
+		Spec:       *mail.Spec.DeepCopy(),
+		Status:     *mail.Status.DeepCopy(),
+		ArchivedAt: time.Now().UTC(),
+	}
+
+	if order != nil {
+		for _, lineItem := range order.Data.Lineitems {
+			record.ResolvedAddresses = append(record.ResolvedAddresses, ResolvedAddress{
+				LineItemID: lineItem.ID,
+				To:         lineItem.To.Formatted,
+				From:       lineItem.From.Formatted,
+			})
+		}
+	}
+
+	// Copy the document first so the record only ever references documents that exist
+	if mail.Status.Document != nil && mail.Status.Document.ArchiveLocation != "" {
+		record.Document = mail.Status.Document.ArchiveLocation
+	} else {
+		document, err := a.readDocument(ctx, mail)
+		switch {
+		case err != nil:
+			record.DocumentError = fmt.Sprintf("reading document: %s", err)
+		case document != nil:
+			record.Document, err = a.ArchiveDocument(ctx, mail, document)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	body, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = a.backend.Put(ctx, path.Join(prefix, recordName), body, "application/json")
+	if err != nil {
+		return "", fmt.Errorf("archiving record: %w", err)
+	}
+
+	return a.backend.Location(prefix + "/"), nil
+}
+
+// Prefix returns the key prefix mail is archived under.
+func Prefix(mail *mailformv1alpha1.Mail) string {
+	return path.Join(mail.Namespace, fmt.Sprintf("%s-%s", mail.Name, mail.UID))
+}
+
+// readDocument returns the contents of the mail's document from the URL, local file path or ConfigMap.
+// It's only used for mail whose document wasn't archived when its order was created.
+func (a *Archiver) readDocument(ctx context.Context, mail *mailformv1alpha1.Mail) ([]byte, error) {
+	switch {
+	case len(mail.Spec.Documents) > 0 || mail.Spec.CoverPage != nil || mail.Spec.Body != nil:
+		return nil, ErrDocumentNotArchived
+	case mail.Spec.ConfigMapRef != nil && a.client != nil:
+		return document.ReadConfigMap(ctx, a.client, mail.Namespace, mail.Spec.ConfigMapRef)
+	case mail.Spec.FilePath != "":
+		f, err := os.Open(mail.Spec.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close() // nolint:errcheck
+
+		return a.readLimited(f)
+	case mail.Spec.URL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, mail.Spec.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := a.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close() // nolint:errcheck
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status downloading %s: %s", mail.Spec.URL, resp.Status)
+		}
+
+		return a.readLimited(resp.Body)
+	}
+
+	return nil, nil
+}
+
+// readLimited reads r, failing if it is larger than the max document size.
+func (a *Archiver) readLimited(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, a.maxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > a.maxDocumentSize {
+		return nil, ErrDocumentTooLarge
+	}
+
+	return b, nil
+}
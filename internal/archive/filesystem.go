@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrEmptyDirectory is returned when no directory is passed to NewFilesystemBackend().
+var ErrEmptyDirectory = errors.New("archive directory cannot be empty")
+
+// FilesystemBackend archives objects to a local directory such as a mounted PVC.
+type FilesystemBackend struct {
+	dir string
+}
+
+// NewFilesystemBackend returns a backend that writes objects beneath dir, creating it if needed.
+func NewFilesystemBackend(dir string) (*FilesystemBackend, error) {
+	if dir == "" {
+		return nil, ErrEmptyDirectory
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilesystemBackend{dir: dir}, nil
+}
+
+// Put writes body to key beneath the backend's directory.
+// Objects are written to a temporary file and renamed so partial writes are never visible.
+func (f *FilesystemBackend) Put(_ context.Context, key string, body []byte, _ string) (string, error) {
+	dest := filepath.Join(f.dir, filepath.FromSlash(key))
+
+	err := os.MkdirAll(filepath.Dir(dest), 0o750)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".archive-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // nolint:errcheck
+
+	_, err = tmp.Write(body)
+	if err != nil {
+		tmp.Close() // nolint:errcheck
+		return "", err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp.Name(), dest)
+	if err != nil {
+		return "", err
+	}
+
+	return f.Location(key), nil
+}
+
+// Location returns the file URL of key.
+func (f *FilesystemBackend) Location(key string) string {
+	return "file://" + filepath.ToSlash(filepath.Join(f.dir, filepath.FromSlash(key))) + trailingSlash(key)
+}
+
+// trailingSlash preserves a trailing slash on keys that refer to a prefix.
+func trailingSlash(key string) string {
+	if len(key) > 0 && key[len(key)-1] == '/' {
+		return "/"
+	}
+
+	return ""
+}
@@ -0,0 +1,302 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultS3Region is used when no region is configured.
+	DefaultS3Region = "us-east-1"
+	// s3SigningAlgorithm is the AWS signature version 4 algorithm identifier.
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	// s3Service is the service name used in the signing scope.
+	s3Service = "s3"
+	// amzDateFormat is the timestamp format used by signature version 4.
+	amzDateFormat = "20060102T150405Z"
+)
+
+var (
+	// ErrNilS3Config is returned when a nil config is passed to NewS3Backend().
+	ErrNilS3Config = errors.New("s3 config cannot be nil")
+	// ErrEmptyBucket is returned when no bucket is configured.
+	ErrEmptyBucket = errors.New("s3 bucket cannot be empty")
+)
+
+// S3Config is the configuration used to communicate with an S3-compatible API.
+type S3Config struct {
+	// Endpoint is the base URL of the API. Defaults to https://s3.<region>.amazonaws.com
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// PathStyle addresses the bucket in the path rather than the host. Most S3-compatible stores require this.
+	PathStyle  bool
+	HTTPClient *http.Client
+}
+
+// S3Backend archives objects to an S3-compatible object store.
+type S3Backend struct {
+	endpoint        *url.URL
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	pathStyle       bool
+	httpClient      *http.Client
+	now             func() time.Time
+}
+
+// NewS3Backend returns a backend that writes objects to an S3 bucket.
+func NewS3Backend(c *S3Config) (*S3Backend, error) {
+	if c == nil {
+		return nil, ErrNilS3Config
+	}
+
+	if c.Bucket == "" {
+		return nil, ErrEmptyBucket
+	}
+
+	region := DefaultS3Region
+	if c.Region != "" {
+		region = c.Region
+	}
+
+	endpoint := fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	if c.Endpoint != "" {
+		endpoint = c.Endpoint
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpointURL.Scheme == "" || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", endpoint)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &S3Backend{
+		endpoint:        endpointURL,
+		bucket:          c.Bucket,
+		region:          region,
+		accessKeyID:     c.AccessKeyID,
+		secretAccessKey: c.SecretAccessKey,
+		sessionToken:    c.SessionToken,
+		pathStyle:       c.PathStyle,
+		httpClient:      httpClient,
+		now:             time.Now,
+	}, nil
+}
+
+// Put uploads body to key in the bucket.
+func (s *S3Backend) Put(ctx context.Context, key string, body []byte, contentType string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	s.sign(req, body)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unexpected status uploading %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return s.Location(key), nil
+}
+
+// Location returns the s3:// URL of key.
+func (s *S3Backend) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key)
+}
+
+// objectURL returns the HTTP URL of key.
+func (s *S3Backend) objectURL(key string) string {
+	u := *s.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+
+	if s.pathStyle {
+		u.Path = fmt.Sprintf("%s/%s/%s", basePath, s.bucket, key)
+	} else {
+		u.Host = fmt.Sprintf("%s.%s", s.bucket, u.Host)
+		u.Path = fmt.Sprintf("%s/%s", basePath, key)
+	}
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// sign adds AWS signature version 4 headers to the request.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3Backend) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format("20060102"), s.region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, s.accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalizeHeaders returns the canonical headers and signed headers list of the request.
+// The host and all x-amz-* headers are always signed, as is the content type when set.
+func canonicalizeHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "range" || lower == "content-md5" {
+			trimmed := make([]string, 0, len(values))
+			for _, v := range values {
+				trimmed = append(trimmed, strings.Join(strings.Fields(v), " "))
+			}
+			headers[lower] = strings.Join(trimmed, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(":")
+		b.WriteString(headers[name])
+		b.WriteString("\n")
+	}
+
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalURI returns the URI-encoded path of the request.
+func canonicalURI(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			unescaped = segment
+		}
+		segments[i] = uriEncode(unescaped)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the sorted, URI-encoded query string of the request.
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes everything except the unreserved characters as required by signature version 4.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+// sha256Hex returns the hex encoded SHA-256 of b.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,394 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/orderinput"
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+const (
+	// DefaultReportName is the name of the MailAuditReport written by the auditor.
+	DefaultReportName = "postk8s"
+	// DefaultMinOrphanAge gives new orders time to be recorded on their Mail before they're considered orphaned.
+	DefaultMinOrphanAge = time.Hour
+
+	// typeAudited represents whether the last audit succeeded
+	typeAudited = "Audited"
+
+	// Drift reasons
+	reasonStateMismatch = "StateMismatch"
+	reasonTotalMismatch = "TotalMismatch"
+	reasonOrderNotFound = "OrderNotFound"
+	reasonOrderIDLost   = "OrderIDLost"
+)
+
+var (
+	// ErrNilConfig is returned when no config is provided to New.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrNilClient is returned when no kubernetes client is provided to New.
+	ErrNilClient = errors.New("client cannot be nil")
+	// ErrNilLister is returned when no order lister is provided to New.
+	ErrNilLister = errors.New("order lister cannot be nil")
+	// ErrInvalidInterval is returned when the audit interval isn't positive.
+	ErrInvalidInterval = errors.New("interval must be greater than 0")
+)
+
+// MailformIface is an interface to Get/Cancel orders from Mailform.
+type MailformIface interface {
+	GetOrder(o string) (*mailform.Order, error)
+	CancelOrder(o string) error
+}
+
+// Config is the configuration used to create an Auditor.
+type Config struct {
+	Client client.Client
+	Lister OrderLister
+	// MailformClient confirms orders missing from the listing and cancels orphans.
+	MailformClient MailformIface
+	Interval       time.Duration
+	// MinOrphanAge defaults to DefaultMinOrphanAge.
+	MinOrphanAge time.Duration
+	// AutoCancelOrphans cancels unfinished orders with no owning Mail that postk8s created for Mail without their own
+	// customer reference. Other orphans may have been placed from the dashboard or by other systems and are only reported.
+	AutoCancelOrphans bool
+	// ReportName defaults to DefaultReportName.
+	ReportName string
+}
+
+// Auditor periodically compares Mail objects with the orders in the Mailform account.
+type Auditor struct {
+	client            client.Client
+	lister            OrderLister
+	mailformClient    MailformIface
+	interval          time.Duration
+	minOrphanAge      time.Duration
+	autoCancelOrphans bool
+	reportName        string
+}
+
+// +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=mailauditreports,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=mailauditreports/status,verbs=get;update;patch
+
+// New returns a new Auditor.
+func New(c *Config) (*Auditor, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Client == nil {
+		return nil, ErrNilClient
+	}
+
+	if c.Lister == nil {
+		return nil, ErrNilLister
+	}
+
+	if c.Interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	minOrphanAge := DefaultMinOrphanAge
+	if c.MinOrphanAge > 0 {
+		minOrphanAge = c.MinOrphanAge
+	}
+
+	reportName := DefaultReportName
+	if c.ReportName != "" {
+		reportName = c.ReportName
+	}
+
+	return &Auditor{
+		client:            c.Client,
+		lister:            c.Lister,
+		mailformClient:    c.MailformClient,
+		interval:          c.Interval,
+		minOrphanAge:      minOrphanAge,
+		autoCancelOrphans: c.AutoCancelOrphans,
+		reportName:        reportName,
+	}, nil
+}
+
+// Start audits every interval until the context is cancelled. Implements manager.Runnable.
+func (a *Auditor) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("auditor")
+	ctx = logf.IntoContext(ctx, log)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		_, err := a.Audit(ctx)
+		if err != nil {
+			log.Error(err, "audit failed")
+		}
+	}, a.interval)
+
+	return nil
+}
+
+// NeedLeaderElection ensures only one replica audits and cancels orphans.
+func (a *Auditor) NeedLeaderElection() bool {
+	return true
+}
+
+// Audit compares Mail with the account's orders, cancels orphans if enabled and writes the report.
+func (a *Auditor) Audit(ctx context.Context) (*mailformv1alpha1.MailAuditReportStatus, error) {
+	log := logf.FromContext(ctx)
+
+	status, auditErr := a.audit(ctx)
+
+	now := metav1.Now()
+	status.LastAuditTime = now
+
+	if auditErr != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    typeAudited,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AuditFailed",
+			Message: auditErr.Error(),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    typeAudited,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Audited",
+			Message: fmt.Sprintf("Found %d orphaned orders and %d drifted mail", status.OrphanCount, status.DriftCount),
+		})
+	}
+
+	err := a.writeReport(ctx, status)
+	if err != nil {
+		return status, errors.Join(auditErr, err)
+	}
+
+	if auditErr == nil {
+		log.Info("audited mailform orders",
+			"orders", status.OrdersAudited, "mail", status.MailAudited,
+			"orphans", status.OrphanCount, "drifted", status.DriftCount)
+	}
+
+	return status, auditErr
+}
+
+// audit builds the report status. A failed audit keeps the results from the previous report.
+func (a *Auditor) audit(ctx context.Context) (*mailformv1alpha1.MailAuditReportStatus, error) {
+	log := logf.FromContext(ctx)
+
+	previous, err := a.getReport(ctx)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return &mailformv1alpha1.MailAuditReportStatus{}, err
+	}
+
+	status := previous.Status.DeepCopy()
+
+	mailList := &mailformv1alpha1.MailList{}
+	err = a.client.List(ctx, mailList)
+	if err != nil {
+		return status, err
+	}
+
+	orders, err := a.lister.ListOrders(ctx)
+	if err != nil {
+		return status, fmt.Errorf("listing orders: %w", err)
+	}
+
+	orphans, drifted := compare(mailList.Items, orders, time.Now(), a.minOrphanAge)
+	drifted = append(drifted, a.findMissingOrders(mailList.Items, orders)...)
+
+	if a.autoCancelOrphans && a.mailformClient != nil {
+		for i := range orphans {
+			// Orders placed from the dashboard or by other systems are only reported
+			if !orderinput.IsMailReference(orphans[i].CustomerReference) {
+				continue
+			}
+
+			err := a.mailformClient.CancelOrder(orphans[i].ID)
+			if err != nil {
+				log.Error(err, "failed to cancel orphaned order", "orderID", orphans[i].ID)
+				continue
+			}
+
+			orphans[i].Cancelled = true
+			log.Info("cancelled orphaned order", "orderID", orphans[i].ID)
+		}
+	}
+
+	status.OrdersAudited = len(orders)
+	status.MailAudited = len(mailList.Items)
+	status.Orphans = orphans
+	status.OrphanCount = len(orphans)
+	status.Drifted = drifted
+	status.DriftCount = len(drifted)
+
+	return status, nil
+}
+
+// findMissingOrders confirms unfinished Mail whose orders aren't in the listing still exist in Mailform.
+// The listing is complete, so this is usually only orders that are really missing or were created since it was listed.
+func (a *Auditor) findMissingOrders(mails []mailformv1alpha1.Mail, orders []Order) []mailformv1alpha1.DriftedMail {
+	if a.mailformClient == nil {
+		return nil
+	}
+
+	listed := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		listed[order.ID] = true
+	}
+
+	var drifted []mailformv1alpha1.DriftedMail
+	for _, mail := range mails {
+		if mail.Status.ID == "" || listed[mail.Status.ID] || isFinished(mail.Status.State) || mail.Status.Sent {
+			continue
+		}
+
+		// Only orders Mailform says don't exist are drifted, other errors may succeed next audit
+		_, err := a.mailformClient.GetOrder(mail.Status.ID)
+		if provider.Classify(err) == provider.ClassNotFound {
+			drifted = append(drifted, mailformv1alpha1.DriftedMail{
+				Namespace: mail.Namespace,
+				Name:      mail.Name,
+				OrderID:   mail.Status.ID,
+				Reason:    reasonOrderNotFound,
+				Message:   err.Error(),
+			})
+		}
+	}
+
+	return drifted
+}
+
+// compare matches orders to Mail by order ID, falling back to the customer reference of their orders for Mail that lost
+// their ID.
+// Unmatched unfinished orders older than minOrphanAge are orphans.
+func compare(mails []mailformv1alpha1.Mail, orders []Order, now time.Time, minOrphanAge time.Duration) ([]mailformv1alpha1.OrphanedOrder, []mailformv1alpha1.DriftedMail) {
+	byID := map[string]*mailformv1alpha1.Mail{}
+	byReference := map[string]*mailformv1alpha1.Mail{}
+
+	for i := range mails {
+		mail := &mails[i]
+
+		switch {
+		case mail.Status.ID != "":
+			byID[mail.Status.ID] = mail
+		case mail.Spec.AdoptOrderID != "":
+			byID[mail.Spec.AdoptOrderID] = mail
+		case orderinput.CustomerReference(mail) != "":
+			byReference[orderinput.CustomerReference(mail)] = mail
+		}
+	}
+
+	orphans := []mailformv1alpha1.OrphanedOrder{}
+	drifted := []mailformv1alpha1.DriftedMail{}
+
+	for _, order := range orders {
+		if mail, ok := byID[order.ID]; ok {
+			// Mail that are still adopting the order haven't synced any status yet
+			if mail.Status.ID == "" {
+				continue
+			}
+
+			switch {
+			case mail.Status.State != order.State:
+				drifted = append(drifted, driftedMail(mail, order.ID, reasonStateMismatch,
+					fmt.Sprintf("Stored state %q but Mailform reports %q", mail.Status.State, order.State)))
+			case mail.Status.Total != order.Total:
+				drifted = append(drifted, driftedMail(mail, order.ID, reasonTotalMismatch,
+					fmt.Sprintf("Stored total %d but Mailform reports %d", mail.Status.Total, order.Total)))
+			}
+
+			continue
+		}
+
+		if mail, ok := byReference[order.CustomerReference]; ok && order.CustomerReference != "" {
+			drifted = append(drifted, driftedMail(mail, order.ID, reasonOrderIDLost,
+				fmt.Sprintf("Mail has no order ID but order %s has its customer reference %q", order.ID, order.CustomerReference)))
+			continue
+		}
+
+		if isFinished(order.State) || now.Sub(order.Created) < minOrphanAge {
+			continue
+		}
+
+		orphans = append(orphans, mailformv1alpha1.OrphanedOrder{
+			ID:                order.ID,
+			State:             order.State,
+			CustomerReference: order.CustomerReference,
+			Total:             order.Total,
+			Created:           metav1.NewTime(order.Created),
+		})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].ID < orphans[j].ID
+	})
+
+	sort.Slice(drifted, func(i, j int) bool {
+		if drifted[i].Namespace != drifted[j].Namespace {
+			return drifted[i].Namespace < drifted[j].Namespace
+		}
+		return drifted[i].Name < drifted[j].Name
+	})
+
+	return orphans, drifted
+}
+
+// driftedMail returns a drift entry for the mail.
+func driftedMail(mail *mailformv1alpha1.Mail, orderID, reason, message string) mailformv1alpha1.DriftedMail {
+	return mailformv1alpha1.DriftedMail{
+		Namespace: mail.Namespace,
+		Name:      mail.Name,
+		OrderID:   orderID,
+		Reason:    reason,
+		Message:   message,
+	}
+}
+
+// isFinished reports whether an order state is final.
+func isFinished(state string) bool {
+	return state == mailform.StatusFulfilled || state == mailform.StatusCancelled
+}
+
+// getReport fetches the report, returning an empty report if it doesn't exist yet.
+func (a *Auditor) getReport(ctx context.Context) (*mailformv1alpha1.MailAuditReport, error) {
+	report := &mailformv1alpha1.MailAuditReport{}
+
+	err := a.client.Get(ctx, types.NamespacedName{Name: a.reportName}, report)
+	if err != nil {
+		return &mailformv1alpha1.MailAuditReport{}, err
+	}
+
+	return report, nil
+}
+
+// writeReport creates the report if needed and updates its status.
+func (a *Auditor) writeReport(ctx context.Context, status *mailformv1alpha1.MailAuditReportStatus) error {
+	report, err := a.getReport(ctx)
+	if apierrors.IsNotFound(err) {
+		report = &mailformv1alpha1.MailAuditReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: a.reportName,
+			},
+		}
+
+		err = a.client.Create(ctx, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	report.Status = *status
+
+	return a.client.Status().Update(ctx, report)
+}
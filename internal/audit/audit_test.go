@@ -0,0 +1,444 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+// mockLister returns a fixed list of orders.
+type mockLister struct {
+	orders  []Order
+	mockErr error
+}
+
+// ListOrders returns the mock orders. Will return mockErr if not nil
+func (m mockLister) ListOrders(ctx context.Context) ([]Order, error) {
+	return m.orders, m.mockErr
+}
+
+// mockMailformClient records fetched and cancelled orders.
+type mockMailformClient struct {
+	missing   map[string]bool
+	failing   map[string]bool
+	fetched   []string
+	cancelled []string
+}
+
+// GetOrder records the fetched order and returns an error for missing and failing orders
+func (m *mockMailformClient) GetOrder(o string) (*mailform.Order, error) {
+	m.fetched = append(m.fetched, o)
+
+	if m.missing[o] {
+		return nil, provider.ErrOrderNotFound
+	}
+
+	if m.failing[o] {
+		return nil, errors.New("connection reset")
+	}
+
+	return &mailform.Order{}, nil
+}
+
+// CancelOrder records the cancelled order
+func (m *mockMailformClient) CancelOrder(o string) error {
+	m.cancelled = append(m.cancelled, o)
+	return nil
+}
+
+func newMail(name string, spec mailformv1alpha1.MailSpec, status mailformv1alpha1.MailStatus) *mailformv1alpha1.Mail {
+	spec.Service = "USPS_STANDARD"
+
+	return &mailformv1alpha1.Mail{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec:   spec,
+		Status: status,
+	}
+}
+
+var _ = Describe("Audit", func() {
+	var (
+		ctx        context.Context
+		scheme     *runtime.Scheme
+		fakeClient client.Client
+		now        time.Time
+		orders     []Order
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+
+		scheme = runtime.NewScheme()
+		utilruntime.Must(mailformv1alpha1.AddToScheme(scheme))
+
+		fakeClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithStatusSubresource(&mailformv1alpha1.MailAuditReport{}).
+			WithObjects(
+				newMail("in-sync", mailformv1alpha1.MailSpec{}, mailformv1alpha1.MailStatus{
+					ID: "order-1", State: mailform.StatusQueued, Total: 100,
+				}),
+				newMail("stale", mailformv1alpha1.MailSpec{}, mailformv1alpha1.MailStatus{
+					ID: "order-2", State: mailform.StatusQueued, Total: 100,
+				}),
+				newMail("lost-id", mailformv1alpha1.MailSpec{CustomerReference: "invoice-42"}, mailformv1alpha1.MailStatus{}),
+				newMail("missing", mailformv1alpha1.MailSpec{}, mailformv1alpha1.MailStatus{
+					ID: "order-gone", State: mailform.StatusAwaitingFulfillment,
+				}),
+				newMail("adopting", mailformv1alpha1.MailSpec{AdoptOrderID: "order-5"}, mailformv1alpha1.MailStatus{}),
+			).
+			Build()
+
+		orders = []Order{
+			{ID: "order-1", State: mailform.StatusQueued, Total: 100, Created: now.Add(-48 * time.Hour)},
+			{ID: "order-2", State: mailform.StatusFulfilled, Total: 100, Created: now.Add(-48 * time.Hour)},
+			{ID: "order-3", State: mailform.StatusQueued, CustomerReference: "invoice-42", Created: now.Add(-48 * time.Hour)},
+			{ID: "order-4", State: mailform.StatusQueued, Total: 250, Created: now.Add(-48 * time.Hour)},
+			{ID: "order-5", State: mailform.StatusQueued, Created: now.Add(-48 * time.Hour)},
+			{ID: "order-new", State: mailform.StatusQueued, Created: now.Add(-time.Minute)},
+			{ID: "order-done", State: mailform.StatusFulfilled, Created: now.Add(-48 * time.Hour)},
+		}
+	})
+
+	It("should return errors for invalid config", func() {
+		_, err := New(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = New(&Config{Lister: mockLister{}, Interval: time.Hour})
+		Expect(err).To(MatchError(ErrNilClient))
+
+		_, err = New(&Config{Client: fakeClient, Interval: time.Hour})
+		Expect(err).To(MatchError(ErrNilLister))
+
+		_, err = New(&Config{Client: fakeClient, Lister: mockLister{}})
+		Expect(err).To(MatchError(ErrInvalidInterval))
+	})
+
+	It("should report orphaned orders and drifted mail", func() {
+		mailformClient := &mockMailformClient{missing: map[string]bool{"order-gone": true}}
+
+		auditor, err := New(&Config{
+			Client:         fakeClient,
+			Lister:         mockLister{orders: orders},
+			MailformClient: mailformClient,
+			Interval:       time.Hour,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = auditor.Audit(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		report := &mailformv1alpha1.MailAuditReport{}
+		Expect(fakeClient.Get(ctx, types.NamespacedName{Name: DefaultReportName}, report)).To(Succeed())
+
+		Expect(report.Status.OrdersAudited).To(Equal(len(orders)))
+		Expect(report.Status.MailAudited).To(Equal(5))
+		Expect(report.Status.OrphanCount).To(Equal(1))
+		Expect(report.Status.Orphans[0].ID).To(Equal("order-4"))
+		Expect(report.Status.Orphans[0].Total).To(Equal(250))
+		Expect(report.Status.Orphans[0].Cancelled).To(BeFalse())
+
+		reasons := map[string]string{}
+		for _, drifted := range report.Status.Drifted {
+			reasons[drifted.Name] = drifted.Reason
+		}
+		Expect(reasons).To(Equal(map[string]string{
+			"stale":   reasonStateMismatch,
+			"lost-id": reasonOrderIDLost,
+			"missing": reasonOrderNotFound,
+		}))
+		Expect(report.Status.DriftCount).To(Equal(3))
+		Expect(meta.IsStatusConditionTrue(report.Status.Conditions, typeAudited)).To(BeTrue())
+		Expect(mailformClient.cancelled).To(BeEmpty())
+
+		// Only unfinished mail whose orders aren't listed are looked up
+		Expect(mailformClient.fetched).To(Equal([]string{"order-gone"}))
+	})
+
+	It("should not report mail as drifted when its order can't be fetched", func() {
+		auditor, err := New(&Config{
+			Client:         fakeClient,
+			Lister:         mockLister{orders: orders},
+			MailformClient: &mockMailformClient{failing: map[string]bool{"order-gone": true}},
+			Interval:       time.Hour,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := auditor.Audit(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, drifted := range status.Drifted {
+			Expect(drifted.Name).NotTo(Equal("missing"))
+		}
+	})
+
+	It("should cancel orphaned orders postk8s created if enabled", func() {
+		mailformClient := &mockMailformClient{}
+		// order-4 was created for a mail that has since been deleted, order-new was placed from the dashboard
+		orders[3].CustomerReference = "postk8s:deleted-uid"
+
+		auditor, err := New(&Config{
+			Client:            fakeClient,
+			Lister:            mockLister{orders: orders},
+			MailformClient:    mailformClient,
+			Interval:          time.Hour,
+			MinOrphanAge:      time.Second,
+			AutoCancelOrphans: true,
+			ReportName:        "audit",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := auditor.Audit(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mailformClient.cancelled).To(ConsistOf("order-4"))
+		Expect(status.Orphans).To(HaveLen(2))
+		Expect(status.Orphans[0].ID).To(Equal("order-4"))
+		Expect(status.Orphans[0].Cancelled).To(BeTrue())
+		Expect(status.Orphans[1].ID).To(Equal("order-new"))
+		Expect(status.Orphans[1].Cancelled).To(BeFalse())
+
+		report := &mailformv1alpha1.MailAuditReport{}
+		Expect(fakeClient.Get(ctx, types.NamespacedName{Name: "audit"}, report)).To(Succeed())
+	})
+
+	It("should match orders to mail that lost their ID by the customer reference postk8s set", func() {
+		mail := newMail("lost-tagged", mailformv1alpha1.MailSpec{}, mailformv1alpha1.MailStatus{})
+		mail.UID = "uid-7"
+		Expect(fakeClient.Create(ctx, mail)).To(Succeed())
+		orders = append(orders, Order{
+			ID: "order-7", State: mailform.StatusQueued, CustomerReference: "postk8s:uid-7", Created: now.Add(-48 * time.Hour),
+		})
+		mailformClient := &mockMailformClient{}
+
+		auditor, err := New(&Config{
+			Client:            fakeClient,
+			Lister:            mockLister{orders: orders},
+			MailformClient:    mailformClient,
+			Interval:          time.Hour,
+			AutoCancelOrphans: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := auditor.Audit(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mailformClient.cancelled).NotTo(ContainElement("order-7"))
+		Expect(status.Drifted).To(ContainElement(mailformv1alpha1.DriftedMail{
+			Namespace: "default",
+			Name:      "lost-tagged",
+			OrderID:   "order-7",
+			Reason:    reasonOrderIDLost,
+			Message:   `Mail has no order ID but order order-7 has its customer reference "postk8s:uid-7"`,
+		}))
+	})
+
+	It("should keep the previous results when listing orders fails", func() {
+		mailformClient := &mockMailformClient{}
+		auditor, err := New(&Config{
+			Client:   fakeClient,
+			Lister:   mockLister{orders: orders},
+			Interval: time.Hour,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = auditor.Audit(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		auditor.lister = mockLister{mockErr: errors.New("unavailable")}
+		auditor.mailformClient = mailformClient
+		_, err = auditor.Audit(ctx)
+		Expect(err).To(MatchError(ContainSubstring("unavailable")))
+
+		report := &mailformv1alpha1.MailAuditReport{}
+		Expect(fakeClient.Get(ctx, types.NamespacedName{Name: DefaultReportName}, report)).To(Succeed())
+		Expect(report.Status.OrphanCount).To(Equal(1))
+
+		condition := meta.FindStatusCondition(report.Status.Conditions, typeAudited)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("unavailable"))
+
+		// Missing orders are only looked up against a complete listing
+		Expect(mailformClient.fetched).To(BeEmpty())
+	})
+
+	Context("HTTPLister", func() {
+		It("should list orders from the mailform api", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/orders"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer test-token"))
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"success": true,
+					"data": []map[string]any{
+						{"id": "order-1", "state": "queued", "customer_reference": "invoice-42", "total": 150},
+					},
+				})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL + "/"})
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := lister.ListOrders(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(Equal([]Order{{ID: "order-1", State: "queued", CustomerReference: "invoice-42", Total: 150}}))
+		})
+
+		It("should list every page of orders", func() {
+			var pages []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page := r.URL.Query().Get("page")
+				pages = append(pages, page)
+
+				data := []map[string]any{}
+				switch page {
+				case "1":
+					data = append(data, map[string]any{"id": "order-1"}, map[string]any{"id": "order-2"})
+				case "2":
+					data = append(data, map[string]any{"id": "order-3"})
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "data": data})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := lister.ListOrders(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(Equal([]Order{{ID: "order-1"}, {ID: "order-2"}, {ID: "order-3"}}))
+			Expect(pages).To(Equal([]string{"1", "2", "3"}))
+		})
+
+		It("should stop listing when a page repeats orders", func() {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"success": true,
+					"data":    []map[string]any{{"id": "order-1"}},
+				})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := lister.ListOrders(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listed).To(Equal([]Order{{ID: "order-1"}}))
+			Expect(requests).To(Equal(2))
+		})
+
+		It("should return an error instead of a partial listing", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("page") != "1" {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"success": true,
+					"data":    []map[string]any{{"id": "order-1"}},
+				})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			listed, err := lister.ListOrders(ctx)
+			Expect(err).To(MatchError("page 2: bad gateway"))
+			Expect(listed).To(BeNil())
+		})
+
+		It("should return an error when the listing doesn't end", func() {
+			page := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				page++
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"success": true,
+					"data":    []map[string]any{{"id": fmt.Sprintf("order-%d", page)}},
+				})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = lister.ListOrders(ctx)
+			Expect(err).To(MatchError(ErrTooManyPages))
+			Expect(page).To(Equal(maxOrderPages))
+		})
+
+		It("should return mailform errors", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{Token: "test-token", APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = lister.ListOrders(ctx)
+			mailformErr := &mailform.ErrMailform{}
+			Expect(errors.As(err, &mailformErr)).To(BeTrue())
+			Expect(mailformErr.Err.Code).To(Equal("401"))
+		})
+
+		It("should use the current token from the token func", func() {
+			token := "old-token"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer " + token))
+
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "data": []map[string]any{}})
+			}))
+			defer server.Close()
+
+			lister, err := NewHTTPLister(&HTTPListerConfig{TokenFunc: func() string { return token }, APIBaseURL: server.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = lister.ListOrders(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			token = "new-token"
+			_, err = lister.ListOrders(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should require a token", func() {
+			_, err := NewHTTPLister(&HTTPListerConfig{})
+			Expect(err).To(MatchError(ErrEmptyToken))
+		})
+	})
+})
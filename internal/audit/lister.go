@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+const (
+	// ordersEndpoint is the Mailform endpoint for listing orders.
+	ordersEndpoint = "/orders"
+	// maxOrderPages stops listing orders from an API that never runs out of pages.
+	maxOrderPages = 1000
+)
+
+var (
+	// ErrNilListerConfig is returned when no config is provided to NewHTTPLister.
+	ErrNilListerConfig = errors.New("lister config cannot be nil")
+	// ErrEmptyToken is returned when no API token is provided to NewHTTPLister.
+	ErrEmptyToken = errors.New("api token cannot be empty")
+	// ErrTooManyPages is returned when the order listing doesn't end.
+	ErrTooManyPages = errors.New("order listing didn't end")
+)
+
+// Order is the subset of a Mailform order used for auditing.
+type Order struct {
+	ID                string    `json:"id"`
+	State             string    `json:"state"`
+	CustomerReference string    `json:"customer_reference"`
+	Total             int       `json:"total"`
+	Created           time.Time `json:"created"`
+	Modified          time.Time `json:"modified"`
+}
+
+// OrderLister lists the orders in a Mailform account.
+// ListOrders returns an error rather than a partial listing, since orders missing from it are looked up one by one.
+type OrderLister interface {
+	ListOrders(ctx context.Context) ([]Order, error)
+}
+
+// HTTPListerConfig is the configuration used to create an HTTPLister.
+type HTTPListerConfig struct {
+	Token string
+	// TokenFunc optionally returns the current token for each request, e.g. when the token is reloaded from a file.
+	// Overrides Token.
+	TokenFunc func() string
+	// APIBaseURL defaults to mailform.DefaultAPIBaseURL.
+	APIBaseURL string
+	HTTPClient *http.Client
+}
+
+// HTTPLister lists orders from the Mailform API.
+// go-mailform doesn't support listing orders so the endpoint is called directly.
+type HTTPLister struct {
+	token      string
+	tokenFunc  func() string
+	apiBaseURL string
+	httpClient *http.Client
+}
+
+// NewHTTPLister returns a new Mailform order lister.
+func NewHTTPLister(c *HTTPListerConfig) (*HTTPLister, error) {
+	if c == nil {
+		return nil, ErrNilListerConfig
+	}
+
+	if c.Token == "" && c.TokenFunc == nil {
+		return nil, ErrEmptyToken
+	}
+
+	apiBaseURL := mailform.DefaultAPIBaseURL
+	if c.APIBaseURL != "" {
+		apiBaseURL = c.APIBaseURL
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: mailform.DefaultTimeout}
+	}
+
+	return &HTTPLister{
+		token:      c.Token,
+		tokenFunc:  c.TokenFunc,
+		apiBaseURL: strings.TrimSuffix(apiBaseURL, "/"),
+		httpClient: httpClient,
+	}, nil
+}
+
+// ListOrders returns all orders in the account, following the listing page by page until it's complete.
+// Mailform doesn't say how many pages there are, so the listing ends at the first page without any orders that
+// weren't already listed. An error is returned rather than a partial listing.
+func (l *HTTPLister) ListOrders(ctx context.Context) ([]Order, error) {
+	var orders []Order
+	listed := map[string]bool{}
+
+	for page := 1; ; page++ {
+		if page > maxOrderPages {
+			return nil, fmt.Errorf("%w: more than %d pages", ErrTooManyPages, maxOrderPages)
+		}
+
+		pageOrders, err := l.listPage(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+
+		added := 0
+		for _, order := range pageOrders {
+			if listed[order.ID] {
+				continue
+			}
+			listed[order.ID] = true
+			orders = append(orders, order)
+			added++
+		}
+
+		if added == 0 {
+			return orders, nil
+		}
+	}
+}
+
+// listPage returns a page of the orders in the account.
+func (l *HTTPLister) listPage(ctx context.Context, page int) ([]Order, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.apiBaseURL+ordersEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = url.Values{"page": {strconv.Itoa(page)}}.Encode()
+
+	token := l.token
+	if l.tokenFunc != nil {
+		token = l.tokenFunc()
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	// Mailform can respond successfully with an error in the body so always check for one
+	var body struct {
+		mailform.ErrMailform
+		Data []Order `json:"data"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decoding orders: %w", err)
+	}
+
+	if body.Err.Message != "" {
+		return nil, &body.ErrMailform
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		mailformErr := &mailform.ErrMailform{}
+		mailformErr.Err.Code = strconv.Itoa(resp.StatusCode)
+		mailformErr.Err.Message = strings.ToLower(http.StatusText(resp.StatusCode))
+		return nil, mailformErr
+	}
+
+	return body.Data, nil
+}
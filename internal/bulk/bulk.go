@@ -0,0 +1,506 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/orderinput"
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+// Format is the format of an import file.
+type Format string
+
+const (
+	// FormatCSV is a CSV file with a header row of column names.
+	FormatCSV Format = "csv"
+	// FormatJSON is a JSON array of objects keyed by column name.
+	FormatJSON Format = "json"
+
+	// DefaultNamePrefix is used to name mail for rows without a name column.
+	DefaultNamePrefix = "mail"
+
+	// nameColumn is the column that names each Mail.
+	nameColumn = "name"
+	// urlColumn is the column for the document URL.
+	urlColumn = "url"
+	// serviceColumn is the column for the delivery service.
+	serviceColumn = "service"
+)
+
+var (
+	// ErrNilConfig is returned when no config is provided to Build.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrUnsupportedFormat is returned for unknown import formats.
+	ErrUnsupportedFormat = errors.New("unsupported format, must be one of csv or json")
+)
+
+// Row is a single record of an import file.
+type Row struct {
+	// Number is the row as shown in a spreadsheet for CSV, where the header is row 1,
+	// or the 1-based index of the object in a JSON array.
+	Number int
+	// Values are the non-empty values of the row keyed by column.
+	Values map[string]string
+}
+
+// FieldError is a problem with a column of a row.
+type FieldError struct {
+	Row     int
+	Column  string
+	Message string
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+
+	return fmt.Sprintf("row %d, column %s: %s", e.Row, e.Column, e.Message)
+}
+
+// Errors are all of the problems found in an import file.
+type Errors []*FieldError
+
+// Error implements the error interface.
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// column maps an import column to a field of the MailSpec.
+type column struct {
+	// field is the OrderInput field name reported by validation, if any.
+	field string
+	// placeholder is a valid value used to find further validation errors once this column has been reported.
+	placeholder string
+	set         func(spec *mailformv1alpha1.MailSpec, value string) error
+}
+
+// columns are the supported import columns.
+var columns = map[string]column{
+	nameColumn: {},
+	serviceColumn: {
+		// The placeholder delivers to every country so it doesn't hide errors in the address columns
+		placeholder: string(mailformv1alpha1.ServiceUSPSFirstClass),
+		set:         setString(func(s *mailformv1alpha1.MailSpec) *string { return (*string)(&s.Service) }),
+	},
+	urlColumn: {
+		placeholder: "https://example.com/document.pdf",
+		set:         setString(func(s *mailformv1alpha1.MailSpec) *string { return &s.URL }),
+	},
+	"customer_reference": {set: setString(func(s *mailformv1alpha1.MailSpec) *string { return &s.CustomerReference })},
+	"webhook":            {set: setString(func(s *mailformv1alpha1.MailSpec) *string { return &s.Webhook })},
+	"company":            {set: setString(func(s *mailformv1alpha1.MailSpec) *string { return &s.Company })},
+	"message":            {set: setString(func(s *mailformv1alpha1.MailSpec) *string { return &s.Message })},
+	"simplex":            {set: setBool(func(s *mailformv1alpha1.MailSpec) *bool { return &s.Simplex })},
+	"color":              {set: setBool(func(s *mailformv1alpha1.MailSpec) *bool { return &s.Color })},
+	"flat":               {set: setBool(func(s *mailformv1alpha1.MailSpec) *bool { return &s.Flat })},
+	"stamp":              {set: setBool(func(s *mailformv1alpha1.MailSpec) *bool { return &s.Stamp })},
+	"check_bank_account": {
+		field:       "BankAccount",
+		placeholder: "-",
+		set:         setString(func(s *mailformv1alpha1.MailSpec) *string { return &check(s).BankAccount }),
+	},
+	"check_amount_cents": {
+		field:       "Amount",
+		placeholder: "1",
+		set:         setInt32(func(s *mailformv1alpha1.MailSpec) *int32 { return &check(s).AmountCents }),
+	},
+	"check_name": {
+		field:       "CheckName",
+		placeholder: "-",
+		set:         setString(func(s *mailformv1alpha1.MailSpec) *string { return &check(s).Name }),
+	},
+	"check_number": {
+		field:       "CheckNumber",
+		placeholder: "1",
+		set:         setInt32(func(s *mailformv1alpha1.MailSpec) *int32 { return &check(s).Number }),
+	},
+	"check_memo": {set: setString(func(s *mailformv1alpha1.MailSpec) *string { return &check(s).Memo })},
+}
+
+// check returns the check of the MailSpec, adding one if it doesn't have one yet.
+func check(s *mailformv1alpha1.MailSpec) *mailformv1alpha1.Check {
+	if s.Check == nil {
+		s.Check = &mailformv1alpha1.Check{}
+	}
+
+	return s.Check
+}
+
+func init() {
+	addressColumns("to", func(s *mailformv1alpha1.MailSpec) *mailformv1alpha1.Address { return s.To })
+	addressColumns("from", func(s *mailformv1alpha1.MailSpec) *mailformv1alpha1.Address { return s.From })
+}
+
+// addressColumns registers the columns for an address, e.g. to_name.
+func addressColumns(prefix string, address func(s *mailformv1alpha1.MailSpec) *mailformv1alpha1.Address) {
+	fields := []struct {
+		name  string
+		field func(a *mailformv1alpha1.Address) *string
+	}{
+		{"Name", func(a *mailformv1alpha1.Address) *string { return &a.Name }},
+		{"Organization", func(a *mailformv1alpha1.Address) *string { return &a.Organization }},
+		{"Address1", func(a *mailformv1alpha1.Address) *string { return &a.Address1 }},
+		{"Address2", func(a *mailformv1alpha1.Address) *string { return &a.Address2 }},
+		{"City", func(a *mailformv1alpha1.Address) *string { return &a.City }},
+		{"State", func(a *mailformv1alpha1.Address) *string { return &a.State }},
+		{"Postcode", func(a *mailformv1alpha1.Address) *string { return &a.Postcode }},
+		{"Country", func(a *mailformv1alpha1.Address) *string { return &a.Country }},
+	}
+
+	for _, f := range fields {
+		columns[prefix+"_"+strings.ToLower(f.name)] = column{
+			field:       strings.ToUpper(prefix[:1]) + prefix[1:] + f.name,
+			placeholder: "-",
+			set: setString(func(s *mailformv1alpha1.MailSpec) *string {
+				return f.field(address(s))
+			}),
+		}
+	}
+}
+
+// setString returns a setter for a string field of the MailSpec.
+func setString(field func(s *mailformv1alpha1.MailSpec) *string) func(*mailformv1alpha1.MailSpec, string) error {
+	return func(spec *mailformv1alpha1.MailSpec, value string) error {
+		*field(spec) = value
+		return nil
+	}
+}
+
+// setBool returns a setter for a bool field of the MailSpec.
+func setBool(field func(s *mailformv1alpha1.MailSpec) *bool) func(*mailformv1alpha1.MailSpec, string) error {
+	return func(spec *mailformv1alpha1.MailSpec, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+
+		*field(spec) = b
+		return nil
+	}
+}
+
+// setInt32 returns a setter for an int32 field of the MailSpec.
+func setInt32(field func(s *mailformv1alpha1.MailSpec) *int32) func(*mailformv1alpha1.MailSpec, string) error {
+	return func(spec *mailformv1alpha1.MailSpec, value string) error {
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+
+		*field(spec) = int32(i)
+		return nil
+	}
+}
+
+// Columns returns the supported column names.
+func Columns() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FormatFromPath returns the format of a file based on its extension.
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// Read reads the rows of an import file.
+// Unknown columns are returned as Errors so every problem can be reported at once.
+func Read(r io.Reader, format Format) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSON:
+		return readJSON(r)
+	}
+
+	return nil, ErrUnsupportedFormat
+}
+
+// readCSV reads rows from a CSV file with a header row.
+func readCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var errs Errors
+	for i, name := range header {
+		header[i] = normalizeColumn(name)
+		if _, ok := columns[header[i]]; !ok {
+			errs = append(errs, unknownColumnError(1, name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Number: line, Values: map[string]string{}}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				row.Values[header[i]] = value
+			}
+		}
+
+		// Skip blank lines left in spreadsheets
+		if len(row.Values) == 0 {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+}
+
+// readJSON reads rows from a JSON array of objects.
+func readJSON(r io.Reader) ([]Row, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var objects []map[string]any
+	err := decoder.Decode(&objects)
+	if err != nil {
+		return nil, fmt.Errorf("expected a JSON array of objects: %w", err)
+	}
+
+	var errs Errors
+	rows := make([]Row, 0, len(objects))
+	for i, object := range objects {
+		row := Row{Number: i + 1, Values: map[string]string{}}
+
+		for key, value := range object {
+			name := normalizeColumn(key)
+			if _, ok := columns[name]; !ok {
+				errs = append(errs, unknownColumnError(row.Number, key))
+				continue
+			}
+
+			switch v := value.(type) {
+			case nil:
+			case string:
+				if s := strings.TrimSpace(v); s != "" {
+					row.Values[name] = s
+				}
+			case json.Number, bool:
+				row.Values[name] = fmt.Sprint(v)
+			default:
+				errs = append(errs, &FieldError{Row: row.Number, Column: name, Message: "must be a string, number or boolean"})
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	sortErrors(errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return rows, nil
+}
+
+// normalizeColumn lowercases a column name and accepts dashes or spaces in place of underscores.
+func normalizeColumn(name string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// unknownColumnError returns an error listing the supported columns.
+func unknownColumnError(row int, name string) *FieldError {
+	return &FieldError{
+		Row:     row,
+		Column:  name,
+		Message: fmt.Sprintf("unknown column, must be one of %s", strings.Join(Columns(), ", ")),
+	}
+}
+
+// Config is the configuration used to build Mail from rows.
+type Config struct {
+	// Template is the Mail each row is applied on top of. Values in a row take precedence.
+	Template *mailformv1alpha1.Mail
+	// NamePrefix names mail for rows without a name column, e.g. mail-2. Defaults to DefaultNamePrefix.
+	NamePrefix string
+}
+
+// Build returns a Mail for each row and the problems found in any of them.
+// Each row is validated the same way the controller validates a Mail before ordering it.
+func Build(rows []Row, c *Config) ([]*mailformv1alpha1.Mail, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	template := c.Template
+	if template == nil {
+		template = &mailformv1alpha1.Mail{}
+	}
+
+	namePrefix := c.NamePrefix
+	if namePrefix == "" {
+		namePrefix = DefaultNamePrefix
+	}
+
+	var errs Errors
+	mails := make([]*mailformv1alpha1.Mail, 0, len(rows))
+	names := map[string]int{}
+
+	for _, row := range rows {
+		mail := template.DeepCopy()
+		if mail.Spec.To == nil {
+			mail.Spec.To = &mailformv1alpha1.Address{}
+		}
+		if mail.Spec.From == nil {
+			mail.Spec.From = &mailformv1alpha1.Address{}
+		}
+
+		mail.Name = row.Values[nameColumn]
+		if mail.Name == "" {
+			mail.Name = fmt.Sprintf("%s-%d", namePrefix, row.Number)
+		}
+
+		for _, message := range validation.IsDNS1123Subdomain(mail.Name) {
+			errs = append(errs, &FieldError{Row: row.Number, Column: nameColumn, Message: message})
+		}
+
+		if previous, ok := names[mail.Name]; ok {
+			errs = append(errs, &FieldError{
+				Row:     row.Number,
+				Column:  nameColumn,
+				Message: fmt.Sprintf("%s is also used by row %d", mail.Name, previous),
+			})
+		}
+		names[mail.Name] = row.Number
+
+		rowErrs := Errors{}
+		for name, value := range row.Values {
+			col := columns[name]
+			if col.set == nil {
+				continue
+			}
+
+			err := col.set(&mail.Spec, value)
+			if err != nil {
+				rowErrs = append(rowErrs, &FieldError{Row: row.Number, Column: name, Message: err.Error()})
+			}
+		}
+
+		// Only validate the order once every column has been parsed
+		if len(rowErrs) == 0 {
+			rowErrs = validateOrder(row.Number, mail)
+		}
+
+		errs = append(errs, rowErrs...)
+		mails = append(mails, mail)
+	}
+
+	sortErrors(errs)
+	if len(errs) > 0 {
+		return mails, errs
+	}
+
+	return mails, nil
+}
+
+// validateOrder validates a Mail with the same order validation as the controller.
+// Validation stops at the first problem, so each reported column is filled with a placeholder to find the next.
+func validateOrder(row int, mail *mailformv1alpha1.Mail) Errors {
+	var errs Errors
+	reported := map[string]bool{}
+	mail = mail.DeepCopy()
+
+	for range len(columns) {
+		orderInput := orderinput.Build(mail)
+		err := orderinput.Validate(mail, &orderInput)
+		if err == nil {
+			break
+		}
+
+		name, col, ok := columnForError(err)
+		errs = append(errs, &FieldError{Row: row, Column: name, Message: err.Error()})
+		if !ok || reported[name] {
+			break
+		}
+		reported[name] = true
+
+		_ = col.set(&mail.Spec, col.placeholder)
+	}
+
+	return errs
+}
+
+// columnForError returns the column a validation error refers to.
+func columnForError(err error) (string, column, bool) {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "service code"), errors.Is(err, provider.ErrDomesticOnly):
+		return serviceColumn, columns[serviceColumn], true
+	case strings.Contains(message, "URL"):
+		return urlColumn, columns[urlColumn], true
+	}
+
+	field, _, found := strings.Cut(message, " not provided")
+	if found {
+		for name, col := range columns {
+			if col.field == field {
+				return name, col, true
+			}
+		}
+	}
+
+	return "", column{}, false
+}
+
+// sortErrors orders errors by row then column.
+func sortErrors(errs Errors) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Row != errs[j].Row {
+			return errs[i].Row < errs[j].Row
+		}
+		return errs[i].Column < errs[j].Column
+	})
+}
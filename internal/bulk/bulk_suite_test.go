@@ -0,0 +1,14 @@
+package bulk
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBulk(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Bulk Suite")
+}
@@ -0,0 +1,213 @@
+package bulk
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+const testCSV = `name,to_name,to_address1,to_city,to_state,to_postcode,to_country,color
+alice,Alice,1 Main St,Springfield,IL,62701,US,true
+
+bob,Bob,2 Main St,Springfield,IL,62702,US,
+`
+
+func newTemplate() *mailformv1alpha1.Mail {
+	return &mailformv1alpha1.Mail{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+		},
+		Spec: mailformv1alpha1.MailSpec{
+			Service: "USPS_STANDARD",
+			URL:     "https://pdfobject.com/pdf/sample.pdf",
+			From: &mailformv1alpha1.Address{
+				Name:     "Sender Name",
+				Address1: "123 Sender St",
+				City:     "Senderville",
+				State:    "CA",
+				Postcode: "94016",
+				Country:  "US",
+			},
+		},
+	}
+}
+
+var _ = Describe("Bulk", func() {
+	Context("Read", func() {
+		It("should read csv rows with spreadsheet row numbers", func() {
+			rows, err := Read(strings.NewReader(testCSV), FormatCSV)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rows).To(HaveLen(2))
+			Expect(rows[0].Number).To(Equal(2))
+			Expect(rows[0].Values).To(HaveKeyWithValue("to_name", "Alice"))
+			Expect(rows[1].Number).To(Equal(4))
+			Expect(rows[1].Values).NotTo(HaveKey("color"))
+		})
+
+		It("should accept column names with spaces, dashes and capitals", func() {
+			rows, err := Read(strings.NewReader("To Name,to-city\nAlice,Springfield\n"), FormatCSV)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rows[0].Values).To(Equal(map[string]string{"to_name": "Alice", "to_city": "Springfield"}))
+		})
+
+		It("should read json rows keeping numbers as written", func() {
+			rows, err := Read(strings.NewReader(`[{"to_name": "Alice", "to_postcode": 62701, "color": true}, {}]`), FormatJSON)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rows).To(HaveLen(2))
+			Expect(rows[0].Number).To(Equal(1))
+			Expect(rows[0].Values).To(Equal(map[string]string{"to_name": "Alice", "to_postcode": "62701", "color": "true"}))
+			Expect(rows[1].Number).To(Equal(2))
+		})
+
+		It("should report unknown columns", func() {
+			_, err := Read(strings.NewReader("to_name,zip\nAlice,62701\n"), FormatCSV)
+			Expect(err).To(BeAssignableToTypeOf(Errors{}))
+			errs := err.(Errors)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Row).To(Equal(1))
+			Expect(errs[0].Column).To(Equal("zip"))
+
+			_, err = Read(strings.NewReader(`[{"to_name": "Alice"}, {"to_name": {"first": "Bob"}}]`), FormatJSON)
+			Expect(err).To(MatchError("row 2, column to_name: must be a string, number or boolean"))
+		})
+
+		It("should reject unsupported formats", func() {
+			_, err := Read(strings.NewReader(""), Format("xml"))
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+
+			_, err = FormatFromPath("recipients.xlsx")
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+
+			format, err := FormatFromPath("recipients.CSV")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal(FormatCSV))
+		})
+	})
+
+	Context("Build", func() {
+		It("should apply rows on top of the template", func() {
+			rows, err := Read(strings.NewReader(testCSV), FormatCSV)
+			Expect(err).NotTo(HaveOccurred())
+
+			template := newTemplate()
+			mails, err := Build(rows, &Config{Template: template})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mails).To(HaveLen(2))
+
+			Expect(mails[0].Name).To(Equal("alice"))
+			Expect(mails[0].Namespace).To(Equal("default"))
+			Expect(mails[0].Spec.Color).To(BeTrue())
+			Expect(mails[0].Spec.To.Postcode).To(Equal("62701"))
+			Expect(mails[0].Spec.From.Name).To(Equal("Sender Name"))
+			Expect(mails[1].Spec.Color).To(BeFalse())
+
+			// The template is left untouched
+			Expect(template.Spec.To).To(BeNil())
+		})
+
+		It("should name rows without a name column", func() {
+			rows := []Row{{Number: 2, Values: map[string]string{
+				"to_name": "Alice", "to_address1": "1 Main St", "to_city": "Springfield",
+				"to_state": "IL", "to_postcode": "62701", "to_country": "US",
+			}}}
+
+			mails, err := Build(rows, &Config{Template: newTemplate(), NamePrefix: "invoices"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mails[0].Name).To(Equal("invoices-2"))
+		})
+
+		It("should report every invalid column of every row", func() {
+			csv := `name,service,to_name,to_address1,to_city,to_state,to_postcode,to_country,flat
+alice,USPS_STANDARD,Alice,1 Main St,Springfield,IL,,US,
+Bob,USPS_SNAIL,Bob,2 Main St,,IL,62702,US,maybe
+alice,,Carol,,Springfield,IL,62703,US,
+dave,USPS_CERTIFIED,Dave,1 Rue de Rivoli,Paris,IDF,75001,FR,
+`
+			rows, err := Read(strings.NewReader(csv), FormatCSV)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = Build(rows, &Config{Template: newTemplate()})
+			Expect(err).To(BeAssignableToTypeOf(Errors{}))
+
+			type location struct {
+				Row    int
+				Column string
+			}
+			var locations []location
+			for _, e := range err.(Errors) {
+				locations = append(locations, location{e.Row, e.Column})
+			}
+
+			Expect(locations).To(Equal([]location{
+				{2, "to_postcode"},
+				{3, "flat"},
+				{3, "name"},
+				{4, "name"},
+				{4, "to_address1"},
+				{5, "service"},
+			}))
+		})
+
+		It("should build checks and report their missing fields", func() {
+			csv := `name,to_name,to_address1,to_city,to_state,to_postcode,to_country,check_bank_account,check_amount_cents,check_name,check_number,check_memo
+alice,Alice,1 Main St,Springfield,IL,62701,US,ba_1234,12000,Alice,1001,Refund
+bob,Bob,2 Main St,Springfield,IL,62702,US,ba_1234,,,,
+carol,Carol,3 Main St,Springfield,IL,62703,US,,12.50,,,
+`
+			rows, err := Read(strings.NewReader(csv), FormatCSV)
+			Expect(err).NotTo(HaveOccurred())
+
+			mails, err := Build(rows[:1], &Config{Template: newTemplate()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mails[0].Spec.Check).To(Equal(&mailformv1alpha1.Check{
+				BankAccount: "ba_1234",
+				AmountCents: 12000,
+				Name:        "Alice",
+				Number:      1001,
+				Memo:        "Refund",
+			}))
+
+			_, err = Build(rows, &Config{Template: newTemplate()})
+			Expect(err).To(BeAssignableToTypeOf(Errors{}))
+
+			var columns []string
+			for _, e := range err.(Errors) {
+				columns = append(columns, e.Column)
+			}
+			Expect(columns).To(Equal([]string{
+				"check_amount_cents", "check_name", "check_number",
+				"check_amount_cents",
+			}))
+		})
+
+		It("should validate with the same logic as the controller", func() {
+			rows := []Row{{Number: 1, Values: map[string]string{
+				"service": "USPS_SNAIL", "to_name": "Alice",
+			}}}
+
+			template := newTemplate()
+			template.Spec.URL = ""
+			_, err := Build(rows, &Config{Template: template})
+
+			Expect(err).To(BeAssignableToTypeOf(Errors{}))
+			errs := err.(Errors)
+			Expect(errs[0].Column).To(Equal("service"))
+			Expect(errs[0].Message).To(ContainSubstring("service code: 'USPS_SNAIL' not supported"))
+
+			var columns []string
+			for _, e := range errs {
+				columns = append(columns, e.Column)
+			}
+			Expect(columns).To(ConsistOf("service", "url", "to_address1", "to_city", "to_state", "to_postcode", "to_country"))
+		})
+
+		It("should return an error without a config", func() {
+			_, err := Build(nil, nil)
+			Expect(err).To(MatchError(ErrNilConfig))
+		})
+	})
+})
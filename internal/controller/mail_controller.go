@@ -2,22 +2,42 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	mailform "github.com/circa10a/go-mailform"
 	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/document"
+	"github.com/circa10a/postk8s/internal/orderinput"
+	"github.com/circa10a/postk8s/internal/polling"
+	"github.com/circa10a/postk8s/internal/provider"
+	"github.com/circa10a/postk8s/internal/runtimeconfig"
 )
 
 // MailformIface is an interface to Create/Get orders from Mailform.
@@ -27,27 +47,110 @@ type MailformIface interface {
 	CancelOrder(o string) error
 }
 
+// ArchiverIface is an interface to archive records of finished mail and the documents submitted with their orders.
+type ArchiverIface interface {
+	ArchiveDocument(ctx context.Context, mail *mailformv1alpha1.Mail, document []byte) (string, error)
+	Archive(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) (string, error)
+}
+
+// KillSwitchIface is an interface to check whether new orders are halted across the cluster.
+type KillSwitchIface interface {
+	Halted(ctx context.Context) (bool, string, error)
+	Key() types.NamespacedName
+}
+
 // MailReconciler reconciles a Mail object
 type MailReconciler struct {
 	client.Client
 	MailformClient MailformIface
 	Scheme         *runtime.Scheme
-	SyncInterval   time.Duration
+	// RuntimeConfig optionally holds settings that can change while the manager is running.
+	// When set, it replaces SyncInterval, TTLAfterFinished, OrderLostPolicy and PollPolicy.
+	RuntimeConfig *runtimeconfig.Store
+	SyncInterval  time.Duration
+	// TTLAfterFinished is the default time to keep fulfilled/cancelled mail before deleting it.
+	// Zero disables cleanup unless the Mail sets spec.ttlSecondsAfterFinished.
+	TTLAfterFinished time.Duration
+	// Archiver optionally archives a record of mail once it's fulfilled or cancelled.
+	Archiver ArchiverIface
+	// OrderLostPolicy is the default for mail whose order can't be found. Defaults to GiveUp.
+	OrderLostPolicy mailformv1alpha1.OrderLostPolicy
+	// PollPolicy optionally adapts how often orders are checked to their state and age.
+	// SyncInterval is used for every order if nil.
+	PollPolicy *polling.Policy
+	// StartLimiter optionally limits how quickly mail starts calling Mailform after the manager starts.
+	StartLimiter *rate.Limiter
+	// MaxConcurrentReconciles defaults to 1.
+	MaxConcurrentReconciles int
+	// KillSwitch optionally halts new orders. Existing orders are still synced and cancelled.
+	KillSwitch KillSwitchIface
+	// Preflight optionally verifies documents before their orders are created.
+	Preflight *document.Preflight
+	// APIReader reads Secrets and ConfigMaps straight from the API server so they aren't cached. Defaults to Client.
+	APIReader client.Reader
+	// PreviewPages optionally renders a preview of the first pages of verified documents into a ConfigMap.
+	// Zero disables previews.
+	PreviewPages int
+
+	// started holds the UIDs of mail that has been reconciled since the manager started.
+	started sync.Map
 }
 
 // Definitions to manage status conditions
 const (
 	// typeValidationMail represents the status of the Mail fuilfillment
 	typeFulfillmentMail = "Fulfillment"
+	// typeAdoptedMail represents whether an existing order was adopted by the Mail
+	typeAdoptedMail = "Adopted"
+	// typeOrderRecoveredMail represents whether the Mail's order ID was recovered from its annotations
+	typeOrderRecoveredMail = "OrderRecovered"
+	// typeOrderLostMail represents whether the Mail's order can no longer be found
+	typeOrderLostMail = "OrderLost"
+	// typeProviderUnavailableMail represents whether requests to Mailform are paused by the circuit breaker
+	typeProviderUnavailableMail = "ProviderUnavailable"
+	// typeHaltedMail represents whether creating the Mail's order is halted by the kill switch
+	typeHaltedMail = "Halted"
+	// typeDocumentVerifiedMail represents whether the Mail's document passed preflight
+	typeDocumentVerifiedMail = "DocumentVerified"
+	// typeArchivedMail represents whether the deleted Mail could be archived
+	typeArchivedMail = "Archived"
+	// rateLimitedRequeueAfter is how long to wait before retrying when Mailform is rate limiting requests
+	rateLimitedRequeueAfter = time.Minute
+	// orderLostRequeueAfter is how long to wait before recreating or cleaning up mail with a lost order
+	orderLostRequeueAfter = time.Second
+	// archiveOnDeleteTimeout is how long deleted mail waits to be archived before it's deleted without an archive
+	archiveOnDeleteTimeout = time.Hour
 	// Finalizer for ensuring safe to delete by validated mail was sent/cancelled
 	mailSentOrCancelledFinalizerName = "mailform.circa10a.github.io/mail-sent-or-cancelled-finalizer"
 	// This is our exception annotation to override the finalizer so mail can be deleted without talking to mailform.
 	skipCancellationOnDeleteAnnotation = "mailform.circa10a.github.io/skip-cancellation-on-delete"
+	// fieldManager identifies the controller's writes to Mail
+	fieldManager = "postk8s"
+	// orderIDAnnotation records the order ID outside of status so it survives restores that drop status
+	orderIDAnnotation = "mailform.circa10a.github.io/order-id"
+	// orderProviderAnnotation records which provider the order ID belongs to
+	orderProviderAnnotation = "mailform.circa10a.github.io/provider"
+	// orderProvider is the provider orders are placed with
+	orderProvider = "mailform"
+	// pausedAnnotation stops the controller from doing anything but deleting the mail while set to true
+	pausedAnnotation = "mailform.circa10a.github.io/paused"
+	// resyncRequestedAnnotation requests an immediate status refresh whenever its value, a timestamp, changes
+	resyncRequestedAnnotation = "mailform.circa10a.github.io/resync-requested"
+	// typePausedMail represents whether the controller is leaving the Mail alone
+	typePausedMail = "Paused"
+	// previewConfigMapSuffix is appended to the Mail's name to name the ConfigMap holding its preview
+	previewConfigMapSuffix = "-preview"
+	// previewKey is the ConfigMap key previews are stored under
+	previewKey = "preview.png"
+	// maxPreviewSize leaves room for metadata within the 1MiB ConfigMap limit
+	maxPreviewSize = 1000 * 1024
 )
 
 // +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=mails,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=mails/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=mails/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -63,12 +166,29 @@ func (r *MailReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
+	// Paused mail is left alone until it's resumed or deleted
+	if isPaused(mail) && mail.DeletionTimestamp.IsZero() {
+		log.Info("mail paused, skipping reconciliation", "name", req.Name)
+		return ctrl.Result{}, r.setPausedCondition(ctx, mail, true)
+	}
+
+	err = r.setPausedCondition(ctx, mail, false)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Add finalizer for ensuring mail was sent or cancelled.
 	err = r.ensureMailSentOrCancelledFinalizer(ctx, mail)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Restored mail may have lost its status, recover the order before anything relies on it
+	err = r.ensureOrderIdentity(ctx, mail)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Ensure finalizers are met
 	done, err := r.handleDeletion(ctx, mail)
 	if err != nil {
@@ -77,47 +197,56 @@ func (r *MailReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 
 	// Deleted successfully
 	if done {
+		r.started.Delete(mail.UID)
 		return ctrl.Result{}, nil
 	}
 
-	// Nothing to do if mail is already sent or cancelled
-	if mail.Status.Sent || mail.Status.State == mailform.StatusCancelled {
-		log.Info("order sent/cancelled", "name", req.Name, "orderID", mail.Status.ID)
-		return ctrl.Result{}, nil
-	}
+	// Requested resyncs refresh the status now, even for finished mail
+	resync := resyncRequested(mail)
 
-	// Validate the spec/order
-	orderInput := r.buildOrderInput(mail)
-	err = orderInput.Validate()
-	if err != nil {
-		log.Error(err, "mail spec invalid, skipping reconciliation", "name", req.Name)
-		return ctrl.Result{}, err
-	}
+	// Nothing to do if mail is already sent, cancelled or lost other than archiving and cleaning it up
+	if (isFinished(mail) || isOrderLost(mail)) && !resync {
+		log.Info("order sent/cancelled/lost", "name", req.Name, "orderID", mail.Status.ID)
 
-	// Mailspec is valid let's ensure it's updated only once
-	if !mail.Status.Valid {
-		mail.Status.Valid = true
-		err = r.Status().Update(ctx, mail)
+		err = r.ensureArchived(ctx, mail, nil)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+
+		return r.handleTTLAfterFinished(ctx, mail)
 	}
 
-	// Create order if it doesn't exist
-	if mail.Status.ID == "" {
-		orderID, err := r.createOrder(ctx, mail, &orderInput)
+	// Spread out the first call to Mailform for each mail so a restart doesn't call it for every mail at once
+	startDelay := r.startDelay(mail)
+	if startDelay > 0 && !resync {
+		log.Info("delaying first sync after start", "name", req.Name, "requeueAfter", startDelay)
+		return ctrl.Result{RequeueAfter: startDelay}, nil
+	}
+
+	if mail.Spec.AdoptOrderID != "" {
+		// Adopted orders were created outside the cluster so there is nothing to validate or create
+		adopted, err := r.ensureAdopted(ctx, mail)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 
-		log.Info("created mail order", "name", req.Name, "orderID", orderID)
+		// Wait for the owning mail to go away
+		if !adopted {
+			return ctrl.Result{RequeueAfter: r.syncInterval(mail)}, nil
+		}
+	} else {
+		created, result, err := r.ensureOrderCreated(ctx, mail)
+		if !created {
+			return result, err
+		}
 	}
 
 	// Get order details
 	order, err := r.getOrder(mail.Status.ID)
 	if err != nil {
-		log.Error(err, "error fetching order", "name", req.Name, "orderID", mail.Status.ID)
-		return ctrl.Result{}, err
+		log.Error(err, "error fetching order", "name", req.Name, "orderID", mail.Status.ID,
+			"class", provider.Classify(err))
+		return r.handleProviderError(ctx, mail, err)
 	}
 
 	// Update status fields if there are any order updates
@@ -126,195 +255,1353 @@ func (r *MailReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
+	// Archive as soon as the order finishes. Finished orders aren't checked again, and status updates don't trigger a
+	// reconcile, so requeue for the TTL instead of the sync interval.
+	if isFinished(mail) {
+		log.Info("order finished", "name", req.Name, "orderID", mail.Status.ID, "state", mail.Status.State)
+
+		err = r.ensureArchived(ctx, mail, order)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return r.handleTTLAfterFinished(ctx, mail)
+	}
+
+	requeueAfter := r.syncInterval(mail)
+
 	log.Info("got status from order, requeuing",
 		"name", req.Name,
 		"orderID", mail.Status.ID,
 		"state", mail.Status.State,
 		"sent", mail.Status.Sent,
-		"requeueAfter", r.SyncInterval,
+		"requeueAfter", requeueAfter,
 	)
 
-	return ctrl.Result{RequeueAfter: r.SyncInterval}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *MailReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&mailformv1alpha1.Mail{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
-		Named("mail").
-		Complete(r)
+// ensureOrderCreated validates the mail and places its order if it doesn't have one yet.
+// Returns false with the result to return from Reconcile if there's no order.
+func (r *MailReconciler) ensureOrderCreated(ctx context.Context, mail *mailformv1alpha1.Mail) (bool, ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	// Validate the spec/order
+	orderInput := orderinput.Build(mail)
+	err := orderinput.Validate(mail, &orderInput)
+	if err != nil {
+		log.Error(err, "mail spec invalid, skipping reconciliation", "name", mail.Name)
+		return false, ctrl.Result{}, err
+	}
+
+	// Verify the document before paying for it
+	var content []byte
+	if mail.Status.ID == "" {
+		var verified bool
+		content, verified, err = r.verifyDocument(ctx, mail)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+		if !verified {
+			return false, ctrl.Result{RequeueAfter: r.syncInterval(mail)}, nil
+		}
+	}
+
+	// Mailspec is valid let's ensure it's updated only once
+	if !mail.Status.Valid {
+		base := mail.DeepCopy()
+		mail.Status.Valid = true
+		err = r.patchStatus(ctx, mail, base)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+	}
+
+	// Create order if it doesn't exist
+	if mail.Status.ID == "" {
+		halted, err := r.checkKillSwitch(ctx, mail)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+		if halted {
+			log.Info("order creation halted by kill switch", "name", mail.Name)
+			return false, ctrl.Result{RequeueAfter: r.syncInterval(mail)}, nil
+		}
+
+		orderID, err := r.createOrder(ctx, mail, &orderInput, content)
+		if provider.Classify(err) == provider.ClassValidation {
+			return false, ctrl.Result{}, r.handleOrderRejected(ctx, mail, err)
+		}
+		if err != nil {
+			result, err := r.handleProviderError(ctx, mail, err)
+			return false, result, err
+		}
+
+		log.Info("created mail order", "name", mail.Name, "orderID", orderID)
+	}
+
+	return true, ctrl.Result{}, nil
 }
 
-// loadMail fetches the Mail object.
-func (r *MailReconciler) loadMail(ctx context.Context, key types.NamespacedName) (*mailformv1alpha1.Mail, error) {
-	mail := &mailformv1alpha1.Mail{}
+// checkKillSwitch reports whether the kill switch halts creating the mail's order and records it in a condition.
+func (r *MailReconciler) checkKillSwitch(ctx context.Context, mail *mailformv1alpha1.Mail) (bool, error) {
+	if r.KillSwitch == nil || !r.settings().KillSwitch {
+		return false, nil
+	}
 
-	err := r.Get(ctx, key, mail)
+	halted, reason, err := r.KillSwitch.Halted(ctx)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	return mail, nil
+	if !halted && !meta.IsStatusConditionTrue(mail.Status.Conditions, typeHaltedMail) {
+		return false, nil
+	}
+
+	condition := metav1.Condition{
+		Type:    typeHaltedMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "KillSwitch",
+		Message: reason,
+	}
+	if !halted {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Resumed"
+		condition.Message = "Order creation resumed"
+	}
+
+	base := mail.DeepCopy()
+	meta.SetStatusCondition(&mail.Status.Conditions, condition)
+
+	return halted, r.patchStatus(ctx, mail, base)
 }
 
-// ensureMailSentOrCancelledFinalizer adds the finalizer responsible for not allowing delete until sent/cancelled.
-func (r *MailReconciler) ensureMailSentOrCancelledFinalizer(ctx context.Context, mail *mailformv1alpha1.Mail) error {
-	if mail.DeletionTimestamp.IsZero() && !controllerutil.ContainsFinalizer(mail, mailSentOrCancelledFinalizerName) {
-		controllerutil.AddFinalizer(mail, mailSentOrCancelledFinalizerName)
-		return r.Update(ctx, mail)
+// verifyDocument downloads and checks the mail's document before its order is created and records it in status.
+// Returns false if the document is invalid, which is checked again every sync in case the document is fixed.
+// Valid documents are only verified once per generation unless they're pinned or built by the operator.
+// The verified content is returned to be archived with the order, and uploaded if orderinput.UploadsDocument.
+func (r *MailReconciler) verifyDocument(ctx context.Context, mail *mailformv1alpha1.Mail) ([]byte, bool, error) {
+	pinned := mail.Spec.DocumentSHA256 != ""
+	upload := pinned || orderinput.BuildsDocument(mail)
+	preflight := r.Preflight
+	if preflight == nil {
+		if !upload {
+			return nil, true, nil
+		}
+		// Documents that are uploaded are always verified
+		preflight, _ = document.NewPreflight(&document.PreflightConfig{})
 	}
 
-	return nil
+	condition := meta.FindStatusCondition(mail.Status.Conditions, typeDocumentVerifiedMail)
+	if !upload && condition != nil && condition.Status == metav1.ConditionTrue && condition.ObservedGeneration == mail.Generation {
+		return nil, true, nil
+	}
+
+	var info *document.Info
+	data, parts, err := r.readDocument(ctx, preflight, mail)
+	if err == nil {
+		info, err = preflight.Inspect(data)
+	}
+	if err == nil && pinned {
+		err = info.Verify(mail.Spec.DocumentSHA256)
+	}
+	if err != nil && !errors.Is(err, document.ErrInvalid) {
+		return nil, false, err
+	}
+
+	base := mail.DeepCopy()
+	verified := metav1.Condition{
+		Type:               typeDocumentVerifiedMail,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Verified",
+		ObservedGeneration: mail.Generation,
+	}
+
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "document failed preflight", "name", mail.Name)
+		data = nil
+		mail.Status.Valid = false
+		mail.Status.Document = nil
+		mail.Status.Preview = nil
+		verified.Status = metav1.ConditionFalse
+		verified.Reason = "Invalid"
+		verified.Message = err.Error()
+	} else {
+		mail.Status.Document = &mailformv1alpha1.DocumentStatus{
+			SHA256: info.SHA256,
+			Pages:  info.Pages,
+			Size:   info.Size,
+			Parts:  partStatuses(parts),
+			Pinned: pinned,
+		}
+		verified.Message = fmt.Sprintf("PDF with %d pages", info.Pages)
+
+		previewErr := r.ensurePreview(ctx, mail, data, info.SHA256)
+		if previewErr != nil {
+			return nil, false, previewErr
+		}
+	}
+	meta.SetStatusCondition(&mail.Status.Conditions, verified)
+
+	return data, err == nil, r.patchStatus(ctx, mail, base)
 }
 
-// Ensure finalizer conditions are met and can be deleted
-func (r *MailReconciler) handleDeletion(ctx context.Context, mail *mailformv1alpha1.Mail) (bool, error) {
+// ensurePreview renders a preview of the mail's document into a ConfigMap owned by the mail and references it in status.
+// Previews are only rendered again when the document changes.
+// Documents that can't be previewed are logged without failing the mail since they already passed preflight.
+func (r *MailReconciler) ensurePreview(ctx context.Context, mail *mailformv1alpha1.Mail, data []byte, sha256 string) error {
+	if r.PreviewPages <= 0 {
+		return nil
+	}
+
+	if mail.Status.Preview != nil && mail.Status.Preview.SHA256 == sha256 {
+		return nil
+	}
+
 	log := logf.FromContext(ctx)
 
-	if mail.DeletionTimestamp.IsZero() {
-		return false, nil
+	config := &document.PreviewConfig{Pages: r.PreviewPages, Envelope: envelope(mail)}
+	// Mail without a cover page shows where the addresses go
+	if mail.Spec.CoverPage == nil {
+		config.From = addressLines(mail.Spec.From)
+		config.To = addressLines(mail.Spec.To)
 	}
 
-	if controllerutil.ContainsFinalizer(mail, mailSentOrCancelledFinalizerName) {
-		// Check for skip override
-		val := mail.Annotations[skipCancellationOnDeleteAnnotation]
-		skip, _ := strconv.ParseBool(val)
+	preview, pages, err := document.RenderPreview(data, config)
+	if err != nil {
+		log.Error(err, "unable to render preview", "name", mail.Name)
+		return nil
+	}
 
-		if skip {
-			log.Info("skip-cancellation annotation present, skipping cancellation for", "orderID", mail.Status.ID, "name", mail.Name)
-			controllerutil.RemoveFinalizer(mail, mailSentOrCancelledFinalizerName)
-			return true, r.Update(ctx, mail)
-		}
+	if len(preview) > maxPreviewSize {
+		log.Info("preview too large for a ConfigMap, skipping", "name", mail.Name, "size", len(preview))
+		return nil
+	}
 
-		// Fetch latest state from Mailform if there is an order ID
-		order := &mailform.Order{}
-		var err error
-		if mail.Status.ID != "" {
-			order, err = r.getOrder(mail.Status.ID)
-			if err != nil {
-				log.Error(err, "failed to fetch order for deletion check", "orderID", mail.Status.ID, "name", mail.Name)
-				return false, err
-			}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      mail.Name + previewConfigMapSuffix,
+			Namespace: mail.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.BinaryData = map[string][]byte{previewKey: preview}
+		return controllerutil.SetControllerReference(mail, configMap, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	mail.Status.Preview = &mailformv1alpha1.PreviewStatus{
+		ConfigMapName: configMap.Name,
+		Key:           previewKey,
+		Pages:         pages,
+		SHA256:        sha256,
+	}
+
+	return nil
+}
+
+// readDocument returns the mail's document from whichever source it's in.
+// Documents built from several documents or with a cover page are returned with the parts they were merged from.
+func (r *MailReconciler) readDocument(
+	ctx context.Context, preflight *document.Preflight, mail *mailformv1alpha1.Mail,
+) ([]byte, []document.Part, error) {
+	if !orderinput.BuildsDocument(mail) {
+		data, err := r.readSourceDocument(ctx, preflight, mail)
+		return data, nil, err
+	}
+
+	docs := [][]byte{}
+	if mail.Spec.CoverPage != nil {
+		cover, err := document.RenderCoverPage(coverPage(mail))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cover page: %w", err)
 		}
+		docs = append(docs, cover)
+	}
 
-		// Only cancel if not already sent/cancelled
-		if order == nil || (order.Data.State != mailform.StatusFulfilled && order.Data.State != mailform.StatusCancelled) {
-			if mail.Status.ID != "" {
-				err := r.cancelOrder(mail.Status.ID)
-				if err != nil {
-					log.Error(err, "failed to cancel order", "orderID", mail.Status.ID, "name", mail.Name)
-					return false, err
-				}
-				log.Info("order cancelled", "orderID", mail.Status.ID, "name", mail.Name)
-			}
+	if len(mail.Spec.Documents) == 0 {
+		data, err := r.readSourceDocument(ctx, preflight, mail)
+		if err != nil {
+			return nil, nil, err
 		}
+		docs = append(docs, data)
+	}
 
-		// Remove finalizer after cancelling or if already sent
-		controllerutil.RemoveFinalizer(mail, mailSentOrCancelledFinalizerName)
+	for i, source := range mail.Spec.Documents {
+		var data []byte
+		var err error
 
-		err = r.Update(ctx, mail)
+		switch {
+		case source.URL != "":
+			data, err = preflight.Fetch(ctx, source.URL)
+		case source.ConfigMapRef != nil:
+			data, err = document.ReadConfigMap(ctx, r.apiReader(), mail.Namespace, source.ConfigMapRef)
+		case source.SecretRef != nil:
+			data, err = document.ReadSecret(ctx, r.apiReader(), mail.Namespace, source.SecretRef)
+		default:
+			data, err = document.RenderTemplate(source.Template, mail, nil)
+		}
 		if err != nil {
-			return false, err
+			return nil, nil, fmt.Errorf("spec.documents[%d]: %w", i, err)
 		}
+
+		docs = append(docs, data)
 	}
 
-	return true, nil
+	return document.Merge(docs, &document.MergeConfig{
+		Duplex: mail.Spec.DuplexAlignment && !mail.Spec.Simplex,
+	})
 }
 
-// buildOrderInput builds the external API order input from the Mail spec.
-func (r *MailReconciler) buildOrderInput(mail *mailformv1alpha1.Mail) mailform.OrderInput {
-	return mailform.OrderInput{
-		FilePath:          mail.Spec.FilePath,
-		URL:               mail.Spec.URL,
-		CustomerReference: mail.Spec.CustomerReference,
-		Service:           mail.Spec.Service,
-		Webhook:           mail.Spec.Webhook,
-		Company:           mail.Spec.Company,
-		Simplex:           mail.Spec.Simplex,
-		Color:             mail.Spec.Color,
-		Flat:              mail.Spec.Flat,
-		Message:           mail.Spec.Message,
-		ToName:            mail.Spec.To.Name,
-		ToOrganization:    mail.Spec.To.Organization,
-		ToAddress1:        mail.Spec.To.Address1,
-		ToAddress2:        mail.Spec.To.Address2,
-		ToCity:            mail.Spec.To.City,
-		ToState:           mail.Spec.To.State,
-		ToPostcode:        mail.Spec.To.Postcode,
-		ToCountry:         mail.Spec.To.Country,
-		FromName:          mail.Spec.From.Name,
-		FromOrganization:  mail.Spec.From.Organization,
-		FromAddress1:      mail.Spec.From.Address1,
-		FromAddress2:      mail.Spec.From.Address2,
-		FromCity:          mail.Spec.From.City,
-		FromState:         mail.Spec.From.State,
-		FromPostcode:      mail.Spec.From.Postcode,
-		FromCountry:       mail.Spec.From.Country,
+// readSourceDocument returns the document from the mail's url, filePath, configMapRef or rendered body.
+func (r *MailReconciler) readSourceDocument(
+	ctx context.Context, preflight *document.Preflight, mail *mailformv1alpha1.Mail,
+) ([]byte, error) {
+	switch {
+	case mail.Spec.Body != nil:
+		data, err := document.RenderLetter(mail.Spec.Body.Content, letterConfig(mail))
+		if err != nil {
+			return nil, fmt.Errorf("spec.body: %w", err)
+		}
+		return data, nil
+	case mail.Spec.ConfigMapRef != nil:
+		return document.ReadConfigMap(ctx, r.apiReader(), mail.Namespace, mail.Spec.ConfigMapRef)
+	case mail.Spec.URL != "":
+		return preflight.Fetch(ctx, mail.Spec.URL)
+	default:
+		return os.ReadFile(mail.Spec.FilePath)
 	}
 }
 
-// createOrder with create an order.
-func (r *MailReconciler) createOrder(ctx context.Context, mail *mailformv1alpha1.Mail, orderInput *mailform.OrderInput) (string, error) {
-	order, err := r.MailformClient.CreateOrder(*orderInput)
-	if err != nil {
-		return "", err
+// letterConfig returns the layout of the mail's body. Pages default to the size the envelope is made for.
+func letterConfig(mail *mailformv1alpha1.Mail) *document.LetterConfig {
+	body := mail.Spec.Body
+	c := &document.LetterConfig{
+		Format:   document.Format(body.Format),
+		Font:     document.Font(body.Font),
+		FontSize: float64(body.FontSize),
+		Margin:   float64(body.MarginPoints),
+		PageSize: document.PageSize(body.PageSize),
 	}
 
-	mail.Status.ID = order.Data.ID
+	if c.PageSize == "" {
+		c.PageSize = document.PageSizeA4
+		if envelope(mail) == document.EnvelopeUS10 {
+			c.PageSize = document.PageSizeLetter
+		}
+	}
 
-	err = r.Status().Update(ctx, mail)
-	if err != nil {
-		return order.Data.ID, err
+	if body.Letterhead != nil {
+		c.Letterhead = body.Letterhead.Lines
+		c.LetterheadAlign = document.Align(body.Letterhead.Align)
 	}
 
-	return order.Data.ID, nil
+	return c
 }
 
-// getOrder retrieves the order via the external API.
-func (r *MailReconciler) getOrder(orderID string) (*mailform.Order, error) {
-	return r.MailformClient.GetOrder(orderID)
+// envelope returns the envelope the mail is laid out for, which defaults to US #10 for mail to the US and DL otherwise.
+func envelope(mail *mailformv1alpha1.Mail) document.Envelope {
+	if mail.Spec.CoverPage != nil && mail.Spec.CoverPage.Envelope != "" {
+		return document.Envelope(mail.Spec.CoverPage.Envelope)
+	}
+
+	if mail.Spec.To != nil && strings.EqualFold(mail.Spec.To.Country, "US") {
+		return document.EnvelopeUS10
+	}
+
+	return document.EnvelopeDL
 }
 
-// cancelOrder cancels the order via the external API.
-func (r *MailReconciler) cancelOrder(orderID string) error {
-	return r.MailformClient.CancelOrder(orderID)
+// coverPage returns the cover page for the mail's addresses and envelope.
+func coverPage(mail *mailformv1alpha1.Mail) *document.CoverPage {
+	qrCode := ""
+	if mail.Spec.CoverPage.QRCode {
+		qrCode = strings.TrimSpace(string(mail.UID) + "\n" + mail.Spec.CustomerReference)
+	}
+
+	return &document.CoverPage{
+		Envelope: envelope(mail),
+		From:     addressLines(mail.Spec.From),
+		To:       addressLines(mail.Spec.To),
+		QRCode:   qrCode,
+	}
 }
 
-// updateStatusFromOrder maps the external order into Mail.Status and persists it.
-func (r *MailReconciler) updateStatusFromOrder(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) error {
-	mail.Status.Sent = order.Data.State == mailform.StatusFulfilled
-	mail.Status.State = order.Data.State
-	mail.Status.Total = order.Data.Total
-	mail.Status.Created = metav1.NewTime(order.Data.Created)
-	mail.Status.Modified = metav1.NewTime(order.Data.Modified)
-	mail.Status.Cancelled = metav1.NewTime(order.Data.Cancelled)
-	mail.Status.CancellationReason = order.Data.CancellationReason
+// addressLines formats an address for an envelope, the US way for US addresses and with the country otherwise.
+func addressLines(address *mailformv1alpha1.Address) []string {
+	if address == nil {
+		return nil
+	}
 
-	now := metav1.Now()
+	lines := []string{}
+	for _, line := range []string{address.Name, address.Organization, address.Address1, address.Address2} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
 
-	if mail.Status.Sent {
-		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
-			Type:               typeFulfillmentMail,
-			Status:             metav1.ConditionTrue,
-			Reason:             "Fulfilled",
-			Message:            "Mail order fulfilled",
-			LastTransitionTime: now,
-		})
-	} else {
-		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
-			Type:               typeFulfillmentMail,
-			Status:             metav1.ConditionFalse,
-			Reason:             "AwaitingFulfillment",
-			Message:            "Mail order not fulfilled yet",
-			LastTransitionTime: now,
+	if strings.EqualFold(address.Country, "US") {
+		return append(lines, strings.Join([]string{address.City, address.State, address.Postcode}, " "))
+	}
+
+	lines = append(lines, strings.TrimSpace(address.Postcode+" "+address.City))
+	if address.State != "" {
+		lines = append(lines, address.State)
+	}
+
+	return append(lines, strings.ToUpper(address.Country))
+}
+
+// partStatuses converts the parts of a merged document to their status.
+func partStatuses(parts []document.Part) []mailformv1alpha1.DocumentPartStatus {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	statuses := make([]mailformv1alpha1.DocumentPartStatus, 0, len(parts))
+	for _, part := range parts {
+		statuses = append(statuses, mailformv1alpha1.DocumentPartStatus{
+			Pages:      part.Pages,
+			BlankPages: part.BlankPages,
 		})
 	}
 
-	err := r.Status().Update(ctx, mail)
+	return statuses
+}
+
+// apiReader returns the reader used for Secrets and ConfigMaps.
+func (r *MailReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+
+	return r.Client
+}
+
+// startDelay returns how long to wait before the mail's first reconcile since the manager started may call Mailform.
+// Delays are reserved from StartLimiter so mail is started at a bounded rate.
+func (r *MailReconciler) startDelay(mail *mailformv1alpha1.Mail) time.Duration {
+	if r.StartLimiter == nil {
+		return 0
+	}
+
+	_, seen := r.started.LoadOrStore(mail.UID, struct{}{})
+	if seen {
+		return 0
+	}
+
+	return r.StartLimiter.Reserve().Delay()
+}
+
+// syncInterval returns how long to wait before checking the mail's order again.
+func (r *MailReconciler) syncInterval(mail *mailformv1alpha1.Mail) time.Duration {
+	settings := r.settings()
+
+	if mail.Spec.SyncIntervalSeconds != nil {
+		interval := time.Duration(*mail.Spec.SyncIntervalSeconds) * time.Second
+		if settings.PollPolicy == nil {
+			return interval
+		}
+		return settings.PollPolicy.Jitter(interval, string(mail.UID))
+	}
+
+	if settings.PollPolicy == nil {
+		return settings.SyncInterval
+	}
+
+	// Orders are only as old as the Mail until Mailform reports when they were created
+	created := mail.CreationTimestamp.Time
+	if !mail.Status.Created.IsZero() {
+		created = mail.Status.Created.Time
+	}
+
+	return settings.PollPolicy.Interval(mail.Status.State, time.Since(created), string(mail.UID))
+}
+
+// settings returns the current runtime settings, or the reconciler's own fields if there's no runtime config.
+func (r *MailReconciler) settings() runtimeconfig.Settings {
+	if r.RuntimeConfig != nil {
+		return r.RuntimeConfig.Current()
+	}
+
+	return runtimeconfig.Settings{
+		SyncInterval:     r.SyncInterval,
+		PollPolicy:       r.PollPolicy,
+		DeletionPolicy:   mailformv1alpha1.DeletionPolicyCancel,
+		TTLAfterFinished: r.TTLAfterFinished,
+		OrderLostPolicy:  r.OrderLostPolicy,
+		Archive:          true,
+		KillSwitch:       true,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MailReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&mailformv1alpha1.Mail{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, controlAnnotationsChangedPredicate()),
+		)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Named("mail")
+
+	if r.KillSwitch != nil {
+		// Retry halted mail as soon as the kill switch is turned off
+		key := r.KillSwitch.Key()
+		b = b.Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mailWithoutOrders),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+				return o.GetNamespace() == key.Namespace && o.GetName() == key.Name
+			})),
+		)
+	}
+
+	return b.Complete(r)
+}
+
+// mailWithoutOrders returns requests for all mail that doesn't have an order yet.
+func (r *MailReconciler) mailWithoutOrders(ctx context.Context, _ client.Object) []reconcile.Request {
+	mailList := &mailformv1alpha1.MailList{}
+	err := r.List(ctx, mailList)
 	if err != nil {
-		return err
+		logf.FromContext(ctx).Error(err, "unable to list mail for kill switch")
+		return nil
 	}
 
-	return nil
+	var requests []reconcile.Request
+	for _, mail := range mailList.Items {
+		if mail.Status.ID == "" {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: mail.Namespace, Name: mail.Name},
+			})
+		}
+	}
+
+	return requests
+}
+
+// controlAnnotationsChangedPredicate admits updates that change the pause or resync annotations.
+// Annotation changes don't change the generation so they're otherwise filtered out.
+func controlAnnotationsChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return false
+			}
+
+			oldAnnotations := e.ObjectOld.GetAnnotations()
+			newAnnotations := e.ObjectNew.GetAnnotations()
+
+			return oldAnnotations[pausedAnnotation] != newAnnotations[pausedAnnotation] ||
+				oldAnnotations[resyncRequestedAnnotation] != newAnnotations[resyncRequestedAnnotation]
+		},
+	}
+}
+
+// loadMail fetches the Mail object.
+func (r *MailReconciler) loadMail(ctx context.Context, key types.NamespacedName) (*mailformv1alpha1.Mail, error) {
+	mail := &mailformv1alpha1.Mail{}
+
+	err := r.Get(ctx, key, mail)
+	if err != nil {
+		return nil, err
+	}
+
+	return mail, nil
+}
+
+// ensureMailSentOrCancelledFinalizer adds the finalizer responsible for not allowing delete until sent/cancelled.
+func (r *MailReconciler) ensureMailSentOrCancelledFinalizer(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	if mail.DeletionTimestamp.IsZero() && !controllerutil.ContainsFinalizer(mail, mailSentOrCancelledFinalizerName) {
+		return r.patchFinalizers(ctx, mail, func(mail *mailformv1alpha1.Mail) bool {
+			return mail.DeletionTimestamp.IsZero() && controllerutil.AddFinalizer(mail, mailSentOrCancelledFinalizerName)
+		})
+	}
+
+	return nil
+}
+
+// removeMailSentOrCancelledFinalizer removes the finalizer so the mail can be deleted.
+func (r *MailReconciler) removeMailSentOrCancelledFinalizer(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	return r.patchFinalizers(ctx, mail, func(mail *mailformv1alpha1.Mail) bool {
+		return controllerutil.RemoveFinalizer(mail, mailSentOrCancelledFinalizerName)
+	})
+}
+
+// patchFinalizers applies mutate to the mail's finalizers and patches them if they changed.
+// Finalizers are a list that merge patches replace whole, so the patch only applies to the latest version of the
+// mail so another writer's finalizers aren't dropped. On conflict the mail is fetched again and mutate reapplied.
+func (r *MailReconciler) patchFinalizers(ctx context.Context, mail *mailformv1alpha1.Mail, mutate func(*mailformv1alpha1.Mail) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		base := mail.DeepCopy()
+		if !mutate(mail) {
+			return nil
+		}
+
+		patch := client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})
+		err := r.Patch(ctx, mail, patch, client.FieldOwner(fieldManager))
+		if apierrors.IsConflict(err) {
+			getErr := r.Get(ctx, client.ObjectKeyFromObject(mail), mail)
+			if getErr != nil {
+				return getErr
+			}
+		}
+
+		return err
+	})
+}
+
+// setPausedCondition records whether the mail is paused. Mail that was never paused isn't given the condition.
+func (r *MailReconciler) setPausedCondition(ctx context.Context, mail *mailformv1alpha1.Mail, paused bool) error {
+	if !paused && meta.FindStatusCondition(mail.Status.Conditions, typePausedMail) == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:    typePausedMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Paused",
+		Message: fmt.Sprintf("Reconciliation paused by the %s annotation", pausedAnnotation),
+	}
+	if !paused {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Resumed"
+		condition.Message = "Reconciliation resumed"
+	}
+
+	base := mail.DeepCopy()
+	meta.SetStatusCondition(&mail.Status.Conditions, condition)
+
+	return r.patchStatus(ctx, mail, base)
+}
+
+// ensureOrderIdentity recovers the mail's order ID from its annotations if needed, then keeps them in sync.
+func (r *MailReconciler) ensureOrderIdentity(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	err := r.recoverOrderID(ctx, mail)
+	if err != nil {
+		return err
+	}
+
+	return r.annotateOrderID(ctx, mail)
+}
+
+// recoverOrderID restores the mail's order ID from its annotations if its status was lost.
+// Backup tools such as Velero restore objects without their status, placing the order again would send it twice.
+// Annotations are also copied when a Mail is cloned, so the order is only recovered if no other Mail owns it. Otherwise
+// the copied annotations are dropped and the mail is ordered like any new Mail.
+func (r *MailReconciler) recoverOrderID(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	log := logf.FromContext(ctx)
+
+	annotatedID := mail.Annotations[orderIDAnnotation]
+	if mail.Status.ID != "" || annotatedID == "" || mail.Annotations[orderProviderAnnotation] != orderProvider {
+		return nil
+	}
+
+	owner, err := r.findOrderOwner(ctx, mail, annotatedID)
+	if err != nil {
+		return err
+	}
+
+	base := mail.DeepCopy()
+
+	if owner != nil {
+		log.Info("order already owned by another mail, not recovering",
+			"name", mail.Name, "orderID", annotatedID, "owner", client.ObjectKeyFromObject(owner))
+
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:   typeOrderRecoveredMail,
+			Status: metav1.ConditionFalse,
+			Reason: "AlreadyOwned",
+			Message: fmt.Sprintf("Order %s from the %s annotation is already owned by mail %s",
+				annotatedID, orderIDAnnotation, client.ObjectKeyFromObject(owner)),
+		})
+
+		return r.patchStatus(ctx, mail, base)
+	}
+
+	log.Info("recovered order ID from annotations", "name", mail.Name, "orderID", annotatedID)
+
+	mail.Status.ID = annotatedID
+	meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+		Type:    typeOrderRecoveredMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Recovered",
+		Message: fmt.Sprintf("Order ID recovered from the %s annotation", orderIDAnnotation),
+	})
+
+	return r.patchStatus(ctx, mail, base)
+}
+
+// annotateOrderID records the mail's order ID and provider in its annotations, or removes them if it has no order.
+func (r *MailReconciler) annotateOrderID(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	annotatedID, annotated := mail.Annotations[orderIDAnnotation]
+	if mail.Status.ID == "" && !annotated {
+		return nil
+	}
+
+	if mail.Status.ID != "" && annotatedID == mail.Status.ID && mail.Annotations[orderProviderAnnotation] == orderProvider {
+		return nil
+	}
+
+	base := mail.DeepCopy()
+	if mail.Status.ID == "" {
+		delete(mail.Annotations, orderIDAnnotation)
+		delete(mail.Annotations, orderProviderAnnotation)
+	} else {
+		if mail.Annotations == nil {
+			mail.Annotations = map[string]string{}
+		}
+		mail.Annotations[orderIDAnnotation] = mail.Status.ID
+		mail.Annotations[orderProviderAnnotation] = orderProvider
+	}
+
+	// Annotations merge by key so this can't overwrite another writer's annotations
+	err := r.Patch(ctx, mail, client.MergeFrom(base), client.FieldOwner(fieldManager))
+	if err != nil {
+		// Keep the annotations as they were so writing them is tried again
+		mail.Annotations = base.Annotations
+	}
+
+	return err
+}
+
+// patchStatus writes the changes made to the mail's status since base.
+// Merge patches don't conflict with other writers, so status such as a new order ID is never lost because the
+// mail was modified while the controller was working on it. Nothing is written if the status didn't change.
+func (r *MailReconciler) patchStatus(ctx context.Context, mail *mailformv1alpha1.Mail, base *mailformv1alpha1.Mail) error {
+	if equality.Semantic.DeepEqual(base.Status, mail.Status) {
+		return nil
+	}
+
+	return r.Status().Patch(ctx, mail, client.MergeFrom(base), client.FieldOwner(fieldManager))
+}
+
+// Ensure finalizer conditions are met and can be deleted
+func (r *MailReconciler) handleDeletion(ctx context.Context, mail *mailformv1alpha1.Mail) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	if mail.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+
+	if controllerutil.ContainsFinalizer(mail, mailSentOrCancelledFinalizerName) {
+		// Check for skip override
+		if r.skipCancellation(mail) {
+			log.Info("skipping cancellation for", "orderID", mail.Status.ID, "name", mail.Name)
+			return true, r.removeMailSentOrCancelledFinalizer(ctx, mail)
+		}
+
+		// Nothing to cancel if the order already finished, was lost or was never created
+		if mail.Status.ID != "" && !isFinished(mail) && !isOrderLost(mail) {
+			err := r.cancelIfUnfinished(ctx, mail)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		// Keep a record of the order before the mail is gone
+		if mail.Status.ID != "" {
+			err := r.ensureArchived(ctx, mail, nil)
+			if err != nil {
+				giveUp, err := r.handleArchiveFailed(ctx, mail, err)
+				if !giveUp {
+					return false, err
+				}
+			}
+		}
+
+		// Remove finalizer after cancelling or if already sent
+		err := r.removeMailSentOrCancelledFinalizer(ctx, mail)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// handleArchiveFailed records why deleted mail couldn't be archived and reports whether to give up archiving it.
+// Deletion waits for archiving for up to archiveOnDeleteTimeout so an unavailable archive backend can't leave mail
+// stuck terminating. The skip cancellation annotation deletes the mail without archiving it right away.
+func (r *MailReconciler) handleArchiveFailed(ctx context.Context, mail *mailformv1alpha1.Mail, err error) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	deadline := mail.DeletionTimestamp.Add(archiveOnDeleteTimeout)
+	if !time.Now().Before(deadline) {
+		log.Error(err, "giving up archiving deleted mail", "orderID", mail.Status.ID, "name", mail.Name)
+		return true, nil
+	}
+
+	base := mail.DeepCopy()
+	meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+		Type:   typeArchivedMail,
+		Status: metav1.ConditionFalse,
+		Reason: "ArchiveFailed",
+		Message: fmt.Sprintf("Deletion is waiting for the mail to be archived until %s, or the %s annotation is set: %s",
+			deadline.UTC().Format(time.RFC3339), skipCancellationOnDeleteAnnotation, err),
+	})
+
+	return false, errors.Join(err, r.patchStatus(ctx, mail, base))
+}
+
+// cancelIfUnfinished cancels the mail's order unless it has already been sent or cancelled.
+// Orders that no longer exist have nothing to cancel so they don't block deletion.
+func (r *MailReconciler) cancelIfUnfinished(ctx context.Context, mail *mailformv1alpha1.Mail) error {
+	log := logf.FromContext(ctx)
+
+	// Fetch latest state from Mailform
+	order, err := r.getOrder(mail.Status.ID)
+	if provider.Classify(err) == provider.ClassNotFound {
+		log.Info("order not found, nothing to cancel", "orderID", mail.Status.ID, "name", mail.Name)
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "failed to fetch order for deletion check", "orderID", mail.Status.ID, "name", mail.Name)
+		return err
+	}
+
+	// Only cancel if not already sent/cancelled
+	if order != nil && (order.Data.State == mailform.StatusFulfilled || order.Data.State == mailform.StatusCancelled) {
+		return nil
+	}
+
+	err = r.cancelOrder(mail.Status.ID)
+	if provider.Classify(err) == provider.ClassNotFound {
+		log.Info("order not found, nothing to cancel", "orderID", mail.Status.ID, "name", mail.Name)
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "failed to cancel order", "orderID", mail.Status.ID, "name", mail.Name)
+		return err
+	}
+
+	log.Info("order cancelled", "orderID", mail.Status.ID, "name", mail.Name)
+
+	return nil
+}
+
+// handleOrderRejected marks the mail as invalid when Mailform rejects its order.
+// Retrying won't help until the spec changes, which triggers a new reconcile.
+func (r *MailReconciler) handleOrderRejected(ctx context.Context, mail *mailformv1alpha1.Mail, err error) error {
+	log := logf.FromContext(ctx)
+	log.Error(err, "order rejected by mailform, not retrying", "name", mail.Name)
+
+	base := mail.DeepCopy()
+	mail.Status.Valid = false
+	return r.patchStatus(ctx, mail, base)
+}
+
+// handleProviderError decides how to retry a failed Mailform request based on the class of error.
+// Validation errors are retried with backoff like unknown errors. Only rejected orders are final, see handleOrderRejected,
+// and an order that was already accepted may be reported as invalid by an odd response when it's fetched.
+func (r *MailReconciler) handleProviderError(ctx context.Context, mail *mailformv1alpha1.Mail, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	switch provider.Classify(err) {
+	case provider.ClassNotFound:
+		if mail.Status.ID != "" {
+			return r.handleOrderLost(ctx, mail, err)
+		}
+	case provider.ClassRateLimit:
+		log.Info("rate limited by mailform, requeuing", "name", mail.Name, "requeueAfter", rateLimitedRequeueAfter)
+		return ctrl.Result{RequeueAfter: rateLimitedRequeueAfter}, nil
+	case provider.ClassUnavailable:
+		return r.handleProviderUnavailable(ctx, mail, err)
+	}
+
+	return ctrl.Result{}, err
+}
+
+// handleProviderUnavailable marks the mail as waiting on Mailform and requeues it once the circuit breaker allows requests.
+func (r *MailReconciler) handleProviderUnavailable(ctx context.Context, mail *mailformv1alpha1.Mail, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	requeueAfter := rateLimitedRequeueAfter
+	circuitOpenErr := &provider.CircuitOpenError{}
+	if errors.As(err, &circuitOpenErr) {
+		requeueAfter = max(circuitOpenErr.RetryAfter, time.Second)
+	}
+
+	log.Info("mailform unavailable, requeuing", "name", mail.Name, "requeueAfter", requeueAfter)
+
+	base := mail.DeepCopy()
+	meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+		Type:    typeProviderUnavailableMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "CircuitOpen",
+		Message: "Requests to Mailform are paused after repeated failures",
+	})
+
+	err = r.patchStatus(ctx, mail, base)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// handleOrderLost applies the order lost policy to mail whose order no longer exists.
+func (r *MailReconciler) handleOrderLost(ctx context.Context, mail *mailformv1alpha1.Mail, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	base := mail.DeepCopy()
+	lostOrderID := mail.Status.ID
+	policy := r.orderLostPolicy(mail)
+
+	if policy == mailformv1alpha1.OrderLostPolicyRecreate {
+		log.Info("order lost, recreating", "name", mail.Name, "orderID", lostOrderID)
+
+		mail.Status = mailformv1alpha1.MailStatus{
+			Valid:                 mail.Status.Valid,
+			Conditions:            mail.Status.Conditions,
+			ArchiveLocation:       mail.Status.ArchiveLocation,
+			ObservedResyncRequest: mail.Annotations[resyncRequestedAnnotation],
+		}
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:    typeOrderLostMail,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Recreated",
+			Message: fmt.Sprintf("Order %s was not found, a new order will be created: %s", lostOrderID, err),
+		})
+
+		err = r.patchStatus(ctx, mail, base)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Forget the lost order so it isn't recovered from the annotations
+		err = r.annotateOrderID(ctx, mail)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Status updates don't trigger reconciles so requeue to create the new order
+		return ctrl.Result{RequeueAfter: orderLostRequeueAfter}, nil
+	}
+
+	log.Info("order lost, giving up", "name", mail.Name, "orderID", lostOrderID)
+
+	// A resync of lost mail can't refresh anything, so it's done once the order is found to still be missing
+	mail.Status.ObservedResyncRequest = mail.Annotations[resyncRequestedAnnotation]
+	meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+		Type:    typeOrderLostMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NotFound",
+		Message: fmt.Sprintf("Order %s was not found: %s", lostOrderID, err),
+	})
+
+	// Requeue to archive and clean up the mail
+	return ctrl.Result{RequeueAfter: orderLostRequeueAfter}, r.patchStatus(ctx, mail, base)
+}
+
+// orderLostPolicy returns the order lost policy for the mail, preferring the Mail spec over the manager default.
+func (r *MailReconciler) orderLostPolicy(mail *mailformv1alpha1.Mail) mailformv1alpha1.OrderLostPolicy {
+	// There's no spec to recreate adopted orders from
+	if mail.Spec.AdoptOrderID != "" {
+		return mailformv1alpha1.OrderLostPolicyGiveUp
+	}
+
+	if mail.Spec.OrderLostPolicy != "" {
+		return mail.Spec.OrderLostPolicy
+	}
+
+	if policy := r.settings().OrderLostPolicy; policy != "" {
+		return policy
+	}
+
+	return mailformv1alpha1.OrderLostPolicyGiveUp
+}
+
+// ensureArchived archives the mail if an archiver is configured and it hasn't been archived yet.
+// The order is fetched from Mailform if not provided so the record and status reflect its final state.
+func (r *MailReconciler) ensureArchived(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) error {
+	log := logf.FromContext(ctx)
+
+	if r.Archiver == nil || !r.settings().Archive || mail.Status.ArchiveLocation != "" || mail.Status.ID == "" {
+		return nil
+	}
+
+	base := mail.DeepCopy()
+
+	var err error
+	if order == nil {
+		order, err = r.getOrder(mail.Status.ID)
+		switch {
+		case provider.Classify(err) == provider.ClassNotFound:
+			// Archive what is known about lost orders
+			order = nil
+		case err != nil:
+			log.Error(err, "failed to fetch order for archiving", "orderID", mail.Status.ID, "name", mail.Name)
+			return err
+		default:
+			setStatusFromOrder(mail, order)
+		}
+	}
+
+	location, err := r.Archiver.Archive(ctx, mail, order)
+	if err != nil {
+		log.Error(err, "failed to archive mail", "orderID", mail.Status.ID, "name", mail.Name)
+		return err
+	}
+
+	mail.Status.ArchiveLocation = location
+
+	err = r.patchStatus(ctx, mail, base)
+	if err != nil {
+		return err
+	}
+
+	log.Info("archived mail", "orderID", mail.Status.ID, "name", mail.Name, "location", location)
+
+	return nil
+}
+
+// handleTTLAfterFinished deletes finished mail once its TTL has expired, otherwise requeues until it does.
+func (r *MailReconciler) handleTTLAfterFinished(ctx context.Context, mail *mailformv1alpha1.Mail) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	ttl, ok := r.ttlAfterFinished(mail)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	remaining := time.Until(finishedAt(mail).Add(ttl))
+	if remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	log.Info("ttl after finished expired, deleting mail", "name", mail.Name, "orderID", mail.Status.ID, "ttl", ttl)
+
+	err := r.Delete(ctx, mail)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// ttlAfterFinished returns the TTL for finished mail, preferring the Mail spec over the manager default.
+// Returns false if no TTL applies.
+func (r *MailReconciler) ttlAfterFinished(mail *mailformv1alpha1.Mail) (time.Duration, bool) {
+	if mail.Spec.TTLSecondsAfterFinished != nil {
+		return time.Duration(*mail.Spec.TTLSecondsAfterFinished) * time.Second, true
+	}
+
+	if ttl := r.settings().TTLAfterFinished; ttl > 0 {
+		return ttl, true
+	}
+
+	return 0, false
+}
+
+// skipCancellation reports whether the mail's order should be left alone when it's deleted.
+// The skip-cancellation annotation is preferred over the default deletion policy.
+func (r *MailReconciler) skipCancellation(mail *mailformv1alpha1.Mail) bool {
+	val, ok := mail.Annotations[skipCancellationOnDeleteAnnotation]
+	if ok {
+		skip, _ := strconv.ParseBool(val)
+		return skip
+	}
+
+	return r.settings().DeletionPolicy == mailformv1alpha1.DeletionPolicyOrphan
+}
+
+// isFinished reports whether the mail order has been fulfilled or cancelled.
+func isFinished(mail *mailformv1alpha1.Mail) bool {
+	return mail.Status.Sent || mail.Status.State == mailform.StatusFulfilled || mail.Status.State == mailform.StatusCancelled
+}
+
+// isPaused reports whether the mail's paused annotation is set to true.
+func isPaused(mail *mailformv1alpha1.Mail) bool {
+	paused, _ := strconv.ParseBool(mail.Annotations[pausedAnnotation])
+	return paused
+}
+
+// resyncRequested reports whether the mail has a resync request that hasn't been handled yet.
+func resyncRequested(mail *mailformv1alpha1.Mail) bool {
+	request := mail.Annotations[resyncRequestedAnnotation]
+	return request != "" && request != mail.Status.ObservedResyncRequest
+}
+
+// isOrderLost reports whether the mail's order could not be found and it was given up on.
+func isOrderLost(mail *mailformv1alpha1.Mail) bool {
+	return meta.IsStatusConditionTrue(mail.Status.Conditions, typeOrderLostMail)
+}
+
+// finishedAt returns when the mail order finished, falling back to the creation time if unknown.
+func finishedAt(mail *mailformv1alpha1.Mail) time.Time {
+	if condition := meta.FindStatusCondition(mail.Status.Conditions, typeOrderLostMail); condition != nil &&
+		condition.Status == metav1.ConditionTrue {
+		return condition.LastTransitionTime.Time
+	}
+
+	if mail.Status.State == mailform.StatusCancelled && !mail.Status.Cancelled.IsZero() {
+		return mail.Status.Cancelled.Time
+	}
+
+	if !mail.Status.Modified.IsZero() {
+		return mail.Status.Modified.Time
+	}
+
+	return mail.CreationTimestamp.Time
+}
+
+// writeDocument writes the document to path.
+func writeDocument(path string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o600)
+}
+
+// ensureAdopted takes ownership of an existing order referenced by spec.adoptOrderID.
+// Returns false if the order is already owned by another Mail.
+func (r *MailReconciler) ensureAdopted(ctx context.Context, mail *mailformv1alpha1.Mail) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	if mail.Status.ID != "" {
+		return true, nil
+	}
+
+	owner, err := r.findOrderOwner(ctx, mail, mail.Spec.AdoptOrderID)
+	if err != nil {
+		return false, err
+	}
+
+	base := mail.DeepCopy()
+
+	if owner != nil {
+		log.Info("order already owned by another mail, not adopting",
+			"name", mail.Name, "orderID", mail.Spec.AdoptOrderID, "owner", client.ObjectKeyFromObject(owner))
+
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:    typeAdoptedMail,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AlreadyOwned",
+			Message: fmt.Sprintf("Order %s is already owned by mail %s", mail.Spec.AdoptOrderID, client.ObjectKeyFromObject(owner)),
+		})
+
+		return false, r.patchStatus(ctx, mail, base)
+	}
+
+	order, err := r.getOrder(mail.Spec.AdoptOrderID)
+	if err != nil {
+		log.Error(err, "error fetching order to adopt", "name", mail.Name, "orderID", mail.Spec.AdoptOrderID,
+			"class", provider.Classify(err))
+		return false, err
+	}
+
+	mail.Status.ID = mail.Spec.AdoptOrderID
+	mail.Status.Valid = true
+	setStatusFromOrder(mail, order)
+
+	meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+		Type:    typeAdoptedMail,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Adopted",
+		Message: "Existing order adopted",
+	})
+
+	err = r.patchStatus(ctx, mail, base)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.annotateOrderID(ctx, mail)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info("adopted mail order", "name", mail.Name, "orderID", mail.Status.ID)
+
+	return true, nil
+}
+
+// findOrderOwner returns the Mail that already owns the order the mail wants to adopt or recover, if any.
+// When several Mail adopt or recover the same order at once the oldest wins.
+func (r *MailReconciler) findOrderOwner(
+	ctx context.Context, mail *mailformv1alpha1.Mail, orderID string,
+) (*mailformv1alpha1.Mail, error) {
+	mailList := &mailformv1alpha1.MailList{}
+
+	err := r.List(ctx, mailList)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range mailList.Items {
+		other := &mailList.Items[i]
+		if other.UID == mail.UID {
+			continue
+		}
+
+		if other.Status.ID == orderID {
+			return other, nil
+		}
+
+		if other.Status.ID == "" && claimsOrder(other, orderID) && adoptsFirst(other, mail) {
+			return other, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// claimsOrder reports whether mail without an order ID is adopting the order or will recover it from its annotations.
+func claimsOrder(mail *mailformv1alpha1.Mail, orderID string) bool {
+	if mail.Spec.AdoptOrderID == orderID {
+		return true
+	}
+
+	return mail.Annotations[orderIDAnnotation] == orderID && mail.Annotations[orderProviderAnnotation] == orderProvider
+}
+
+// adoptsFirst reports whether a takes precedence over b when both adopt the same order.
+func adoptsFirst(a, b *mailformv1alpha1.Mail) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	return a.UID < b.UID
+}
+
+// createOrder with create an order.
+// Verified content is uploaded as is, otherwise documents stored in ConfigMaps are read right before uploading.
+func (r *MailReconciler) createOrder(
+	ctx context.Context, mail *mailformv1alpha1.Mail, orderInput *mailform.OrderInput, content []byte,
+) (string, error) {
+	if content == nil && mail.Spec.ConfigMapRef != nil {
+		b, err := document.ReadConfigMap(ctx, r.apiReader(), mail.Namespace, mail.Spec.ConfigMapRef)
+		if err != nil {
+			return "", err
+		}
+		content = b
+	}
+
+	if content != nil && orderinput.UploadsDocument(mail) {
+		err := writeDocument(orderInput.FilePath, content)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(orderInput.FilePath) // nolint:errcheck
+	}
+
+	order, err := r.MailformClient.CreateOrder(*orderInput)
+	if err != nil {
+		return "", err
+	}
+
+	base := mail.DeepCopy()
+	mail.Status.ID = order.Data.ID
+	r.archiveDocument(ctx, mail, content)
+
+	return order.Data.ID, r.recordOrderID(ctx, mail, base)
+}
+
+// recordOrderID writes a new order's ID to the mail's status and annotations, retrying failed writes.
+// The order has already been placed, so if its ID were lost the next reconcile would place it again. The annotation
+// is written even if the status can't be so the order ID can still be recovered from it.
+func (r *MailReconciler) recordOrderID(ctx context.Context, mail *mailformv1alpha1.Mail, base *mailformv1alpha1.Mail) error {
+	statusErr := retry.OnError(retry.DefaultBackoff, isRetriableWrite, func() error {
+		return r.patchStatus(ctx, mail, base)
+	})
+
+	annotateErr := retry.OnError(retry.DefaultBackoff, isRetriableWrite, func() error {
+		return r.annotateOrderID(ctx, mail)
+	})
+
+	return errors.Join(statusErr, annotateErr)
+}
+
+// isRetriableWrite reports whether writing to the mail can succeed if tried again, i.e. the mail still exists.
+func isRetriableWrite(err error) bool {
+	return !apierrors.IsNotFound(err)
+}
+
+// archiveDocument archives the document submitted with the mail's order and records where in its DocumentStatus.
+// Failures are only logged since the order has already been placed. The document is read again when the mail is archived.
+func (r *MailReconciler) archiveDocument(ctx context.Context, mail *mailformv1alpha1.Mail, content []byte) {
+	if r.Archiver == nil || !r.settings().Archive || content == nil || mail.Status.Document == nil {
+		return
+	}
+
+	location, err := r.Archiver.ArchiveDocument(ctx, mail, content)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to archive document", "orderID", mail.Status.ID, "name", mail.Name)
+		return
+	}
+
+	mail.Status.Document.ArchiveLocation = location
+}
+
+// getOrder retrieves the order via the external API.
+func (r *MailReconciler) getOrder(orderID string) (*mailform.Order, error) {
+	return r.MailformClient.GetOrder(orderID)
+}
+
+// cancelOrder cancels the order via the external API.
+func (r *MailReconciler) cancelOrder(orderID string) error {
+	return r.MailformClient.CancelOrder(orderID)
+}
+
+// updateStatusFromOrder maps the external order into Mail.Status and persists it.
+func (r *MailReconciler) updateStatusFromOrder(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) error {
+	base := mail.DeepCopy()
+	setStatusFromOrder(mail, order)
+
+	// The status was just refreshed so any requested resync is done
+	mail.Status.ObservedResyncRequest = mail.Annotations[resyncRequestedAnnotation]
+
+	err := r.patchStatus(ctx, mail, base)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setStatusFromOrder maps the external order into Mail.Status without persisting it.
+// Mapping the same order again leaves the status unchanged.
+func setStatusFromOrder(mail *mailformv1alpha1.Mail, order *mailform.Order) {
+	mail.Status.Sent = order.Data.State == mailform.StatusFulfilled
+	mail.Status.State = order.Data.State
+	mail.Status.Total = order.Data.Total
+	// Times are stored with second precision, truncate them so they compare equal once stored
+	mail.Status.Created = metav1.NewTime(order.Data.Created).Rfc3339Copy()
+	mail.Status.Modified = metav1.NewTime(order.Data.Modified).Rfc3339Copy()
+	mail.Status.Cancelled = metav1.NewTime(order.Data.Cancelled).Rfc3339Copy()
+	mail.Status.CancellationReason = order.Data.CancellationReason
+
+	// Mailform is responding again
+	if meta.IsStatusConditionTrue(mail.Status.Conditions, typeProviderUnavailableMail) {
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:    typeProviderUnavailableMail,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Available",
+			Message: "Requests to Mailform succeeded",
+		})
+	}
+
+	now := metav1.Now()
+
+	if mail.Status.Sent {
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:               typeFulfillmentMail,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Fulfilled",
+			Message:            "Mail order fulfilled",
+			LastTransitionTime: now,
+		})
+	} else {
+		meta.SetStatusCondition(&mail.Status.Conditions, metav1.Condition{
+			Type:               typeFulfillmentMail,
+			Status:             metav1.ConditionFalse,
+			Reason:             "AwaitingFulfillment",
+			Message:            "Mail order not fulfilled yet",
+			LastTransitionTime: now,
+		})
+	}
 }
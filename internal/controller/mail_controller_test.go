@@ -2,27 +2,82 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/circa10a/go-mailform"
 	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/document"
+	"github.com/circa10a/postk8s/internal/orderinput"
+	"github.com/circa10a/postk8s/internal/polling"
+	"github.com/circa10a/postk8s/internal/provider"
+	"github.com/circa10a/postk8s/internal/runtimeconfig"
 )
 
+// flakyStatusClient fails the next failures status patches, as if the apiserver were briefly unavailable
+type flakyStatusClient struct {
+	client.Client
+	failures int
+}
+
+// Status returns a status writer that fails while the client has failures left
+func (c *flakyStatusClient) Status() client.SubResourceWriter {
+	return &flakyStatusWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+type flakyStatusWriter struct {
+	client.SubResourceWriter
+	client *flakyStatusClient
+}
+
+// Patch fails if the client has failures left, otherwise patches the status
+func (w *flakyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if w.client.failures > 0 {
+		w.client.failures--
+		return errors.NewServiceUnavailable("apiserver unavailable")
+	}
+
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
 // mockMailformClient is for mocking mailform responses
 type mockMailformClient struct {
 	output  *mailform.Order
 	mockErr error
+	// hook is called during every request, e.g. to modify the mail while the controller is working on it
+	hook func()
+	// input is set to the order input of every order created, if not nil
+	input *mailform.OrderInput
 }
 
 // CreateOrder is for creating mock orders. Will return mockErr if not nil
 func (m mockMailformClient) CreateOrder(o mailform.OrderInput) (*mailform.Order, error) {
+	if m.hook != nil {
+		m.hook()
+	}
+
+	if m.input != nil {
+		*m.input = o
+	}
+
 	if m.mockErr != nil {
 		return m.output, m.mockErr
 	}
@@ -32,6 +87,10 @@ func (m mockMailformClient) CreateOrder(o mailform.OrderInput) (*mailform.Order,
 
 // GetOrder is for fetching mock orders. Will return mockErr if not nil
 func (m mockMailformClient) GetOrder(o string) (*mailform.Order, error) {
+	if m.hook != nil {
+		m.hook()
+	}
+
 	if m.mockErr != nil {
 		return m.output, m.mockErr
 	}
@@ -41,6 +100,10 @@ func (m mockMailformClient) GetOrder(o string) (*mailform.Order, error) {
 
 // CancelOrder is for cancelling mock orders. Will return mockErr if not nil
 func (m mockMailformClient) CancelOrder(o string) error {
+	if m.hook != nil {
+		m.hook()
+	}
+
 	if m.mockErr != nil {
 		return m.mockErr
 	}
@@ -48,6 +111,48 @@ func (m mockMailformClient) CancelOrder(o string) error {
 	return nil
 }
 
+// mockArchiver is for mocking archive backends
+type mockArchiver struct {
+	location string
+	// mockErr is returned by Archive if not nil
+	mockErr  error
+	archived []*mailformv1alpha1.Mail
+	// documents are the documents archived when orders were created
+	documents [][]byte
+}
+
+// ArchiveDocument records the document that was archived and returns the mock location of it
+func (m *mockArchiver) ArchiveDocument(ctx context.Context, mail *mailformv1alpha1.Mail, document []byte) (string, error) {
+	m.documents = append(m.documents, document)
+	return m.location + "document.pdf", nil
+}
+
+// Archive records the mail that was archived and returns the mock location
+func (m *mockArchiver) Archive(ctx context.Context, mail *mailformv1alpha1.Mail, order *mailform.Order) (string, error) {
+	if m.mockErr != nil {
+		return "", m.mockErr
+	}
+
+	m.archived = append(m.archived, mail.DeepCopy())
+	return m.location, nil
+}
+
+// mockKillSwitch is for mocking the kill switch
+type mockKillSwitch struct {
+	halted bool
+	reason string
+}
+
+// Halted returns whether the mock kill switch is halted
+func (m *mockKillSwitch) Halted(ctx context.Context) (bool, string, error) {
+	return m.halted, m.reason, nil
+}
+
+// Key returns the mock kill switch configmap
+func (m *mockKillSwitch) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: namespaceName, Name: "postk8s-kill-switch"}
+}
+
 const (
 	resourceName  = "test-resource"
 	namespaceName = "default"
@@ -132,6 +237,12 @@ var _ = Describe("Mail Controller", func() {
 				Spec: mailformv1alpha1.MailSpec{
 					Service: "RESPECT_MUH_AUTHORITAH",
 					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					Check: &mailformv1alpha1.Check{
+						BankAccount: "ba_1234",
+						AmountCents: 100,
+						Name:        "test",
+						Number:      1,
+					},
 					To: &mailformv1alpha1.Address{
 						Name:     "test",
 						Address1: "test",
@@ -151,6 +262,15 @@ var _ = Describe("Mail Controller", func() {
 				},
 			}
 
+			// Unknown services are rejected by the CRD
+			Expect(k8sClient.Create(ctx, resource)).To(MatchError(ContainSubstring("spec.service: Unsupported value")))
+
+			// Domestic services are rejected for other countries by the CRD
+			resource.Spec.Service = mailformv1alpha1.ServiceUSPSStandard
+			Expect(k8sClient.Create(ctx, resource)).To(MatchError(ContainSubstring("service only delivers within the US")))
+
+			// Checks can't be mailed with postcards
+			resource.Spec.Service = mailformv1alpha1.ServiceUSPSPostcard
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 
 			fetched := &mailformv1alpha1.Mail{}
@@ -282,18 +402,22 @@ var _ = Describe("Mail Controller", func() {
 			Expect(fetched.Status.Valid).To(BeTrue())
 			Expect(fetched.Status.Sent).To(BeFalse())
 			Expect(fetched.GetFinalizers()).To(ContainElement(mailSentOrCancelledFinalizerName))
+			Expect(fetched.Annotations).To(HaveKeyWithValue(orderIDAnnotation, "order-123"))
+			Expect(fetched.Annotations).To(HaveKeyWithValue(orderProviderAnnotation, orderProvider))
 		})
 
-		It("should update sent status when external order is fulfilled", func() {
+		It("should recover the order ID instead of ordering again when restored without status", func() {
 			ctx := context.Background()
 			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
 
-			order := &mailform.Order{Success: true}
-			order.Data.ID = "order-fulfilled"
-			order.Data.State = mailform.StatusFulfilled
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
 			order.Data.Created = time.Now()
 			order.Data.Modified = time.Now()
-			order.Data.Total = 20
+			order.Data.Total = 10
 
 			resource := &mailformv1alpha1.Mail{
 				ObjectMeta: metav1.ObjectMeta{
@@ -304,99 +428,147 @@ var _ = Describe("Mail Controller", func() {
 					Service: "USPS_PRIORITY",
 					URL:     "https://pdfobject.com/pdf/sample.pdf",
 					To: &mailformv1alpha1.Address{
-						Name:     "to",
-						Address1: "a",
-						City:     "b",
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "12345",
+						Postcode: "11111",
 						State:    "CA",
 					},
 					From: &mailformv1alpha1.Address{
-						Name:     "from",
-						Address1: "a",
-						City:     "b",
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "54321",
+						Postcode: "22222",
 						State:    "CA",
 					},
 				},
 			}
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 
-			resource.Status.ID = "order-fulfilled"
-			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
-
 			controller := &MailReconciler{
 				Client:         k8sClient,
 				Scheme:         k8sClient.Scheme(),
 				MailformClient: mockMailformClient{output: order},
-				SyncInterval:   2 * time.Second,
+				SyncInterval:   1 * time.Second,
 			}
 
-			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(2 * time.Second))
 
+			// Restore tools such as Velero drop the status subresource
 			fetched := &mailformv1alpha1.Mail{}
 			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
-			Expect(fetched.Status.Sent).To(BeTrue())
-			Expect(fetched.Status.State).To(Equal(mailform.StatusFulfilled))
+			fetched.Status = mailformv1alpha1.MailStatus{}
+			Expect(k8sClient.Status().Update(ctx, fetched)).To(Succeed())
+
+			// Any new order would get a new ID
+			newOrder := &mailform.Order{Success: true}
+			newOrder.Data.ID = "order-duplicate"
+			newOrder.Data.State = mailform.StatusQueued
+			controller.MailformClient = mockMailformClient{output: newOrder}
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-123"))
+			Expect(fetched.Annotations).To(HaveKeyWithValue(orderIDAnnotation, "order-123"))
 		})
 
-		It("should return an error if mailform API fails", func() {
+		It("should not recover an order owned by another mail with the same annotations", func() {
 			ctx := context.Background()
 			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
 
-			resource := &mailformv1alpha1.Mail{
+			spec := mailformv1alpha1.MailSpec{
+				Service: "USPS_PRIORITY",
+				URL:     "https://pdfobject.com/pdf/sample.pdf",
+				To: &mailformv1alpha1.Address{
+					Name:     "to-name",
+					Address1: "123 Main St",
+					City:     "City",
+					Country:  "US",
+					Postcode: "11111",
+					State:    "CA",
+				},
+				From: &mailformv1alpha1.Address{
+					Name:     "from-name",
+					Address1: "321 Other St",
+					City:     "City",
+					Country:  "US",
+					Postcode: "22222",
+					State:    "CA",
+				},
+			}
+			annotations := map[string]string{
+				orderIDAnnotation:       "order-123",
+				orderProviderAnnotation: orderProvider,
+			}
+
+			original := &mailformv1alpha1.Mail{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      resourceName,
-					Namespace: namespaceName,
+					Name:        resourceName + "-original",
+					Namespace:   namespaceName,
+					Annotations: annotations,
 				},
-				Spec: mailformv1alpha1.MailSpec{
-					Service: "USPS_PRIORITY",
-					URL:     "https://pdfobject.com/pdf/sample.pdf",
-					To: &mailformv1alpha1.Address{
-						Name:     "to",
-						Address1: "a",
-						City:     "b",
-						Country:  "US",
-						Postcode: "12345",
-						State:    "CA",
-					},
-					From: &mailformv1alpha1.Address{
-						Name:     "from",
-						Address1: "a",
-						City:     "b",
-						Country:  "US",
-						Postcode: "54321",
-						State:    "CA",
-					},
+				Spec: *spec.DeepCopy(),
+			}
+			Expect(k8sClient.Create(ctx, original)).To(Succeed())
+			original.Status.ID = "order-123"
+			Expect(k8sClient.Status().Update(ctx, original)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, original)).To(Succeed())
+			}()
+
+			// Cloned with kubectl get -o yaml, renamed and applied, which copies the annotations but not the status
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        resourceName,
+					Namespace:   namespaceName,
+					Annotations: annotations,
 				},
+				Spec: *spec.DeepCopy(),
 			}
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 
-			mailformClient := mockMailformClient{mockErr: errors.NewBadRequest("mailform error")}
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-clone"
+			order.Data.State = mailform.StatusQueued
+
 			controller := &MailReconciler{
 				Client:         k8sClient,
 				Scheme:         k8sClient.Scheme(),
-				MailformClient: mailformClient,
+				MailformClient: mockMailformClient{output: order},
 				SyncInterval:   1 * time.Second,
 			}
 
 			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("mailform error"))
+			Expect(err).NotTo(HaveOccurred())
+
+			// The clone sends its own letter
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-clone"))
+			Expect(fetched.Annotations).To(HaveKeyWithValue(orderIDAnnotation, "order-clone"))
+
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeOrderRecoveredMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("AlreadyOwned"))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: original.Name, Namespace: namespaceName}, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-123"))
 		})
 
-		It("should reconcile multiple times with a short sync interval", func() {
+		It("should keep the order ID when the mail is modified while the order is created", func() {
 			ctx := context.Background()
 			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
 
-			// Mock order that starts in a pending state
 			order := &mailform.Order{
 				Success: true,
 			}
-			order.Data.ID = "order-requeue"
+			order.Data.ID = "order-123"
 			order.Data.State = mailform.StatusAwaitingFulfillment
 			order.Data.Created = time.Now()
 			order.Data.Modified = time.Now()
@@ -411,169 +583,2593 @@ var _ = Describe("Mail Controller", func() {
 					Service: "USPS_PRIORITY",
 					URL:     "https://pdfobject.com/pdf/sample.pdf",
 					To: &mailformv1alpha1.Address{
-						Name:     "to",
-						Address1: "a",
-						City:     "b",
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "12345",
+						Postcode: "11111",
 						State:    "CA",
 					},
 					From: &mailformv1alpha1.Address{
-						Name:     "from",
-						Address1: "a",
-						City:     "b",
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "54321",
+						Postcode: "22222",
 						State:    "CA",
 					},
 				},
 			}
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 
-			mockClient := &mockMailformClient{output: order}
+			// Another writer modifies the mail after the controller loaded it
+			modifyMail := func() {
+				latest := &mailformv1alpha1.Mail{}
+				Expect(k8sClient.Get(ctx, key, latest)).To(Succeed())
+				if latest.Labels == nil {
+					latest.Labels = map[string]string{}
+				}
+				latest.Labels["team"] = "billing"
+				Expect(k8sClient.Update(ctx, latest)).To(Succeed())
+			}
 
 			controller := &MailReconciler{
 				Client:         k8sClient,
 				Scheme:         k8sClient.Scheme(),
-				MailformClient: mockClient,
-				SyncInterval:   200 * time.Millisecond, // short interval for testing
+				MailformClient: mockMailformClient{output: order, hook: modifyMail},
+				SyncInterval:   1 * time.Second,
 			}
 
-			// First reconcile: should create the order and requeue
-			firstResult, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(firstResult.RequeueAfter).To(Equal(200 * time.Millisecond))
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
 
 			fetched := &mailformv1alpha1.Mail{}
 			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
-			Expect(fetched.Status.ID).To(Equal("order-requeue"))
-			Expect(fetched.Status.Sent).To(BeFalse())
-
-			// Simulate order fulfillment before the next reconcile
-			order.Data.State = mailform.StatusFulfilled
-			order.Data.Modified = time.Now().Add(100 * time.Millisecond)
-
-			// Wait for requeue interval
-			time.Sleep(250 * time.Millisecond)
-
-			// Second reconcile: should mark the mail as Sent
-			secondResult, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
-			Expect(err).NotTo(HaveOccurred())
-			Expect(secondResult.RequeueAfter).To(Equal(200 * time.Millisecond))
-
-			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed()) // did it work
-			Expect(fetched.Status.Sent).To(BeTrue())
-			Expect(fetched.Status.State).To(Equal(mailform.StatusFulfilled))
+			Expect(fetched.Status.ID).To(Equal("order-123"))
+			Expect(fetched.Status.State).To(Equal(mailform.StatusAwaitingFulfillment))
+			Expect(fetched.Labels).To(HaveKeyWithValue("team", "billing"))
+			Expect(fetched.GetFinalizers()).To(ContainElement(mailSentOrCancelledFinalizerName))
 		})
 
-		It("should remove finalizer when skip-cancellation annotation is set", func() {
+		It("should keep the order ID when writing it fails at first", func() {
 			ctx := context.Background()
 			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
 
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 10
+
 			resource := &mailformv1alpha1.Mail{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:       resourceName,
-					Namespace:  namespaceName,
-					Finalizers: []string{mailSentOrCancelledFinalizerName},
-					Annotations: map[string]string{
-						skipCancellationOnDeleteAnnotation: "true",
-					},
+					Name:      resourceName,
+					Namespace: namespaceName,
 				},
 				Spec: mailformv1alpha1.MailSpec{
 					Service: "USPS_PRIORITY",
 					URL:     "https://pdfobject.com/pdf/sample.pdf",
 					To: &mailformv1alpha1.Address{
-						Name:     "to",
-						Address1: "a",
-						City:     "b",
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "12345",
+						Postcode: "11111",
 						State:    "CA",
 					},
 					From: &mailformv1alpha1.Address{
-						Name:     "from",
-						Address1: "a",
-						City:     "b",
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "54321",
+						Postcode: "22222",
 						State:    "CA",
 					},
 				},
 			}
-
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			// The first status patch after the order is created fails
+			flakyClient := &flakyStatusClient{Client: k8sClient}
+			ordered := false
+			createOrder := func() {
+				if !ordered {
+					ordered = true
+					flakyClient.failures = 1
+				}
+			}
 
 			controller := &MailReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:         flakyClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, hook: createOrder},
+				SyncInterval:   1 * time.Second,
 			}
 
-			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+			Expect(ordered).To(BeTrue())
+			Expect(flakyClient.failures).To(BeZero())
 
 			fetched := &mailformv1alpha1.Mail{}
-			err = k8sClient.Get(ctx, key, fetched)
-			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-123"))
+			Expect(fetched.Annotations).To(HaveKeyWithValue(orderIDAnnotation, "order-123"))
 		})
 
-		It("should cancel the order and remove finalizer if mail not sent and not cancelled", func() {
+		It("should not write status again when the order is unchanged", func() {
 			ctx := context.Background()
 			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
 
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 10
+
 			resource := &mailformv1alpha1.Mail{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:       resourceName,
-					Namespace:  namespaceName,
-					Finalizers: []string{mailSentOrCancelledFinalizerName},
+					Name:      resourceName,
+					Namespace: namespaceName,
 				},
 				Spec: mailformv1alpha1.MailSpec{
 					Service: "USPS_PRIORITY",
 					URL:     "https://pdfobject.com/pdf/sample.pdf",
 					To: &mailformv1alpha1.Address{
-						Name:     "to",
-						Address1: "a",
-						City:     "b",
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "12345",
+						Postcode: "11111",
 						State:    "CA",
 					},
 					From: &mailformv1alpha1.Address{
-						Name:     "from",
-						Address1: "a",
-						City:     "b",
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
 						Country:  "US",
-						Postcode: "54321",
+						Postcode: "22222",
 						State:    "CA",
 					},
 				},
-				Status: mailformv1alpha1.MailStatus{
-					ID:    "order-123",
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			resourceVersion := fetched.ResourceVersion
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.ResourceVersion).To(Equal(resourceVersion))
+		})
+
+		It("should spread out the first sync of each mail after starting", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 10
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "11111",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "22222",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			// Use up the burst so the next mail has to wait
+			startLimiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+			startLimiter.Reserve()
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+				StartLimiter:   startLimiter,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+
+			// Mail is only delayed once
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-123"))
+		})
+
+		It("should check new orders often when polling adapts to order age", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 10
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "11111",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "22222",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			pollPolicy, err := polling.New(&polling.Config{MinInterval: 5 * time.Minute, MaxInterval: 12 * time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   12 * time.Hour,
+				PollPolicy:     pollPolicy,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+
+			// The per-mail interval overrides the policy
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			syncIntervalSeconds := int32(60)
+			fetched.Spec.SyncIntervalSeconds = &syncIntervalSeconds
+			Expect(k8sClient.Update(ctx, fetched)).To(Succeed())
+
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+		})
+
+		It("should submit every order option", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-options"
+			order.Data.State = mailform.StatusQueued
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:           "USPS_CERTIFIED",
+					URL:               "https://pdfobject.com/pdf/sample.pdf",
+					CustomerReference: "ref-1234",
+					Webhook:           "https://example.com/webhook",
+					Company:           "company",
+					Simplex:           true,
+					Color:             true,
+					Flat:              true,
+					Stamp:             true,
+					Message:           "message",
+					Check: &mailformv1alpha1.Check{
+						BankAccount: "ba_1234",
+						AmountCents: 12000,
+						Name:        "to-name",
+						Number:      1001,
+						Memo:        "Invoice 1234",
+					},
+					To: &mailformv1alpha1.Address{
+						Name:         "to-name",
+						Organization: "to-organization",
+						Address1:     "123 Main St",
+						Address2:     "Apt 1",
+						City:         "City",
+						Country:      "US",
+						Postcode:     "11111",
+						State:        "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:         "from-name",
+						Organization: "from-organization",
+						Address1:     "321 Other St",
+						Address2:     "Suite 2",
+						City:         "Town",
+						Country:      "US",
+						Postcode:     "22222",
+						State:        "NY",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			var submitted mailform.OrderInput
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, input: &submitted},
+				SyncInterval:   1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(submitted).To(Equal(mailform.OrderInput{
+				URL:               "https://pdfobject.com/pdf/sample.pdf",
+				CustomerReference: "ref-1234",
+				Service:           "USPS_CERTIFIED",
+				Webhook:           "https://example.com/webhook",
+				Company:           "company",
+				Simplex:           true,
+				Color:             true,
+				Flat:              true,
+				Stamp:             true,
+				Message:           "message",
+				ToName:            "to-name",
+				ToOrganization:    "to-organization",
+				ToAddress1:        "123 Main St",
+				ToAddress2:        "Apt 1",
+				ToCity:            "City",
+				ToState:           "CA",
+				ToPostcode:        "11111",
+				ToCountry:         "US",
+				FromName:          "from-name",
+				FromOrganization:  "from-organization",
+				FromAddress1:      "321 Other St",
+				FromAddress2:      "Suite 2",
+				FromCity:          "Town",
+				FromState:         "NY",
+				FromPostcode:      "22222",
+				FromCountry:       "US",
+				BankAccount:       "ba_1234",
+				Amount:            12000,
+				CheckName:         "to-name",
+				CheckNumber:       1001,
+				CheckMemo:         "Invoice 1234",
+			}))
+		})
+
+		It("should create an order from a document stored in a configmap", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-document",
+					Namespace: namespaceName,
+				},
+				BinaryData: map[string][]byte{
+					"document.pdf": []byte("%PDF-1.4 test document"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			}()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					ConfigMapRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+						Key:                  "document.pdf",
+					},
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "11111",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "22222",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-123"))
+			Expect(orderinput.DocumentPath(fetched)).NotTo(BeAnExistingFile())
+		})
+
+		It("should merge documents from every source into one order", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-merged"
+			order.Data.State = mailform.StatusQueued
+
+			pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\n" +
+				"4 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(pdf)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-invoice",
+					Namespace: namespaceName,
+				},
+				BinaryData: map[string][]byte{
+					"invoice.pdf": []byte(pdf),
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			}()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-statement",
+					Namespace: namespaceName,
+				},
+				Data: map[string][]byte{
+					"statement.pdf": []byte(pdf),
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+			}()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					Documents: []mailformv1alpha1.DocumentSource{
+						{Template: "Dear {{ .Spec.To.Name }},\n\nPlease find your invoice enclosed."},
+						{ConfigMapRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+							Key:                  "invoice.pdf",
+						}},
+						{SecretRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+							Key:                  "statement.pdf",
+						}},
+						{URL: server.URL + "/terms.pdf"},
+					},
+					DuplexAlignment: true,
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "11111",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "22222",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			var uploaded []byte
+			controller := &MailReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, hook: func() {
+					b, err := os.ReadFile(orderinput.DocumentPath(resource))
+					if err == nil {
+						uploaded = b
+					}
+				}},
+				SyncInterval: 1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-merged"))
+			Expect(orderinput.DocumentPath(fetched)).NotTo(BeAnExistingFile())
+
+			// The one page letter is padded so the invoice starts on a new sheet
+			Expect(fetched.Status.Document.Pages).To(Equal(8))
+			Expect(fetched.Status.Document.Parts).To(Equal([]mailformv1alpha1.DocumentPartStatus{
+				{Pages: 1, BlankPages: 1},
+				{Pages: 2},
+				{Pages: 2},
+				{Pages: 2},
+			}))
+
+			info, err := document.Inspect(uploaded)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.SHA256).To(Equal(fetched.Status.Document.SHA256))
+			Expect(string(uploaded)).To(ContainSubstring("(Dear to-name,) Tj"))
+		})
+
+		It("should add a cover page in front of the document", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-cover"
+			order.Data.State = mailform.StatusQueued
+
+			pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(pdf)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     server.URL + "/letter.pdf",
+					CoverPage: &mailformv1alpha1.CoverPage{
+						QRCode: true,
+					},
+					DuplexAlignment: true,
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "11111",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "US",
+						Postcode: "22222",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			var uploaded []byte
+			controller := &MailReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, hook: func() {
+					b, err := os.ReadFile(orderinput.DocumentPath(resource))
+					if err == nil {
+						uploaded = b
+					}
+				}},
+				SyncInterval: 1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-cover"))
+
+			// The cover page is padded so the letter starts on a new sheet
+			Expect(fetched.Status.Document.Parts).To(Equal([]mailformv1alpha1.DocumentPartStatus{
+				{Pages: 1, BlankPages: 1},
+				{Pages: 1},
+			}))
+			Expect(string(uploaded)).To(ContainSubstring("(to-name) Tj"))
+			Expect(string(uploaded)).To(ContainSubstring("(City CA 11111) Tj"))
+		})
+
+		It("should render the body and upload it", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-body"
+			order.Data.State = mailform.StatusQueued
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					Body: &mailformv1alpha1.Body{
+						Content: "Dear **to-name**,\n\nThank you for your order.",
+						Letterhead: &mailformv1alpha1.Letterhead{
+							Lines: []string{"from-name", "321 Other St"},
+						},
+					},
+					To: &mailformv1alpha1.Address{
+						Name:     "to-name",
+						Address1: "123 Main St",
+						City:     "City",
+						Country:  "DE",
+						Postcode: "11111",
+						State:    "BE",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from-name",
+						Address1: "321 Other St",
+						City:     "City",
+						Country:  "DE",
+						Postcode: "22222",
+						State:    "BE",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			var uploaded []byte
+			var filePath string
+			controller := &MailReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, hook: func() {
+					filePath = orderinput.DocumentPath(resource)
+					b, err := os.ReadFile(filePath)
+					if err == nil {
+						uploaded = b
+					}
+				}},
+				SyncInterval: 1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-body"))
+			Expect(fetched.Status.Document.Pages).To(Equal(1))
+			Expect(orderinput.Build(fetched).FilePath).To(Equal(filePath))
+
+			// Mail outside of the US is printed on A4
+			Expect(string(uploaded)).To(ContainSubstring("/MediaBox [0 0 595.28 841.89]"))
+			Expect(string(uploaded)).To(ContainSubstring("(from-name) Tj"))
+			Expect(string(uploaded)).To(ContainSubstring("( to-name) Tj"))
+			Expect(string(uploaded)).To(ContainSubstring("(Thank you for your order.) Tj"))
+		})
+
+		It("should update sent status when external order is fulfilled", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-fulfilled"
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 20
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-fulfilled"
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   2 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.Sent).To(BeTrue())
+			Expect(fetched.Status.State).To(Equal(mailform.StatusFulfilled))
+		})
+
+		It("should leave paused mail alone until it is resumed", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-fulfilled"
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 20
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        resourceName,
+					Namespace:   namespaceName,
+					Annotations: map[string]string{pausedAnnotation: "true"},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-fulfilled"
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   2 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.Sent).To(BeFalse())
+			Expect(fetched.GetFinalizers()).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typePausedMail)).To(BeTrue())
+
+			fetched.Annotations[pausedAnnotation] = "false"
+			Expect(k8sClient.Update(ctx, fetched)).To(Succeed())
+
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.Sent).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(fetched.Status.Conditions, typePausedMail)).To(BeTrue())
+		})
+
+		It("should refresh finished mail when a resync is requested", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-fulfilled"
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 20
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			// Stored as cancelled but Mailform reports it was delivered
+			resource.Status.ID = "order-fulfilled"
+			resource.Status.State = mailform.StatusCancelled
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   2 * time.Second,
+			}
+
+			// Finished mail isn't checked again without a resync request
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.State).To(Equal(mailform.StatusCancelled))
+
+			requestedAt := time.Now().Format(time.RFC3339)
+			fetched.Annotations[resyncRequestedAnnotation] = requestedAt
+			Expect(k8sClient.Update(ctx, fetched)).To(Succeed())
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.State).To(Equal(mailform.StatusFulfilled))
+			Expect(fetched.Status.Sent).To(BeTrue())
+			Expect(fetched.Status.ObservedResyncRequest).To(Equal(requestedAt))
+			Expect(resyncRequested(fetched)).To(BeFalse())
+		})
+
+		It("should only admit annotation updates that pause or resync mail", func() {
+			oldMail := &mailformv1alpha1.Mail{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"example.com/note": "a"},
+			}}
+
+			changed := func(annotations map[string]string) bool {
+				newMail := oldMail.DeepCopy()
+				newMail.Annotations = annotations
+				return controlAnnotationsChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldMail, ObjectNew: newMail})
+			}
+
+			Expect(changed(map[string]string{"example.com/note": "b"})).To(BeFalse())
+			Expect(changed(map[string]string{pausedAnnotation: "true"})).To(BeTrue())
+			Expect(changed(map[string]string{resyncRequestedAnnotation: "2025-01-01T00:00:00Z"})).To(BeTrue())
+		})
+
+		It("should archive mail once the external order is fulfilled", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-archived"
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 20
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-archived"
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			archiver := &mockArchiver{location: "file:///archive/default/test-resource/"}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   2 * time.Second,
+				Archiver:       archiver,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ArchiveLocation).To(Equal("file:///archive/default/test-resource/"))
+			Expect(archiver.archived).To(HaveLen(1))
+			Expect(archiver.archived[0].Status.Sent).To(BeTrue())
+
+			// Reconciling again should not archive a second time
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archiver.archived).To(HaveLen(1))
+		})
+
+		It("should archive the verified document when the order is created", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-document"
+			order.Data.State = mailform.StatusQueued
+
+			pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(pdf)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     server.URL + "/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			preflight, err := document.NewPreflight(&document.PreflightConfig{})
+			Expect(err).NotTo(HaveOccurred())
+
+			input := &mailform.OrderInput{}
+			archiver := &mockArchiver{location: "file:///archive/default/test-resource/"}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, input: input},
+				SyncInterval:   2 * time.Second,
+				Preflight:      preflight,
+				Archiver:       archiver,
+			}
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Mailform still fetches the URL itself but the archive keeps what was verified
+			Expect(input.URL).To(Equal(server.URL + "/sample.pdf"))
+			Expect(input.FilePath).To(BeEmpty())
+			Expect(archiver.documents).To(Equal([][]byte{[]byte(pdf)}))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-document"))
+			Expect(fetched.Status.Document.ArchiveLocation).To(Equal("file:///archive/default/test-resource/document.pdf"))
+		})
+
+		DescribeTable("should archive the document built for the order when it's created",
+			func(setSource func(spec *mailformv1alpha1.MailSpec, documentURL string), parts int) {
+				ctx := context.Background()
+				key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+				order := &mailform.Order{Success: true}
+				order.Data.ID = "order-built"
+				order.Data.State = mailform.StatusQueued
+
+				pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+					"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+					"3 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >> endobj\n" +
+					"trailer << /Root 1 0 R >>\n%%EOF\n"
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(pdf)) // nolint:errcheck
+				}))
+				defer server.Close()
+
+				resource := &mailformv1alpha1.Mail{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: namespaceName,
+					},
+					Spec: mailformv1alpha1.MailSpec{
+						Service: "USPS_PRIORITY",
+						To: &mailformv1alpha1.Address{
+							Name:     "to",
+							Address1: "a",
+							City:     "b",
+							Country:  "US",
+							Postcode: "12345",
+							State:    "CA",
+						},
+						From: &mailformv1alpha1.Address{
+							Name:     "from",
+							Address1: "a",
+							City:     "b",
+							Country:  "US",
+							Postcode: "54321",
+							State:    "CA",
+						},
+					},
+				}
+				setSource(&resource.Spec, server.URL+"/sample.pdf")
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+				// Record what's uploaded
+				var uploaded []byte
+				archiver := &mockArchiver{location: "file:///archive/default/test-resource/"}
+				controller := &MailReconciler{
+					Client: k8sClient,
+					Scheme: k8sClient.Scheme(),
+					MailformClient: mockMailformClient{output: order, hook: func() {
+						uploaded, _ = os.ReadFile(orderinput.DocumentPath(resource))
+					}},
+					SyncInterval: 2 * time.Second,
+					Archiver:     archiver,
+				}
+
+				_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uploaded).NotTo(BeEmpty())
+				Expect(archiver.documents).To(Equal([][]byte{uploaded}))
+
+				fetched := &mailformv1alpha1.Mail{}
+				Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+				Expect(fetched.Status.Document.Parts).To(HaveLen(parts))
+				Expect(fetched.Status.Document.ArchiveLocation).To(Equal("file:///archive/default/test-resource/document.pdf"))
+			},
+			Entry("merged documents", func(spec *mailformv1alpha1.MailSpec, documentURL string) {
+				spec.Documents = []mailformv1alpha1.DocumentSource{{URL: documentURL}, {URL: documentURL}}
+			}, 2),
+			Entry("cover page", func(spec *mailformv1alpha1.MailSpec, documentURL string) {
+				spec.URL = documentURL
+				spec.CoverPage = &mailformv1alpha1.CoverPage{}
+			}, 2),
+			Entry("body", func(spec *mailformv1alpha1.MailSpec, _ string) {
+				spec.Body = &mailformv1alpha1.Body{Content: "Dear **to**,\n\nThank you."}
+			}, 1),
+		)
+
+		It("should return an error if mailform API fails", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			mailformClient := mockMailformClient{mockErr: errors.NewBadRequest("mailform error")}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mailformClient,
+				SyncInterval:   1 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mailform error"))
+		})
+
+		It("should requeue without an error while mailform is unavailable", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			mailformClient := mockMailformClient{mockErr: &provider.CircuitOpenError{RetryAfter: 30 * time.Second}}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mailformClient,
+				SyncInterval:   1 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeProviderUnavailableMail)).To(BeTrue())
+		})
+
+		It("should not create orders while the kill switch is halted", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-kill-switch"
+			order.Data.State = mailform.StatusQueued
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			killSwitch := &mockKillSwitch{halted: true, reason: "bad template"}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+				KillSwitch:     killSwitch,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeHaltedMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("bad template"))
+
+			// The halted mail is requeued when the kill switch changes
+			requests := controller.mailWithoutOrders(ctx, &corev1.ConfigMap{})
+			Expect(requests).To(ContainElement(reconcile.Request{NamespacedName: key}))
+
+			killSwitch.halted = false
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-kill-switch"))
+			Expect(meta.IsStatusConditionFalse(fetched.Status.Conditions, typeHaltedMail)).To(BeTrue())
+		})
+
+		It("should verify the document before creating an order", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-preflight"
+			order.Data.State = mailform.StatusQueued
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+
+			// The server returns an error page until the document is uploaded
+			pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			body := "<html><body>Not found</body></html>"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(body)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     server.URL + "/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			preflight, err := document.NewPreflight(&document.PreflightConfig{})
+			Expect(err).NotTo(HaveOccurred())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+				Preflight:      preflight,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			Expect(fetched.Status.Valid).To(BeFalse())
+			Expect(fetched.Status.Document).To(BeNil())
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeDocumentVerifiedMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("not a PDF"))
+
+			// The document is checked again once it's fixed
+			body = pdf
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			sum := sha256.Sum256([]byte(pdf))
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-preflight"))
+			Expect(fetched.Status.Valid).To(BeTrue())
+			Expect(fetched.Status.Document).To(Equal(&mailformv1alpha1.DocumentStatus{
+				SHA256: hex.EncodeToString(sum[:]),
+				Pages:  1,
+				Size:   int64(len(pdf)),
+			}))
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeDocumentVerifiedMail)).To(BeTrue())
+		})
+
+		It("should render a preview of the document into a configmap owned by the mail", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-preview"
+			order.Data.State = mailform.StatusQueued
+
+			pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\n" +
+				"4 0 obj << /Type /Page /Parent 2 0 R >> endobj\n" +
+				"5 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(pdf)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     server.URL + "/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			preflight, err := document.NewPreflight(&document.PreflightConfig{})
+			Expect(err).NotTo(HaveOccurred())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+				Preflight:      preflight,
+				PreviewPages:   2,
+			}
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			sum := sha256.Sum256([]byte(pdf))
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-preview"))
+			Expect(fetched.Status.Preview).To(Equal(&mailformv1alpha1.PreviewStatus{
+				ConfigMapName: resourceName + "-preview",
+				Key:           "preview.png",
+				Pages:         2,
+				SHA256:        hex.EncodeToString(sum[:]),
+			}))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      fetched.Status.Preview.ConfigMapName,
+				Namespace: namespaceName,
+			}, configMap)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			}()
+			Expect(configMap.BinaryData["preview.png"]).To(HavePrefix("\x89PNG"))
+			Expect(metav1.IsControlledBy(configMap, fetched)).To(BeTrue())
+		})
+
+		It("should upload the pinned document instead of the url", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-pinned"
+			order.Data.State = mailform.StatusQueued
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+
+			reviewed := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+				"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+				"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n"
+			sum := sha256.Sum256([]byte(reviewed))
+
+			// The document changed after it was reviewed
+			body := strings.Replace(reviewed, "%%EOF", "% changed\n%%EOF", 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(body)) // nolint:errcheck
+			}))
+			defer server.Close()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:        "USPS_PRIORITY",
+					URL:            server.URL + "/sample.pdf",
+					DocumentSHA256: hex.EncodeToString(sum[:]),
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			// Record what's uploaded
+			var uploaded []byte
+			controller := &MailReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order, hook: func() {
+					b, err := os.ReadFile(orderinput.DocumentPath(resource))
+					if err == nil {
+						uploaded = b
+					}
+					// Changes after the document was verified aren't uploaded
+					body = "changed again"
+				}},
+				SyncInterval: 1 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			Expect(fetched.Status.Valid).To(BeFalse())
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeDocumentVerifiedMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("doesn't match its SHA-256"))
+
+			body = reviewed
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-pinned"))
+			Expect(string(uploaded)).To(Equal(reviewed))
+			Expect(orderinput.DocumentPath(fetched)).NotTo(BeAnExistingFile())
+			Expect(fetched.Status.Document.SHA256).To(Equal(hex.EncodeToString(sum[:])))
+			Expect(fetched.Status.Document.Pinned).To(BeTrue())
+		})
+
+		It("should reconcile multiple times with a short sync interval", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			// Mock order that starts in a pending state
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-requeue"
+			order.Data.State = mailform.StatusAwaitingFulfillment
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 10
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			mockClient := &mockMailformClient{output: order}
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockClient,
+				SyncInterval:   200 * time.Millisecond, // short interval for testing
+			}
+
+			// First reconcile: should create the order and requeue
+			firstResult, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(firstResult.RequeueAfter).To(Equal(200 * time.Millisecond))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-requeue"))
+			Expect(fetched.Status.Sent).To(BeFalse())
+
+			// Simulate order fulfillment before the next reconcile
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Modified = time.Now().Add(100 * time.Millisecond)
+
+			// Wait for requeue interval
+			time.Sleep(250 * time.Millisecond)
+
+			// Second reconcile: should mark the mail as Sent and stop checking it without a ttl
+			secondResult, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secondResult.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed()) // did it work
+			Expect(fetched.Status.Sent).To(BeTrue())
+			Expect(fetched.Status.State).To(Equal(mailform.StatusFulfilled))
+		})
+
+		It("should requeue finished mail until its ttl expires", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			ttl := int32(3600)
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:                 "USPS_PRIORITY",
+					URL:                     "https://pdfobject.com/pdf/sample.pdf",
+					TTLSecondsAfterFinished: &ttl,
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-ttl"
+			resource.Status.Sent = true
+			resource.Status.State = mailform.StatusFulfilled
+			resource.Status.Modified = metav1.Now()
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{},
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Hour))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.DeletionTimestamp.IsZero()).To(BeTrue())
+		})
+
+		It("should requeue mail for its ttl when its order finishes", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-ttl"
+			order.Data.State = mailform.StatusFulfilled
+			order.Data.Created = time.Now().Add(-time.Hour)
+			order.Data.Modified = time.Now()
+
+			ttl := int32(60)
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:                 "USPS_PRIORITY",
+					URL:                     "https://pdfobject.com/pdf/sample.pdf",
+					TTLSecondsAfterFinished: &ttl,
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-ttl"
+			resource.Status.Valid = true
+			resource.Status.State = mailform.StatusQueued
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   12 * time.Hour,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.Sent).To(BeTrue())
+			Expect(fetched.DeletionTimestamp.IsZero()).To(BeTrue())
+		})
+
+		It("should delete finished mail once the default ttl expires", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-ttl"
+			resource.Status.State = mailform.StatusCancelled
+			resource.Status.Cancelled = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			// Any call to mailform would fail, finished orders should never be fetched or cancelled
+			controller := &MailReconciler{
+				Client:           k8sClient,
+				Scheme:           k8sClient.Scheme(),
+				MailformClient:   mockMailformClient{mockErr: errors.NewBadRequest("mailform error")},
+				TTLAfterFinished: time.Hour,
+			}
+
+			// First reconcile: ttl expired, should delete
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			// Second reconcile: finalizer should be removed without contacting mailform
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			err = k8sClient.Get(ctx, key, fetched)
+			Expect(errors.IsNotFound(err)).To(BeTrue(), "Mail resource should be deleted")
+		})
+
+		It("should remove finalizer when skip-cancellation annotation is set", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+					Annotations: map[string]string{
+						skipCancellationOnDeleteAnnotation: "true",
+					},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			fetched := &mailformv1alpha1.Mail{}
+			err = k8sClient.Get(ctx, key, fetched)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should cancel the order and remove finalizer if mail not sent and not cancelled", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+				Status: mailformv1alpha1.MailStatus{
+					ID:    "order-123",
+					Sent:  false,
+					State: mailform.StatusAwaitingFulfillment,
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{},
+			}
+
+			// Reconcile deletion
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			// Ensure finalizer was removed
+			fetched := &mailformv1alpha1.Mail{}
+			err = k8sClient.Get(ctx, key, fetched)
+			Expect(errors.IsNotFound(err)).To(BeTrue(), "Mail resource should be deleted")
+		})
+
+		It("should wait for deleted mail to be archived for a limited time", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-123"
+			resource.Status.State = mailform.StatusFulfilled
+			resource.Status.Sent = true
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			order := &mailform.Order{Success: true}
+			order.Data.ID = "order-123"
+			order.Data.State = mailform.StatusFulfilled
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				Archiver:       &mockArchiver{mockErr: fmt.Errorf("bucket unavailable")},
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).To(MatchError(ContainSubstring("bucket unavailable")))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.GetFinalizers()).To(ContainElement(mailSentOrCancelledFinalizerName))
+			archived := meta.FindStatusCondition(fetched.Status.Conditions, typeArchivedMail)
+			Expect(archived).NotTo(BeNil())
+			Expect(archived.Status).To(Equal(metav1.ConditionFalse))
+			Expect(archived.Reason).To(Equal("ArchiveFailed"))
+			Expect(archived.Message).To(ContainSubstring("bucket unavailable"))
+
+			// Once the deadline has passed the mail is deleted without an archive
+			deletedAt := metav1.NewTime(time.Now().Add(-archiveOnDeleteTimeout))
+			fetched.DeletionTimestamp = &deletedAt
+			giveUp, err := controller.handleArchiveFailed(ctx, fetched, fmt.Errorf("bucket unavailable"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(giveUp).To(BeTrue())
+		})
+
+		It("should leave the order alone when the default deletion policy orphans it", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-orphan"
+			resource.Status.State = mailform.StatusQueued
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			runtimeConfig, err := runtimeconfig.New(&runtimeconfig.Config{
+				Defaults: runtimeconfig.Settings{
+					SyncInterval:   time.Hour,
+					Burst:          1,
+					DeletionPolicy: mailformv1alpha1.DeletionPolicyOrphan,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{mockErr: fmt.Errorf("the order should not be cancelled")},
+				RuntimeConfig:  runtimeConfig,
+			}
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			err = k8sClient.Get(ctx, key, fetched)
+			Expect(errors.IsNotFound(err)).To(BeTrue(), "Mail resource should be deleted")
+		})
+
+		It("should remove finalizer when the mail is modified while the order is cancelled", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+				Status: mailformv1alpha1.MailStatus{
+					ID:    "order-123",
 					Sent:  false,
 					State: mailform.StatusAwaitingFulfillment,
 				},
 			}
 
 			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			// Another writer modifies the mail after the controller loaded it
+			modifyMail := func() {
+				latest := &mailformv1alpha1.Mail{}
+				Expect(k8sClient.Get(ctx, key, latest)).To(Succeed())
+				if latest.Annotations == nil {
+					latest.Annotations = map[string]string{}
+				}
+				latest.Annotations["example.com/cancelling"] = time.Now().String()
+				Expect(k8sClient.Update(ctx, latest)).To(Succeed())
+			}
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{hook: modifyMail},
+			}
+
+			// Reconcile deletion
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			// Ensure finalizer was removed
+			fetched := &mailformv1alpha1.Mail{}
+			err = k8sClient.Get(ctx, key, fetched)
+			Expect(errors.IsNotFound(err)).To(BeTrue(), "Mail resource should be deleted")
+		})
+
+		It("should give up on mail whose order can't be found", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-gone"
+			resource.Status.Valid = true
+			resource.Status.State = mailform.StatusQueued
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			archiver := &mockArchiver{location: "file:///archive/default/test-resource"}
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{mockErr: provider.ErrOrderNotFound},
+				Archiver:       archiver,
+				SyncInterval:   2 * time.Second,
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeOrderLostMail)).To(BeTrue())
+			Expect(fetched.Status.ID).To(Equal("order-gone"))
+
+			// Lost mail is terminal and archived like finished mail
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+			Expect(archiver.archived).To(HaveLen(1))
+
+			// A resync checks the order once more and then settles
+			requests := 0
+			controller.MailformClient = mockMailformClient{
+				mockErr: provider.ErrOrderNotFound,
+				hook:    func() { requests++ },
+			}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			requestedAt := time.Now().Format(time.RFC3339)
+			fetched.Annotations[resyncRequestedAnnotation] = requestedAt
+			Expect(k8sClient.Update(ctx, fetched)).To(Succeed())
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(Equal(1))
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ObservedResyncRequest).To(Equal(requestedAt))
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeOrderLostMail)).To(BeTrue())
+
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+			Expect(requests).To(Equal(1))
+		})
+
+		It("should only give up on orders that Mailform rejects when they're created", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			rejected := &mailform.ErrMailform{}
+			rejected.Err.Code = "422"
+			rejected.Err.Message = "Invalid postal code"
 
 			controller := &MailReconciler{
 				Client:         k8sClient,
 				Scheme:         k8sClient.Scheme(),
-				MailformClient: mockMailformClient{},
+				MailformClient: mockMailformClient{mockErr: rejected},
+				SyncInterval:   2 * time.Second,
 			}
 
-			// Reconcile deletion
+			// A rejected order isn't retried until the spec changes
 			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-			// Ensure finalizer was removed
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			Expect(fetched.Status.Valid).To(BeFalse())
+
+			// An existing order that fails to be fetched with a validation error is retried with backoff
+			fetched.Status.ID = "order-existing"
+			fetched.Status.Valid = true
+			fetched.Status.State = mailform.StatusQueued
+			Expect(k8sClient.Status().Update(ctx, fetched)).To(Succeed())
+
+			invalid := &mailform.ErrMailform{}
+			invalid.Err.Message = "Invalid request"
+			controller.MailformClient = mockMailformClient{mockErr: invalid}
+
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).To(MatchError("Invalid request"))
+
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.Valid).To(BeTrue())
+			Expect(fetched.Status.ID).To(Equal("order-existing"))
+		})
+
+		It("should recreate mail whose order can't be found if the policy allows it", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:         "USPS_PRIORITY",
+					URL:             "https://pdfobject.com/pdf/sample.pdf",
+					OrderLostPolicy: mailformv1alpha1.OrderLostPolicyRecreate,
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			resource.Status.ID = "order-gone"
+			resource.Status.Valid = true
+			resource.Status.State = mailform.StatusQueued
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{mockErr: provider.ErrOrderNotFound},
+				SyncInterval:   2 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(orderLostRequeueAfter))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+			Expect(fetched.Status.State).To(BeEmpty())
+			Expect(fetched.Annotations).NotTo(HaveKey(orderIDAnnotation))
+
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeOrderLostMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Recreated"))
+			Expect(condition.Message).To(ContainSubstring("order-gone"))
+		})
+
+		It("should remove finalizer if the order to cancel can't be found", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  namespaceName,
+					Finalizers: []string{mailSentOrCancelledFinalizerName},
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service: "USPS_PRIORITY",
+					URL:     "https://pdfobject.com/pdf/sample.pdf",
+					To: &mailformv1alpha1.Address{
+						Name:     "to",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "12345",
+						State:    "CA",
+					},
+					From: &mailformv1alpha1.Address{
+						Name:     "from",
+						Address1: "a",
+						City:     "b",
+						Country:  "US",
+						Postcode: "54321",
+						State:    "CA",
+					},
+				},
+				Status: mailformv1alpha1.MailStatus{
+					ID:    "order-gone",
+					State: mailform.StatusAwaitingFulfillment,
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{mockErr: provider.ErrOrderNotFound},
+			}
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
 			fetched := &mailformv1alpha1.Mail{}
 			err = k8sClient.Get(ctx, key, fetched)
 			Expect(errors.IsNotFound(err)).To(BeTrue(), "Mail resource should be deleted")
 		})
 
+		It("should adopt an existing order without to/from addresses", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			order := &mailform.Order{
+				Success: true,
+			}
+			order.Data.ID = "order-adopted"
+			order.Data.State = mailform.StatusQueued
+			order.Data.Created = time.Now()
+			order.Data.Modified = time.Now()
+			order.Data.Total = 321
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:      "USPS_PRIORITY",
+					AdoptOrderID: "order-adopted",
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{output: order},
+				SyncInterval:   1 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(Equal("order-adopted"))
+			Expect(fetched.Status.Valid).To(BeTrue())
+			Expect(fetched.Status.State).To(Equal(mailform.StatusQueued))
+			Expect(fetched.Status.Total).To(Equal(321))
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeAdoptedMail)).To(BeTrue())
+
+			By("Rejecting changes to the adopted order ID")
+			fetched.Spec.AdoptOrderID = "order-other"
+			Expect(k8sClient.Update(ctx, fetched)).To(MatchError(ContainSubstring("adoptOrderID is immutable")))
+		})
+
+		It("should not adopt an order owned by another mail", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: resourceName, Namespace: namespaceName}
+
+			owner := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-owner",
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:      "USPS_PRIORITY",
+					AdoptOrderID: "order-owned",
+				},
+			}
+			Expect(k8sClient.Create(ctx, owner)).To(Succeed())
+			owner.Status.ID = "order-owned"
+			Expect(k8sClient.Status().Update(ctx, owner)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, owner)).To(Succeed())
+			}()
+
+			resource := &mailformv1alpha1.Mail{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: mailformv1alpha1.MailSpec{
+					Service:      "USPS_PRIORITY",
+					AdoptOrderID: "order-owned",
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controller := &MailReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				MailformClient: mockMailformClient{mockErr: fmt.Errorf("should not be called")},
+				SyncInterval:   1 * time.Second,
+			}
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Second))
+
+			fetched := &mailformv1alpha1.Mail{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(fetched.Status.ID).To(BeEmpty())
+
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeAdoptedMail)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("AlreadyOwned"))
+		})
+
 	})
 })
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/runtimeconfig"
+)
+
+// typeAppliedConfig represents whether the PostK8sConfig is the configuration the operator is running with
+const typeAppliedConfig = "Applied"
+
+// PostK8sConfigReconciler applies a PostK8sConfig to the operator's runtime settings.
+type PostK8sConfigReconciler struct {
+	client.Client
+	Scheme        *runtime.Scheme
+	RuntimeConfig *runtimeconfig.Store
+	// Name is the name of the PostK8sConfig that is applied. Defaults to runtimeconfig.DefaultName.
+	Name string
+}
+
+// +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=postk8sconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=mailform.circa10a.github.io,resources=postk8sconfigs/status,verbs=get;update;patch
+
+// Reconcile applies the PostK8sConfig and records the active configuration in its status.
+// Deleting the PostK8sConfig goes back to the configuration from the manager's flags.
+func (r *PostK8sConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	config := &mailformv1alpha1.PostK8sConfig{}
+	err := r.Get(ctx, req.NamespacedName, config)
+	if apierrors.IsNotFound(err) {
+		if req.Name == r.name() {
+			r.RuntimeConfig.Reset()
+			log.Info("runtime config deleted, using defaults", "name", req.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	base := config.DeepCopy()
+	condition := metav1.Condition{
+		Type:    typeAppliedConfig,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: "Configuration applied",
+	}
+
+	if config.Name == r.name() {
+		settings, err := r.RuntimeConfig.Apply(&config.Spec)
+		if err != nil {
+			log.Error(err, "invalid runtime config, keeping the previous configuration", "name", config.Name)
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "Invalid"
+			condition.Message = err.Error()
+		} else {
+			log.Info("applied runtime config", "name", config.Name)
+		}
+		config.Status.Active = runtimeconfig.ToSpec(settings)
+	} else {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Ignored"
+		condition.Message = fmt.Sprintf("Only the PostK8sConfig named %q is applied", r.name())
+		config.Status.Active = nil
+	}
+
+	config.Status.ObservedGeneration = config.Generation
+	condition.ObservedGeneration = config.Generation
+	meta.SetStatusCondition(&config.Status.Conditions, condition)
+
+	if equality.Semantic.DeepEqual(base.Status, config.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{}, r.Status().Patch(ctx, config, client.MergeFrom(base), client.FieldOwner(fieldManager))
+}
+
+// name returns the name of the PostK8sConfig that is applied.
+func (r *PostK8sConfigReconciler) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return runtimeconfig.DefaultName
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PostK8sConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&mailformv1alpha1.PostK8sConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("postk8sconfig").
+		Complete(r)
+}
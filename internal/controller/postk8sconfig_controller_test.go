@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/runtimeconfig"
+)
+
+var _ = Describe("PostK8sConfig Controller", func() {
+	Context("When reconciling a resource", func() {
+		var store *runtimeconfig.Store
+		var controller *PostK8sConfigReconciler
+
+		BeforeEach(func() {
+			var err error
+			store, err = runtimeconfig.New(&runtimeconfig.Config{
+				Defaults: runtimeconfig.Settings{
+					SyncInterval: 12 * time.Hour,
+					Burst:        10,
+					Archive:      true,
+					KillSwitch:   true,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			controller = &PostK8sConfigReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				RuntimeConfig: store,
+			}
+		})
+
+		AfterEach(func() {
+			ctx := context.Background()
+
+			configs := &mailformv1alpha1.PostK8sConfigList{}
+			Expect(k8sClient.List(ctx, configs)).To(Succeed())
+			for i := range configs.Items {
+				Expect(k8sClient.Delete(ctx, &configs.Items[i])).To(Succeed())
+			}
+		})
+
+		It("should apply the config and show the active configuration", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: runtimeconfig.DefaultName}
+
+			config := &mailformv1alpha1.PostK8sConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: runtimeconfig.DefaultName},
+				Spec: mailformv1alpha1.PostK8sConfigSpec{
+					Polling: &mailformv1alpha1.PollingConfig{
+						SyncIntervalSeconds: ptr.To(int32(3600)),
+					},
+					Features: &mailformv1alpha1.FeatureConfig{
+						KillSwitch: ptr.To(false),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, config)).To(Succeed())
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Current().SyncInterval).To(Equal(time.Hour))
+			Expect(store.Current().KillSwitch).To(BeFalse())
+
+			fetched := &mailformv1alpha1.PostK8sConfig{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, typeAppliedConfig)).To(BeTrue())
+			Expect(fetched.Status.ObservedGeneration).To(Equal(fetched.Generation))
+			Expect(fetched.Status.Active).NotTo(BeNil())
+			Expect(*fetched.Status.Active.Polling.SyncIntervalSeconds).To(Equal(int32(3600)))
+			Expect(*fetched.Status.Active.RateLimit.Burst).To(Equal(int32(10)))
+
+			// Deleting the config goes back to the defaults
+			Expect(k8sClient.Delete(ctx, fetched)).To(Succeed())
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Current().SyncInterval).To(Equal(12 * time.Hour))
+			Expect(store.Current().KillSwitch).To(BeTrue())
+		})
+
+		It("should keep the previous configuration if the config is invalid", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: runtimeconfig.DefaultName}
+
+			config := &mailformv1alpha1.PostK8sConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: runtimeconfig.DefaultName},
+				Spec: mailformv1alpha1.PostK8sConfigSpec{
+					Polling: &mailformv1alpha1.PollingConfig{
+						SyncIntervalSeconds:    ptr.To(int32(60)),
+						MinSyncIntervalSeconds: ptr.To(int32(600)),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, config)).To(Succeed())
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Current().SyncInterval).To(Equal(12 * time.Hour))
+
+			fetched := &mailformv1alpha1.PostK8sConfig{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeAppliedConfig)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Invalid"))
+			Expect(*fetched.Status.Active.Polling.SyncIntervalSeconds).To(Equal(int32(43200)))
+		})
+
+		It("should ignore configs with other names", func() {
+			ctx := context.Background()
+			key := types.NamespacedName{Name: "other"}
+
+			config := &mailformv1alpha1.PostK8sConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+				Spec: mailformv1alpha1.PostK8sConfigSpec{
+					Features: &mailformv1alpha1.FeatureConfig{
+						Archive: ptr.To(false),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, config)).To(Succeed())
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Current().Archive).To(BeTrue())
+
+			fetched := &mailformv1alpha1.PostK8sConfig{}
+			Expect(k8sClient.Get(ctx, key, fetched)).To(Succeed())
+			condition := meta.FindStatusCondition(fetched.Status.Conditions, typeAppliedConfig)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Ignored"))
+			Expect(fetched.Status.Active).To(BeNil())
+
+			Expect(k8sClient.Delete(ctx, fetched)).To(Succeed())
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, key, fetched))).To(BeTrue())
+		})
+	})
+})
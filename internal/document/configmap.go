@@ -0,0 +1,31 @@
+package document
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadConfigMap returns the document stored under the selected key of a ConfigMap.
+// binaryData is preferred over data since PDFs are binary.
+func ReadConfigMap(ctx context.Context, reader client.Reader, namespace string, selector *corev1.ConfigMapKeySelector) ([]byte, error) {
+	configMap := &corev1.ConfigMap{}
+
+	err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: selector.Name}, configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := configMap.BinaryData[selector.Key]; ok {
+		return b, nil
+	}
+
+	if s, ok := configMap.Data[selector.Key]; ok {
+		return []byte(s), nil
+	}
+
+	return nil, fmt.Errorf("key %q not found in configmap %s/%s", selector.Key, namespace, selector.Name)
+}
@@ -0,0 +1,192 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// pointsPerInch and pointsPerMM convert measurements to PDF points.
+	pointsPerInch = 72
+	pointsPerMM   = 72 / 25.4
+
+	// a4Width and a4Height are the size of an A4 page in points.
+	a4Width  = 595.28
+	a4Height = 841.89
+
+	// qrCodeSize is the width of the QR code including its quiet zone.
+	qrCodeSize = 1 * pointsPerInch
+)
+
+// Envelope is a window envelope that a cover page is laid out for.
+type Envelope string
+
+const (
+	// EnvelopeUS10 is a US #10 double window envelope with a tri-folded US Letter page.
+	EnvelopeUS10 Envelope = "US10"
+	// EnvelopeC5 is a C5 window envelope with a once folded A4 page, laid out as DIN 5008 form B.
+	EnvelopeC5 Envelope = "C5"
+	// EnvelopeDL is a DL window envelope with a tri-folded A4 page, laid out as DIN 5008 form B.
+	EnvelopeDL Envelope = "DL"
+)
+
+// ErrUnknownEnvelope is returned for envelopes without a cover page layout.
+var ErrUnknownEnvelope = fmt.Errorf("%w: unknown envelope", ErrInvalid)
+
+// envelopeLayout is where the addresses go on a cover page so they show through the envelope's windows.
+// Positions are in points from the top left of the page.
+type envelopeLayout struct {
+	width, height float64
+	// fromX and fromTop are where the return address starts.
+	fromX, fromTop float64
+	// fromSize is the font size of the return address.
+	fromSize float64
+	// fromOneLine puts the return address on one line above the recipient, as in DIN 5008.
+	fromOneLine bool
+	// toX and toTop are where the recipient address starts.
+	toX, toTop float64
+	// toWidth is the width of the recipient window. Longer lines are shrunk to fit.
+	toWidth float64
+	toSize  float64
+	// windows are where the envelope's windows are.
+	windows []window
+}
+
+// window is an envelope window in points from the top left of the page.
+type window struct {
+	x, top, width, height float64
+}
+
+// envelopeLayouts are the cover page layouts for each envelope.
+var envelopeLayouts = map[Envelope]envelopeLayout{
+	// The return address shows through the top window and the recipient through the bottom window
+	EnvelopeUS10: {
+		width: letterWidth, height: letterHeight,
+		fromX: 0.625 * pointsPerInch, fromTop: 0.625 * pointsPerInch, fromSize: 9,
+		toX: 0.875 * pointsPerInch, toTop: 2.125 * pointsPerInch, toWidth: 3.75 * pointsPerInch, toSize: 11,
+		windows: []window{
+			{x: 0.5 * pointsPerInch, top: 0.5 * pointsPerInch, width: 3.25 * pointsPerInch, height: 1 * pointsPerInch},
+			{x: 0.75 * pointsPerInch, top: 1.875 * pointsPerInch, width: 4 * pointsPerInch, height: 1.25 * pointsPerInch},
+		},
+	},
+	// The single window holds a return line above the recipient, 20mm from the left and 45mm from the top
+	EnvelopeC5: dinLayout,
+	EnvelopeDL: dinLayout,
+}
+
+// dinLayout is DIN 5008 form B, which both C5 and DL window envelopes are made for.
+var dinLayout = envelopeLayout{
+	width: a4Width, height: a4Height,
+	fromX: 25 * pointsPerMM, fromTop: 58 * pointsPerMM, fromSize: 7, fromOneLine: true,
+	toX: 25 * pointsPerMM, toTop: 67 * pointsPerMM, toWidth: 75 * pointsPerMM, toSize: 10,
+	windows: []window{
+		{x: 20 * pointsPerMM, top: 45 * pointsPerMM, width: 85 * pointsPerMM, height: 45 * pointsPerMM},
+	},
+}
+
+// CoverPage is an address page for window envelopes.
+type CoverPage struct {
+	// Envelope defaults to EnvelopeUS10.
+	Envelope Envelope
+	// From and To are the lines of the return and recipient addresses.
+	From []string
+	To   []string
+	// QRCode is optionally encoded in a QR code on the page to track returned mail.
+	QRCode string
+}
+
+// RenderCoverPage renders a single page with the addresses positioned for the envelope's windows.
+func RenderCoverPage(c *CoverPage) ([]byte, error) {
+	layout, err := findLayout(c.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPage(layout.width, layout.height)
+	drawAddresses(p, layout, c.From, c.To)
+
+	if c.QRCode != "" {
+		qr, err := encodeQR([]byte(c.QRCode))
+		if err != nil {
+			return nil, err
+		}
+		// The top right corner stays inside the envelope for every layout
+		margin := 0.5 * pointsPerInch
+		drawQRCode(p, qr, layout.width-margin-qrCodeSize, layout.height-margin-qrCodeSize, qrCodeSize)
+	}
+
+	return writePages([]*page{p}), nil
+}
+
+// findLayout returns the layout for the envelope, defaulting to EnvelopeUS10.
+func findLayout(envelope Envelope) (envelopeLayout, error) {
+	if envelope == "" {
+		envelope = EnvelopeUS10
+	}
+
+	layout, ok := envelopeLayouts[envelope]
+	if !ok {
+		return envelopeLayout{}, fmt.Errorf("%w %q", ErrUnknownEnvelope, envelope)
+	}
+
+	return layout, nil
+}
+
+// drawAddresses draws the return and recipient addresses where the layout's windows show them.
+func drawAddresses(p *page, layout envelopeLayout, from, to []string) {
+	if layout.fromOneLine {
+		line := strings.Join(from, ", ")
+		p.text(helvetica, fitSize(helvetica, line, layout.fromSize, layout.toWidth), layout.fromX, layout.height-layout.fromTop, line)
+	} else {
+		drawLines(p, from, layout.fromSize, layout.fromX, layout.height-layout.fromTop, 0)
+	}
+	drawLines(p, to, layout.toSize, layout.toX, layout.height-layout.toTop, layout.toWidth)
+}
+
+// drawLines draws lines downwards from the first baseline at x, y.
+// Lines wider than maxWidth are shrunk to fit unless maxWidth is zero.
+func drawLines(p *page, lines []string, size, x, y, maxWidth float64) {
+	for _, line := range lines {
+		lineSize := size
+		if maxWidth > 0 {
+			lineSize = fitSize(helvetica, line, size, maxWidth)
+		}
+		p.text(helvetica, lineSize, x, y, line)
+		y -= size * 1.2
+	}
+}
+
+// fitSize returns the largest font size up to size that fits line in f within maxWidth.
+func fitSize(f *font, line string, size, maxWidth float64) float64 {
+	width := f.width(line, size)
+	if width <= maxWidth {
+		return size
+	}
+
+	return math.Floor(size*maxWidth/width*10) / 10
+}
+
+// drawQRCode draws the QR code with its bottom left corner at x, y, including the quiet zone around it.
+func drawQRCode(p *page, qr *qrCode, x, y, size float64) {
+	// The quiet zone is four modules wide on every side
+	modules := qr.size + 8
+	module := size / float64(modules)
+
+	// Edges are rounded the same way for neighboring modules so there are no gaps between them
+	edge := func(i int) float64 {
+		return math.Round(float64(i)*module*100) / 100
+	}
+
+	for row := range qr.size {
+		for column := range qr.size {
+			if !qr.modules[row][column] {
+				continue
+			}
+			left, right := edge(column+4), edge(column+5)
+			// Rows go down the page from the top of the code
+			top, bottom := edge(modules-row-4), edge(modules-row-5)
+			p.fillRect(x+left, y+bottom, right-left, top-bottom)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package document
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenderCoverPage", func() {
+	from := []string{"Acme Corp", "1 Infinite Loop", "Cupertino CA 95014"}
+	to := []string{"Jane Doe", "123 Main St", "Springfield IL 62701"}
+
+	It("should place the addresses in the windows of a US #10 envelope", func() {
+		rendered, err := RenderCoverPage(&CoverPage{From: from, To: to})
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := Inspect(rendered)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(1))
+
+		// The return address is 0.625in from the top and left, the recipient 2.125in from the top
+		Expect(string(rendered)).To(ContainSubstring("BT /F1 9 Tf 45 747 Td (Acme Corp) Tj ET"))
+		Expect(string(rendered)).To(ContainSubstring("BT /F1 11 Tf 63 639 Td (Jane Doe) Tj ET"))
+		Expect(string(rendered)).To(ContainSubstring("/MediaBox [0 0 612 792]"))
+	})
+
+	It("should lay out international envelopes as DIN 5008 form B on A4", func() {
+		for _, envelope := range []Envelope{EnvelopeC5, EnvelopeDL} {
+			rendered, err := RenderCoverPage(&CoverPage{Envelope: envelope, From: from, To: to})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(rendered)).To(ContainSubstring("/MediaBox [0 0 595.28 841.89]"))
+			// The return address is a single line above the recipient
+			Expect(string(rendered)).To(ContainSubstring("(Acme Corp, 1 Infinite Loop, Cupertino CA 95014) Tj"))
+		}
+	})
+
+	It("should shrink lines that are wider than the window", func() {
+		long := strings.Repeat("Very Long Organization Name ", 3)
+		rendered, err := RenderCoverPage(&CoverPage{From: from, To: []string{long}})
+		Expect(err).NotTo(HaveOccurred())
+		size := fitSize(helvetica, long, 11, 3.75*72)
+		Expect(size).To(BeNumerically("<", 11))
+		Expect(helvetica.width(long, size)).To(BeNumerically("<=", 3.75*72))
+		Expect(string(rendered)).To(ContainSubstring("/F1 " + formatNumber(size) + " Tf 63 639 Td"))
+	})
+
+	It("should add a QR code", func() {
+		without, err := RenderCoverPage(&CoverPage{From: from, To: to})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(without)).NotTo(ContainSubstring(" re f"))
+
+		rendered, err := RenderCoverPage(&CoverPage{From: from, To: to, QRCode: "d9b2d63d-a233-4123-847a-8c2b0c5cba1a"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rendered)).To(ContainSubstring(" re f"))
+
+		_, err = RenderCoverPage(&CoverPage{From: from, To: to, QRCode: strings.Repeat("x", 500)})
+		Expect(err).To(MatchError(ErrQRCodeTooLong))
+	})
+
+	It("should reject unknown envelopes", func() {
+		_, err := RenderCoverPage(&CoverPage{Envelope: "C4", From: from, To: to})
+		Expect(err).To(MatchError(ErrUnknownEnvelope))
+	})
+})
@@ -0,0 +1,14 @@
+package document
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDocument(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Document Suite")
+}
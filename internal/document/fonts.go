@@ -0,0 +1,153 @@
+package document
+
+import "fmt"
+
+// Font is a family of standard fonts that letters can be set in.
+type Font string
+
+const (
+	// FontHelvetica is a sans-serif font.
+	FontHelvetica Font = "Helvetica"
+	// FontTimes is a serif font.
+	FontTimes Font = "Times"
+	// FontCourier is a fixed width font.
+	FontCourier Font = "Courier"
+)
+
+// ErrUnknownFont is returned for fonts that aren't one of the standard font families.
+var ErrUnknownFont = fmt.Errorf("%w: unknown font", ErrInvalid)
+
+// fontFamily is the regular, bold, italic and bold italic fonts of a family.
+type fontFamily struct {
+	regular, bold, italic, boldItalic *font
+}
+
+// font returns the family's font for the style.
+func (f *fontFamily) font(s style) *font {
+	switch {
+	case s&styleBold != 0 && s&styleItalic != 0:
+		return f.boldItalic
+	case s&styleBold != 0:
+		return f.bold
+	case s&styleItalic != 0:
+		return f.italic
+	default:
+		return f.regular
+	}
+}
+
+// fontFamilies are the standard font families. Oblique Helvetica has the same widths as upright Helvetica.
+var fontFamilies = map[Font]*fontFamily{
+	FontHelvetica: {
+		regular:    helvetica,
+		bold:       helveticaBold,
+		italic:     &font{name: "Helvetica-Oblique", widths: helvetica.widths, defaultWidth: helvetica.defaultWidth},
+		boldItalic: &font{name: "Helvetica-BoldOblique", widths: helveticaBold.widths, defaultWidth: helveticaBold.defaultWidth},
+	},
+	FontTimes: {
+		regular:    timesRoman,
+		bold:       timesBold,
+		italic:     timesItalic,
+		boldItalic: timesBoldItalic,
+	},
+	FontCourier: {
+		regular:    courier("Courier"),
+		bold:       courier("Courier-Bold"),
+		italic:     courier("Courier-Oblique"),
+		boldItalic: courier("Courier-BoldOblique"),
+	},
+}
+
+// findFamily returns the font family, which defaults to Helvetica.
+func findFamily(f Font) (*fontFamily, error) {
+	if f == "" {
+		f = FontHelvetica
+	}
+
+	family, ok := fontFamilies[f]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFont, f)
+	}
+
+	return family, nil
+}
+
+// courier returns a Courier font, where every character is the same width.
+func courier(name string) *font {
+	f := &font{name: name, defaultWidth: 600}
+	for i := range f.widths {
+		f.widths[i] = 600
+	}
+
+	return f
+}
+
+// helveticaBold is bold Helvetica.
+var helveticaBold = &font{
+	name: "Helvetica-Bold",
+	widths: [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611, // 0 to ?
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778, // @ to O
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, // P to _
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, // ` to o
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, // p to ~
+	},
+	defaultWidth: 556,
+}
+
+// timesRoman is the regular Times font.
+var timesRoman = &font{
+	name: "Times-Roman",
+	widths: [95]int{
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278, // space to /
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444, // 0 to ?
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722, // @ to O
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500, // P to _
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500, // ` to o
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541, // p to ~
+	},
+	defaultWidth: 500,
+}
+
+// timesBold is bold Times.
+var timesBold = &font{
+	name: "Times-Bold",
+	widths: [95]int{
+		250, 333, 555, 500, 500, 1000, 833, 278, 333, 333, 500, 570, 250, 333, 250, 278, // space to /
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500, // 0 to ?
+		930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778, // @ to O
+		611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500, // P to _
+		333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500, // ` to o
+		556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520, // p to ~
+	},
+	defaultWidth: 500,
+}
+
+// timesItalic is italic Times.
+var timesItalic = &font{
+	name: "Times-Italic",
+	widths: [95]int{
+		250, 333, 420, 500, 500, 833, 778, 214, 333, 333, 500, 675, 250, 333, 250, 278, // space to /
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 675, 675, 675, 500, // 0 to ?
+		920, 611, 611, 667, 722, 611, 611, 722, 722, 333, 444, 667, 556, 833, 667, 722, // @ to O
+		611, 722, 611, 500, 556, 722, 611, 833, 611, 556, 556, 389, 278, 389, 422, 500, // P to _
+		333, 500, 500, 444, 500, 444, 278, 500, 500, 278, 278, 444, 278, 722, 500, 500, // ` to o
+		500, 500, 389, 389, 278, 500, 444, 667, 444, 444, 389, 400, 275, 400, 541, // p to ~
+	},
+	defaultWidth: 500,
+}
+
+// timesBoldItalic is bold italic Times.
+var timesBoldItalic = &font{
+	name: "Times-BoldItalic",
+	widths: [95]int{
+		250, 389, 555, 500, 500, 833, 778, 278, 333, 333, 500, 570, 250, 333, 250, 278, // space to /
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500, // 0 to ?
+		832, 667, 667, 667, 722, 667, 667, 722, 778, 389, 500, 667, 611, 889, 722, 722, // @ to O
+		611, 722, 667, 556, 611, 722, 667, 889, 667, 611, 611, 333, 278, 333, 570, 500, // P to _
+		333, 500, 500, 444, 500, 444, 333, 500, 556, 278, 278, 500, 278, 778, 556, 500, // ` to o
+		500, 500, 389, 389, 278, 556, 444, 667, 500, 444, 389, 348, 220, 348, 570, // p to ~
+	},
+	defaultWidth: 500,
+}
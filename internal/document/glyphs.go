@@ -0,0 +1,372 @@
+package document
+
+import "strings"
+
+const (
+	// glyphColumns and glyphRows are the size of the preview font's glyphs in cells.
+	// Rows 0 to 6 are above the baseline and rows 7 and 8 hold descenders.
+	glyphColumns = 5
+	glyphRows    = 9
+	// glyphBaseline is the first row below the baseline.
+	glyphBaseline = 7
+)
+
+// glyphs is the bitmap font text is drawn with in previews, covering printable ASCII.
+// Each row is a bitmask with the leftmost cell in bit 4.
+var glyphs = [95][glyphRows]uint8{
+	{}, // space
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},             // !
+	{0x0a, 0x0a, 0x0a},                                     // "
+	{0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},             // #
+	{0x04, 0x0f, 0x14, 0x0e, 0x05, 0x1e, 0x04},             // $
+	{0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},             // %
+	{0x0c, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0d},             // &
+	{0x0c, 0x04, 0x08},                                     // '
+	{0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},             // (
+	{0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},             // )
+	{0x00, 0x04, 0x15, 0x0e, 0x15, 0x04, 0x00},             // *
+	{0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00},             // +
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c, 0x04, 0x08}, // ,
+	{0x00, 0x00, 0x00, 0x1f},                               // -
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},             // .
+	{0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},             // /
+	{0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},             // 0
+	{0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},             // 1
+	{0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},             // 2
+	{0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},             // 3
+	{0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},             // 4
+	{0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},             // 5
+	{0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},             // 6
+	{0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},             // 7
+	{0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},             // 8
+	{0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},             // 9
+	{0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},             // :
+	{0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x04, 0x08},       // ;
+	{0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},             // <
+	{0x00, 0x00, 0x1f, 0x00, 0x1f, 0x00, 0x00},             // =
+	{0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},             // >
+	{0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},             // ?
+	{0x0e, 0x11, 0x01, 0x0d, 0x15, 0x15, 0x0e},             // @
+	{0x0e, 0x11, 0x11, 0x11, 0x1f, 0x11, 0x11},             // A
+	{0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},             // B
+	{0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},             // C
+	{0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c},             // D
+	{0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},             // E
+	{0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},             // F
+	{0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},             // G
+	{0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},             // H
+	{0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},             // I
+	{0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},             // J
+	{0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},             // K
+	{0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},             // L
+	{0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},             // M
+	{0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},             // N
+	{0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},             // O
+	{0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},             // P
+	{0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},             // Q
+	{0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},             // R
+	{0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},             // S
+	{0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},             // T
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},             // U
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},             // V
+	{0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},             // W
+	{0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},             // X
+	{0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},             // Y
+	{0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},             // Z
+	{0x0e, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0e},             // [
+	{0x00, 0x10, 0x08, 0x04, 0x02, 0x01, 0x00},             // \
+	{0x0e, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0e},             // ]
+	{0x04, 0x0a, 0x11},                                     // ^
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},             // _
+	{0x08, 0x04, 0x02},                                     // `
+	{0x00, 0x00, 0x0e, 0x01, 0x0f, 0x11, 0x0f},             // a
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x1e},             // b
+	{0x00, 0x00, 0x0e, 0x10, 0x10, 0x11, 0x0e},             // c
+	{0x01, 0x01, 0x0d, 0x13, 0x11, 0x11, 0x0f},             // d
+	{0x00, 0x00, 0x0e, 0x11, 0x1f, 0x10, 0x0e},             // e
+	{0x06, 0x09, 0x08, 0x1c, 0x08, 0x08, 0x08},             // f
+	{0x00, 0x00, 0x0f, 0x11, 0x11, 0x11, 0x0f, 0x01, 0x0e}, // g
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x11},             // h
+	{0x04, 0x00, 0x0c, 0x04, 0x04, 0x04, 0x0e},             // i
+	{0x02, 0x00, 0x06, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c}, // j
+	{0x10, 0x10, 0x12, 0x14, 0x18, 0x14, 0x12},             // k
+	{0x0c, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},             // l
+	{0x00, 0x00, 0x1a, 0x15, 0x15, 0x11, 0x11},             // m
+	{0x00, 0x00, 0x16, 0x19, 0x11, 0x11, 0x11},             // n
+	{0x00, 0x00, 0x0e, 0x11, 0x11, 0x11, 0x0e},             // o
+	{0x00, 0x00, 0x1e, 0x11, 0x11, 0x11, 0x1e, 0x10, 0x10}, // p
+	{0x00, 0x00, 0x0f, 0x11, 0x11, 0x11, 0x0f, 0x01, 0x01}, // q
+	{0x00, 0x00, 0x16, 0x19, 0x10, 0x10, 0x10},             // r
+	{0x00, 0x00, 0x0e, 0x10, 0x0e, 0x01, 0x1e},             // s
+	{0x08, 0x08, 0x1c, 0x08, 0x08, 0x09, 0x06},             // t
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x13, 0x0d},             // u
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x0a, 0x04},             // v
+	{0x00, 0x00, 0x11, 0x11, 0x15, 0x15, 0x0a},             // w
+	{0x00, 0x00, 0x11, 0x0a, 0x04, 0x0a, 0x11},             // x
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x11, 0x0f, 0x01, 0x0e}, // y
+	{0x00, 0x00, 0x1f, 0x02, 0x04, 0x08, 0x1f},             // z
+	{0x02, 0x04, 0x04, 0x08, 0x04, 0x04, 0x02},             // {
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},             // |
+	{0x08, 0x04, 0x04, 0x02, 0x04, 0x04, 0x08},             // }
+	{0x00, 0x00, 0x08, 0x15, 0x02},                         // ~
+}
+
+// unknownGlyph is drawn for characters the preview font doesn't have, filling the x-height.
+var unknownGlyph = [glyphRows]uint8{0x00, 0x00, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f}
+
+// glyph returns the bitmap for a character code in a simple font.
+func glyph(code int) [glyphRows]uint8 {
+	if code >= ' ' && code <= '~' {
+		return glyphs[code-' ']
+	}
+
+	return unknownGlyph
+}
+
+// previewFont is what's needed from a document's font to lay out its text in previews.
+type previewFont struct {
+	// widths are the widths of character codes in thousandths of the font size.
+	widths map[int]float64
+	// defaultWidth is used for codes without a width.
+	defaultWidth float64
+	// twoByte is set for composite fonts, whose character codes are two bytes.
+	twoByte bool
+}
+
+// width returns the width of a character code in thousandths of the font size.
+func (f *previewFont) width(code int) float64 {
+	if width, ok := f.widths[code]; ok {
+		return width
+	}
+
+	return f.defaultWidth
+}
+
+// loadFont returns the font for a resource name. Fonts that can't be found are laid out like Helvetica.
+func (r *renderer) loadFont(name pdfName, resources pdfDict) *previewFont {
+	fonts, _ := r.resolve(resources["Font"]).(pdfDict)
+	dict, _ := r.resolve(fonts[name]).(pdfDict)
+
+	if r.resolve(dict["Subtype"]) == pdfName("Type0") {
+		return r.loadCompositeFont(dict)
+	}
+
+	f := &previewFont{widths: map[int]float64{}, defaultWidth: float64(helvetica.defaultWidth)}
+
+	widths, ok := r.resolve(dict["Widths"]).(pdfArray)
+	if !ok {
+		// The standard fonts don't need widths, so use Helvetica's or Courier's
+		baseFont, _ := r.resolve(dict["BaseFont"]).(pdfName)
+		for i, width := range helvetica.widths {
+			f.widths[' '+i] = float64(width)
+			if strings.Contains(string(baseFont), "Courier") {
+				f.widths[' '+i] = 600
+			}
+		}
+		return f
+	}
+
+	// Type 3 fonts measure widths in their own units
+	scale := 1.0
+	if fontMatrix, ok := r.resolve(dict["FontMatrix"]).(pdfArray); ok && len(fontMatrix) == 6 {
+		scale = number(r.resolve(fontMatrix[0])) * 1000
+	}
+
+	firstChar := int(number(r.resolve(dict["FirstChar"])))
+	for i, width := range widths {
+		f.widths[firstChar+i] = number(r.resolve(width)) * scale
+	}
+
+	return f
+}
+
+// loadCompositeFont returns a font with two byte character codes, with widths from its descendant font.
+func (r *renderer) loadCompositeFont(dict pdfDict) *previewFont {
+	f := &previewFont{widths: map[int]float64{}, defaultWidth: 1000, twoByte: true}
+
+	descendants, _ := r.resolve(dict["DescendantFonts"]).(pdfArray)
+	if len(descendants) == 0 {
+		return f
+	}
+	descendant, _ := r.resolve(descendants[0]).(pdfDict)
+
+	if defaultWidth, ok := r.resolve(descendant["DW"]).(int64); ok {
+		f.defaultWidth = float64(defaultWidth)
+	}
+
+	// W is a list of "first [widths...]" and "first last width" entries
+	w, _ := r.resolve(descendant["W"]).(pdfArray)
+	for i := 0; i+1 < len(w); {
+		first := int(number(r.resolve(w[i])))
+		if widths, ok := r.resolve(w[i+1]).(pdfArray); ok {
+			for j, width := range widths {
+				f.widths[first+j] = number(r.resolve(width))
+			}
+			i += 2
+			continue
+		}
+
+		if i+2 >= len(w) {
+			break
+		}
+		last := min(int(number(r.resolve(w[i+1]))), first+0xffff)
+		for code := first; code <= last; code++ {
+			f.widths[code] = number(r.resolve(w[i+2]))
+		}
+		i += 3
+	}
+
+	return f
+}
+
+// doText runs text operators.
+func (r *renderer) doText(operator string, operands []any, n func(int) float64, resources pdfDict) {
+	switch operator {
+	case "BT":
+		r.textMatrix, r.lineMatrix = identity, identity
+	case "Tf":
+		var name pdfName
+		if len(operands) > 0 {
+			name, _ = operands[0].(pdfName)
+		}
+		r.state.font = r.loadFont(name, resources)
+		r.state.fontSize = n(1)
+	case "Tc":
+		r.state.charSpacing = n(0)
+	case "Tw":
+		r.state.wordSpacing = n(0)
+	case "Tz":
+		r.state.horizontalScale = n(0) / 100
+	case "TL":
+		r.state.leading = n(0)
+	case "Ts":
+		r.state.rise = n(0)
+	case "Tr":
+		r.state.renderMode = int(n(0))
+	case "Td":
+		r.moveLine(n(0), n(1))
+	case "TD":
+		r.state.leading = -n(1)
+		r.moveLine(n(0), n(1))
+	case "Tm":
+		r.lineMatrix = matrix{n(0), n(1), n(2), n(3), n(4), n(5)}
+		r.textMatrix = r.lineMatrix
+	case "T*":
+		r.moveLine(0, -r.state.leading)
+	case "Tj":
+		r.showText(lastString(operands))
+	case "'":
+		r.moveLine(0, -r.state.leading)
+		r.showText(lastString(operands))
+	case "\"":
+		r.state.wordSpacing, r.state.charSpacing = n(0), n(1)
+		r.moveLine(0, -r.state.leading)
+		r.showText(lastString(operands))
+	case "TJ":
+		array, _ := lastOperand(operands).(pdfArray)
+		for _, item := range array {
+			if s, ok := item.(pdfString); ok {
+				r.showText(s)
+				continue
+			}
+			// Numbers move the next character back in thousandths of the font size
+			r.advance(-number(item) / 1000 * r.state.fontSize * r.state.horizontalScale)
+		}
+	}
+}
+
+// moveLine starts a new line offset from the start of the current line.
+func (r *renderer) moveLine(x, y float64) {
+	r.lineMatrix = translate(x, y).multiply(r.lineMatrix)
+	r.textMatrix = r.lineMatrix
+}
+
+// advance moves the text position along the line.
+func (r *renderer) advance(x float64) {
+	r.textMatrix = translate(x, 0).multiply(r.textMatrix)
+}
+
+// showText draws a string in the preview font where the document's font would have drawn it.
+func (r *renderer) showText(s pdfString) {
+	f := r.state.font
+	if f == nil {
+		f = &previewFont{defaultWidth: float64(helvetica.defaultWidth)}
+	}
+
+	step := 1
+	if f.twoByte {
+		step = 2
+	}
+
+	for i := 0; i+step <= len(s); i += step {
+		code := int(s[i])
+		if f.twoByte {
+			code = code<<8 | int(s[i+1])
+		}
+		width := f.width(code) / 1000
+
+		// Invisible text, such as the text layer of scanned documents, isn't drawn
+		if r.state.renderMode != 3 && r.state.renderMode != 7 {
+			bitmap := glyph(code)
+			// Codes in composite fonts don't say which character they are
+			if f.twoByte {
+				bitmap = unknownGlyph
+			}
+			r.drawGlyph(bitmap, width)
+		}
+
+		advance := width*r.state.fontSize + r.state.charSpacing
+		if !f.twoByte && code == ' ' {
+			advance += r.state.wordSpacing
+		}
+		r.advance(advance * r.state.horizontalScale)
+	}
+}
+
+// drawGlyph draws a bitmap glyph at the text position, centered on a character width wide in text space.
+func (r *renderer) drawGlyph(bitmap [glyphRows]uint8, width float64) {
+	state := r.state
+	textToPixels := matrix{state.fontSize * state.horizontalScale, 0, 0, state.fontSize, 0, state.rise}.
+		multiply(r.textMatrix).multiply(state.ctm)
+
+	// Cells are a tenth of the font size tall, so capitals are about as tall as Helvetica's.
+	// Narrow characters only use the middle columns, so they can overhang their width.
+	cellHeight, cellWidth := 0.1, 0.09
+	left := (width - cellWidth*glyphColumns) / 2
+
+	polygons := [][]point{}
+	for row, bits := range bitmap {
+		top := float64(glyphBaseline-row) * cellHeight
+		bottom := top - cellHeight
+
+		// Runs of filled cells in a row are drawn as one rectangle
+		for column := 0; column < glyphColumns; column++ {
+			if bits&(1<<(glyphColumns-1-column)) == 0 {
+				continue
+			}
+			end := column + 1
+			for end < glyphColumns && bits&(1<<(glyphColumns-1-end)) != 0 {
+				end++
+			}
+
+			x0, x1 := left+float64(column)*cellWidth, left+float64(end)*cellWidth
+			polygons = append(polygons, []point{
+				textToPixels.apply(x0, bottom),
+				textToPixels.apply(x1, bottom),
+				textToPixels.apply(x1, top),
+				textToPixels.apply(x0, top),
+			})
+			column = end
+		}
+	}
+
+	if len(polygons) > 0 {
+		r.canvas.fill(polygons, r.paint(state.fill), false)
+	}
+}
+
+// lastString returns the last operand if it's a string.
+func lastString(operands []any) pdfString {
+	s, _ := lastOperand(operands).(pdfString)
+	return s
+}
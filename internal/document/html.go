@@ -0,0 +1,228 @@
+package document
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidHTML is returned for HTML that can't be parsed.
+var ErrInvalidHTML = fmt.Errorf("%w: invalid HTML", ErrInvalid)
+
+// htmlParser splits HTML into blocks element by element.
+type htmlParser struct {
+	blocks []block
+	// open is the paragraph, heading or list item that text is added to.
+	open *block
+	// bold, italic and code count the open elements of each style.
+	bold, italic, code int
+	// lists are the open lists, innermost last.
+	lists []htmlList
+	// links are the open links, innermost last.
+	links []*htmlLink
+	// pre is the text of the open pre element.
+	pre *strings.Builder
+	// skip counts the open elements whose text isn't printed.
+	skip int
+}
+
+// htmlList is an open list.
+type htmlList struct {
+	ordered bool
+	// next is the number of the next item of an ordered list.
+	next int
+}
+
+// htmlLink is an open link and its text so far.
+type htmlLink struct {
+	href string
+	text strings.Builder
+}
+
+// parseHTML splits HTML into blocks. Headings, paragraphs, line breaks, emphasis, code, links, lists and rules are supported.
+// Other elements are ignored but their text is kept, except for the head, scripts and styles.
+func parseHTML(content string) ([]block, error) {
+	d := xml.NewDecoder(strings.NewReader(content))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	h := &htmlParser{}
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidHTML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			h.start(strings.ToLower(t.Name.Local), t.Attr)
+		case xml.EndElement:
+			h.end(strings.ToLower(t.Name.Local))
+		case xml.CharData:
+			h.text(string(t))
+		}
+	}
+	h.flush()
+
+	return h.blocks, nil
+}
+
+// start handles the start of an element.
+func (h *htmlParser) start(name string, attrs []xml.Attr) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		h.flush()
+		h.open = &block{kind: blockHeading, level: int(name[1] - '0')}
+	case "p", "div", "blockquote", "address", "section", "article", "header", "footer", "table", "tr":
+		h.flush()
+	case "br":
+		h.add(span{text: "\n"})
+	case "hr":
+		h.flush()
+		h.blocks = append(h.blocks, block{kind: blockRule})
+	case "b", "strong":
+		h.bold++
+	case "i", "em", "cite":
+		h.italic++
+	case "code", "tt", "kbd", "samp":
+		h.code++
+	case "pre":
+		h.flush()
+		h.pre = &strings.Builder{}
+	case "ul", "ol":
+		h.flush()
+		list := htmlList{ordered: name == "ol", next: 1}
+		if start, err := strconv.Atoi(attr(attrs, "start")); err == nil {
+			list.next = start
+		}
+		h.lists = append(h.lists, list)
+	case "li":
+		h.flush()
+		h.open = &block{kind: blockItem, marker: bullet, level: max(len(h.lists)-1, 0)}
+		if len(h.lists) > 0 && h.lists[len(h.lists)-1].ordered {
+			list := &h.lists[len(h.lists)-1]
+			h.open.marker = strconv.Itoa(list.next) + "."
+			list.next++
+		}
+	case "a":
+		h.links = append(h.links, &htmlLink{href: attr(attrs, "href")})
+	case "head", "script", "style", "title":
+		h.skip++
+	}
+}
+
+// end handles the end of an element.
+func (h *htmlParser) end(name string) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p", "div", "blockquote", "address", "section", "article", "header",
+		"footer", "table", "tr", "li":
+		h.flush()
+	case "b", "strong":
+		h.bold = max(h.bold-1, 0)
+	case "i", "em", "cite":
+		h.italic = max(h.italic-1, 0)
+	case "code", "tt", "kbd", "samp":
+		h.code = max(h.code-1, 0)
+	case "pre":
+		if h.pre == nil {
+			return
+		}
+		// The newline after the start tag isn't part of the text
+		text := strings.TrimPrefix(strings.ReplaceAll(h.pre.String(), "\t", "    "), "\n")
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			h.blocks = append(h.blocks, block{kind: blockCode, spans: []span{{text: line, style: styleCode}}})
+		}
+		h.pre = nil
+	case "ul", "ol":
+		h.flush()
+		if len(h.lists) > 0 {
+			h.lists = h.lists[:len(h.lists)-1]
+		}
+	case "a":
+		if len(h.links) == 0 {
+			return
+		}
+		link := h.links[len(h.links)-1]
+		h.links = h.links[:len(h.links)-1]
+
+		// Links are printed with their URL after the text, unless the text is the URL
+		text := strings.TrimSpace(link.text.String())
+		if link.href != "" && !strings.HasPrefix(link.href, "#") && text != link.href && text != "" {
+			h.add(span{text: " (" + link.href + ")", style: h.style()})
+		}
+	case "head", "script", "style", "title":
+		h.skip = max(h.skip-1, 0)
+	}
+}
+
+// text handles text between elements.
+func (h *htmlParser) text(text string) {
+	if h.skip > 0 {
+		return
+	}
+
+	if h.pre != nil {
+		h.pre.WriteString(text)
+		return
+	}
+
+	for _, link := range h.links {
+		link.text.WriteString(text)
+	}
+
+	// Whitespace between blocks isn't a paragraph
+	if h.open == nil && strings.TrimFunc(text, isWordSpace) == "" {
+		return
+	}
+	h.add(span{text: text, style: h.style()})
+}
+
+// add adds a span to the open block, opening a paragraph if there isn't one.
+func (h *htmlParser) add(s span) {
+	if h.open == nil {
+		h.open = &block{kind: blockParagraph}
+	}
+	h.open.spans = append(h.open.spans, s)
+}
+
+// style returns the style of text in the open elements.
+func (h *htmlParser) style() style {
+	var s style
+	if h.bold > 0 {
+		s |= styleBold
+	}
+	if h.italic > 0 {
+		s |= styleItalic
+	}
+	if h.code > 0 {
+		s |= styleCode
+	}
+
+	return s
+}
+
+// flush adds the open block.
+func (h *htmlParser) flush() {
+	if h.open != nil {
+		h.blocks = append(h.blocks, *h.open)
+		h.open = nil
+	}
+}
+
+// attr returns the value of the named attribute, or "" if it isn't set.
+func attr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,497 @@
+package document
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// Format is the markup a letter is written in.
+type Format string
+
+const (
+	// FormatMarkdown is Markdown headings, paragraphs, emphasis, lists, rules and code.
+	FormatMarkdown Format = "Markdown"
+	// FormatText is plain text, laid out line by line.
+	FormatText Format = "Text"
+	// FormatHTML is the subset of HTML that Markdown covers.
+	FormatHTML Format = "HTML"
+)
+
+// PageSize is the size of the pages a letter is rendered on.
+type PageSize string
+
+const (
+	// PageSizeLetter is US Letter.
+	PageSizeLetter PageSize = "Letter"
+	// PageSizeA4 is ISO A4.
+	PageSizeA4 PageSize = "A4"
+)
+
+// Align is how letterhead lines are aligned.
+type Align string
+
+const (
+	// AlignLeft starts lines at the left margin.
+	AlignLeft Align = "Left"
+	// AlignCenter centers lines between the margins.
+	AlignCenter Align = "Center"
+	// AlignRight ends lines at the right margin.
+	AlignRight Align = "Right"
+)
+
+const (
+	// lineSpacing is the distance between baselines relative to the font size.
+	lineSpacing = 1.3
+	// minTextWidth is the narrowest the text on a page can be after the margins.
+	minTextWidth = 2 * pointsPerInch
+)
+
+var (
+	// ErrUnknownFormat is returned for letters that aren't in a known format.
+	ErrUnknownFormat = fmt.Errorf("%w: unknown format", ErrInvalid)
+	// ErrUnknownPageSize is returned for page sizes letters can't be rendered on.
+	ErrUnknownPageSize = fmt.Errorf("%w: unknown page size", ErrInvalid)
+	// ErrInvalidLayout is returned for letter layouts that don't fit on the page.
+	ErrInvalidLayout = fmt.Errorf("%w: invalid layout", ErrInvalid)
+)
+
+// pageSizes are the width and height of each page size in points.
+var pageSizes = map[PageSize][2]float64{
+	PageSizeLetter: {letterWidth, letterHeight},
+	PageSizeA4:     {a4Width, a4Height},
+}
+
+// headingScales are the font sizes of headings relative to the body, from level 1 to 3.
+// Deeper headings are the size of level 3.
+var headingScales = []float64{1.6, 1.3, 1.1}
+
+// LetterConfig is the layout of a rendered letter.
+type LetterConfig struct {
+	// Format defaults to FormatMarkdown.
+	Format Format
+	// Font defaults to FontHelvetica. Code is always set in Courier.
+	Font Font
+	// FontSize defaults to 11 points.
+	FontSize float64
+	// Margin defaults to 72 points, one inch, on every side.
+	Margin float64
+	// PageSize defaults to PageSizeLetter.
+	PageSize PageSize
+	// Letterhead lines are printed at the top of the first page above a rule, with the first line larger and in bold.
+	Letterhead []string
+	// LetterheadAlign defaults to AlignLeft.
+	LetterheadAlign Align
+}
+
+// RenderLetter renders a letter onto pages, wrapping lines and starting new pages as needed.
+func RenderLetter(content string, c *LetterConfig) ([]byte, error) {
+	if c == nil {
+		c = &LetterConfig{}
+	}
+
+	family, err := findFamily(c.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	size, ok := pageSizes[cmp.Or(c.PageSize, PageSizeLetter)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPageSize, c.PageSize)
+	}
+
+	align := cmp.Or(c.LetterheadAlign, AlignLeft)
+	if align != AlignLeft && align != AlignCenter && align != AlignRight {
+		return nil, fmt.Errorf("%w: unknown letterhead alignment %q", ErrInvalidLayout, align)
+	}
+
+	l := &letterLayout{
+		family: family,
+		size:   cmp.Or(max(c.FontSize, 0), 11),
+		margin: cmp.Or(max(c.Margin, 0), 72),
+		width:  size[0],
+		height: size[1],
+	}
+	if l.width-2*l.margin < minTextWidth || l.height-2*l.margin < minTextWidth {
+		return nil, fmt.Errorf("%w: a margin of %s points leaves no room for text", ErrInvalidLayout, formatNumber(l.margin))
+	}
+
+	var blocks []block
+	switch cmp.Or(c.Format, FormatMarkdown) {
+	case FormatMarkdown:
+		blocks = parseMarkdown(content)
+	case FormatText:
+		blocks = parseText(content)
+	case FormatHTML:
+		blocks, err = parseHTML(content)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, c.Format)
+	}
+
+	l.letterhead(c.Letterhead, align)
+	for _, b := range blocks {
+		l.block(b)
+	}
+
+	// Letters without any text are still a blank page
+	if len(l.pages) == 0 {
+		l.pages = append(l.pages, newPage(l.width, l.height))
+	}
+
+	return writePages(l.pages), nil
+}
+
+// style is the emphasis of a span of text.
+type style uint8
+
+const (
+	styleBold style = 1 << iota
+	styleItalic
+	// styleCode is set in Courier.
+	styleCode
+)
+
+// span is text in one style. A span of a single newline breaks the line.
+type span struct {
+	text  string
+	style style
+}
+
+// blockKind is the kind of a block of a letter.
+type blockKind int
+
+const (
+	// blockParagraph is wrapped text with space around it.
+	blockParagraph blockKind = iota
+	// blockHeading is a bold paragraph in a larger font.
+	blockHeading
+	// blockItem is a list item, indented after its marker.
+	blockItem
+	// blockRule is a horizontal line across the page.
+	blockRule
+	// blockLine is a line of plain text, wrapped without space around it.
+	blockLine
+	// blockCode is a line of code, which keeps its spaces and is only broken when it's wider than the page.
+	blockCode
+)
+
+// block is a paragraph, heading, list item, rule or line of a letter.
+type block struct {
+	kind blockKind
+	// level is the level of a heading from 1, or how deep a list item is nested from 0.
+	level int
+	// marker is the bullet or number of a list item.
+	marker string
+	spans  []span
+}
+
+// letterLayout lays blocks out on pages from top to bottom.
+type letterLayout struct {
+	family                      *fontFamily
+	size, margin, width, height float64
+	pages                       []*page
+	current                     *page
+	// top is where the space left on the current page starts, in points from the bottom.
+	top float64
+	// space is the space to leave before the next line, unless it starts a page.
+	space float64
+}
+
+// block lays out a block. Space between blocks is the largest of the space after one and before the next.
+func (l *letterLayout) block(b block) {
+	switch b.kind {
+	case blockHeading:
+		size := l.size * headingScales[min(max(b.level, 1), len(headingScales))-1]
+		spans := make([]span, 0, len(b.spans))
+		for _, s := range b.spans {
+			spans = append(spans, span{text: s.text, style: s.style | styleBold})
+		}
+
+		l.addSpace(size * 0.8)
+		l.paragraph(spans, size, l.margin, "", 0)
+		l.addSpace(size * 0.4)
+	case blockParagraph:
+		l.addSpace(l.size * 0.7)
+		l.paragraph(b.spans, l.size, l.margin, "", 0)
+		l.addSpace(l.size * 0.7)
+	case blockItem:
+		indent := l.size * 1.8
+		// Deeply nested items stop being indented halfway across the page
+		markerX := l.margin + min(float64(b.level)*indent, (l.width-2*l.margin)/2)
+
+		l.addSpace(l.size * 0.2)
+		l.paragraph(b.spans, l.size, markerX+indent, b.marker, markerX)
+		l.addSpace(l.size * 0.2)
+	case blockRule:
+		l.addSpace(l.size * 0.7)
+		l.rule()
+		l.addSpace(l.size * 0.7)
+	case blockLine:
+		l.paragraph(b.spans, l.size, l.margin, "", 0)
+	case blockCode:
+		text := ""
+		for _, s := range b.spans {
+			text += s.text
+		}
+		// The line is one word so its spaces are kept
+		code := word{parts: []span{{text: text, style: styleCode}}}
+		for _, line := range l.wrap([]word{code}, l.size, l.width-2*l.margin) {
+			p, y := l.newLine(l.size)
+			l.drawLine(p, l.margin, y, l.size, line)
+		}
+	}
+}
+
+// letterhead lays out the letterhead lines above a rule. Lines wider than the page are shrunk to fit.
+func (l *letterLayout) letterhead(lines []string, align Align) {
+	if len(lines) == 0 {
+		return
+	}
+
+	for i, text := range lines {
+		f, size := l.family.regular, l.size*0.9
+		if i == 0 {
+			f, size = l.family.bold, l.size*1.4
+		}
+
+		available := l.width - 2*l.margin
+		size = fitSize(f, text, size, available)
+
+		p, y := l.newLine(size)
+		x := l.margin
+		switch align {
+		case AlignCenter:
+			x += (available - f.width(text, size)) / 2
+		case AlignRight:
+			x += available - f.width(text, size)
+		}
+		p.text(f, size, x, y, text)
+	}
+
+	l.addSpace(l.size * 0.2)
+	l.rule()
+	l.addSpace(l.size * 1.5)
+}
+
+// rule draws a line across the page, taking up a line of text.
+func (l *letterLayout) rule() {
+	p, y := l.newLine(l.size)
+	p.fillRect(l.margin, y+l.size*0.3, l.width-2*l.margin, 0.5)
+}
+
+// addSpace leaves space before the next line, unless the next line starts a page.
+func (l *letterLayout) addSpace(space float64) {
+	l.space = max(l.space, space)
+}
+
+// newLine returns the page and baseline of the next line of text at size, starting a new page when the current one is full.
+func (l *letterLayout) newLine(size float64) (*page, float64) {
+	if l.current != nil {
+		l.top -= l.space
+	}
+	l.space = 0
+
+	if l.current == nil || l.top-size < l.margin {
+		l.current = newPage(l.width, l.height)
+		l.pages = append(l.pages, l.current)
+		l.top = l.height - l.margin
+	}
+
+	y := l.top - size
+	l.top -= size * lineSpacing
+
+	return l.current, y
+}
+
+// paragraph lays out spans wrapped between x and the right margin.
+// The marker of a list item is drawn at markerX on the first line.
+func (l *letterLayout) paragraph(spans []span, size, x float64, marker string, markerX float64) {
+	for i, line := range l.wrap(words(spans), size, l.width-l.margin-x) {
+		p, y := l.newLine(size)
+		if i == 0 && marker != "" {
+			p.text(l.family.regular, size, markerX, y, marker)
+		}
+		l.drawLine(p, x, y, size, line)
+	}
+}
+
+// font returns the font for text in the style.
+func (l *letterLayout) font(s style) *font {
+	if s&styleCode != 0 {
+		return fontFamilies[FontCourier].font(s)
+	}
+
+	return l.family.font(s)
+}
+
+// word is text between spaces, which can change style part way through.
+type word struct {
+	parts []span
+	// lineBreak is set for a line break instead of a word.
+	lineBreak bool
+}
+
+// isWordSpace reports whether r separates words. Non-breaking spaces don't.
+func isWordSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// words splits spans into words, joining text that isn't separated by spaces across spans.
+func words(spans []span) []word {
+	words := []word{}
+	joined := false
+	for _, s := range spans {
+		if s.text == "\n" {
+			words = append(words, word{lineBreak: true})
+			joined = false
+			continue
+		}
+
+		text := s.text
+		for text != "" {
+			end := strings.IndexFunc(text, isWordSpace)
+			if end == 0 {
+				text = strings.TrimLeftFunc(text, isWordSpace)
+				joined = false
+				continue
+			}
+			if end < 0 {
+				end = len(text)
+			}
+
+			part := span{text: text[:end], style: s.style}
+			if joined {
+				last := &words[len(words)-1]
+				last.parts = append(last.parts, part)
+			} else {
+				words = append(words, word{parts: []span{part}})
+			}
+			joined = true
+			text = text[end:]
+		}
+	}
+
+	return words
+}
+
+// wordWidth returns the width of w in points at size.
+func (l *letterLayout) wordWidth(w word, size float64) float64 {
+	width := 0.0
+	for _, part := range w.parts {
+		width += l.font(part.style).width(part.text, size)
+	}
+
+	return width
+}
+
+// wrap splits words into lines no wider than maxWidth, breaking words wider than a line wherever they run out of space.
+// There's always at least one line, which may be empty.
+func (l *letterLayout) wrap(words []word, size, maxWidth float64) [][]word {
+	lines := [][]word{}
+	line := []word{}
+	lineWidth := 0.0
+
+	for _, w := range words {
+		if w.lineBreak {
+			lines = append(lines, line)
+			line, lineWidth = []word{}, 0
+			continue
+		}
+
+		width := l.wordWidth(w, size)
+		space := 0.0
+		if len(line) > 0 {
+			space = l.font(w.parts[0].style).width(" ", size)
+		}
+
+		if lineWidth+space+width <= maxWidth {
+			line = append(line, w)
+			lineWidth += space + width
+			continue
+		}
+
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+
+		for width > maxWidth && width > 0 {
+			var head word
+			head, w = l.splitWord(w, size, maxWidth)
+			lines = append(lines, []word{head})
+			width = l.wordWidth(w, size)
+		}
+		line, lineWidth = []word{w}, width
+	}
+
+	return append(lines, line)
+}
+
+// splitWord splits w after as many characters as fit in maxWidth, and always after at least one.
+func (l *letterLayout) splitWord(w word, size, maxWidth float64) (word, word) {
+	head, tail := word{}, word{}
+	width := 0.0
+
+	for i, part := range w.parts {
+		f := l.font(part.style)
+		for j, r := range part.text {
+			width += f.width(string(r), size)
+			if width <= maxWidth || (i == 0 && j == 0) {
+				continue
+			}
+
+			if j > 0 {
+				head.parts = append(head.parts, span{text: part.text[:j], style: part.style})
+			}
+			tail.parts = append(tail.parts, span{text: part.text[j:], style: part.style})
+			tail.parts = append(tail.parts, w.parts[i+1:]...)
+
+			return head, tail
+		}
+		head.parts = append(head.parts, part)
+	}
+
+	return head, tail
+}
+
+// drawLine draws the words of a line starting at x, joining words in the same style into one run of text.
+func (l *letterLayout) drawLine(p *page, x, y, size float64, line []word) {
+	runs := []span{}
+	for i, w := range line {
+		for j, part := range w.parts {
+			text := part.text
+			if i > 0 && j == 0 {
+				text = " " + text
+			}
+
+			if last := len(runs) - 1; last >= 0 && runs[last].style == part.style {
+				runs[last].text += text
+				continue
+			}
+			runs = append(runs, span{text: text, style: part.style})
+		}
+	}
+
+	for _, run := range runs {
+		if run.text == "" {
+			continue
+		}
+		f := l.font(run.style)
+		p.text(f, size, x, y, run.text)
+		x += f.width(run.text, size)
+	}
+}
+
+// parseText splits plain text into lines, which are wrapped as they're laid out.
+func parseText(content string) []block {
+	content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\t", "    ")
+
+	blocks := []block{}
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		blocks = append(blocks, block{kind: blockLine, spans: []span{{text: line}}})
+	}
+
+	return blocks
+}
@@ -0,0 +1,146 @@
+package document
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenderLetter", func() {
+	It("should render Markdown headings, emphasis, code and links", func() {
+		rendered, err := RenderLetter("# Reminder\n\nDear **Jane**, invoice `INV-1` is *due*. [Pay online](https://example.com/pay).", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := Inspect(rendered)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(1))
+
+		// Fonts are numbered in the order they're first used on the page
+		Expect(string(rendered)).To(ContainSubstring("BT /F1 17.6 Tf 72 702.4 Td (Reminder) Tj ET"))
+		Expect(string(rendered)).To(ContainSubstring("/F2 11 Tf 72 678.42 Td (Dear) Tj"))
+		// Bold text starts where the regular text before it ends
+		Expect(string(rendered)).To(ContainSubstring("/F1 11 Tf " + formatNumber(72+helvetica.width("Dear", 11)) + " 678.42 Td ( Jane) Tj"))
+		Expect(string(rendered)).To(ContainSubstring("( INV-1) Tj"))
+		Expect(string(rendered)).To(ContainSubstring("( due) Tj"))
+		Expect(string(rendered)).To(ContainSubstring("(. Pay online \\(https://example.com/pay\\).) Tj"))
+		for _, name := range []string{"Helvetica-Bold", "Helvetica", "Courier", "Helvetica-Oblique"} {
+			Expect(string(rendered)).To(ContainSubstring("/BaseFont /" + name + " "))
+		}
+	})
+
+	It("should keep delimiters that aren't emphasis", func() {
+		spans := parseInline("snake_case and 2 * 3 and \\*literal\\*")
+		Expect(spans).To(Equal([]span{{text: "snake_case and 2 * 3 and *literal*"}}))
+
+		spans = parseInline("***both*** then `code`")
+		Expect(spans).To(Equal([]span{
+			{text: "both", style: styleBold | styleItalic},
+			{text: " then "},
+			{text: "code", style: styleCode},
+		}))
+	})
+
+	It("should parse Markdown lists, rules, code blocks and line breaks", func() {
+		blocks := parseMarkdown("- one\n  - nested\n1. first\n\n---\n\n```\n  indented\n```\nline  \nbreak")
+		Expect(blocks).To(Equal([]block{
+			{kind: blockItem, marker: bullet, spans: []span{{text: "one"}}},
+			{kind: blockItem, level: 1, marker: bullet, spans: []span{{text: "nested"}}},
+			{kind: blockItem, marker: "1.", spans: []span{{text: "first"}}},
+			{kind: blockRule},
+			{kind: blockCode, spans: []span{{text: "  indented", style: styleCode}}},
+			{kind: blockParagraph, spans: []span{{text: "line"}, {text: "\n"}, {text: "break"}}},
+		}))
+	})
+
+	It("should parse the HTML subset", func() {
+		blocks, err := parseHTML(`<html><head><title>Ignored</title></head><body>
+			<h2>Hello</h2>
+			<p>Dear <b>Jane</b>, <a href="https://example.com">pay</a> &amp; smile.<br>Bye</p>
+			<ol start="3"><li>three<li>four</ol>
+			<hr>
+		</body></html>`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blocks).To(Equal([]block{
+			{kind: blockHeading, level: 2, spans: []span{{text: "Hello"}}},
+			{kind: blockParagraph, spans: []span{
+				{text: "Dear "}, {text: "Jane", style: styleBold}, {text: ", "}, {text: "pay"},
+				{text: " (https://example.com)"}, {text: " & smile."}, {text: "\n"}, {text: "Bye"},
+			}},
+			{kind: blockItem, marker: "3.", spans: []span{{text: "three"}}},
+			{kind: blockItem, marker: "4.", spans: []span{{text: "four"}}},
+			{kind: blockRule},
+		}))
+
+		_, err = RenderLetter("<p>1 < 2</p>", &LetterConfig{Format: FormatHTML})
+		Expect(err).To(MatchError(ErrInvalidHTML))
+	})
+
+	It("should wrap styled text and start new pages", func() {
+		text := strings.Repeat("All work and **no play** makes Jack a dull boy. ", 500)
+
+		rendered, err := RenderLetter(text, nil)
+		Expect(err).NotTo(HaveOccurred())
+		info, err := Inspect(rendered)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(BeNumerically(">", 1))
+
+		l := &letterLayout{family: fontFamilies[FontHelvetica]}
+		for _, line := range l.wrap(words(parseInline(text)), 11, 468) {
+			width := 0.0
+			for i, w := range line {
+				if i > 0 {
+					width += l.font(w.parts[0].style).width(" ", 11)
+				}
+				width += l.wordWidth(w, 11)
+			}
+			Expect(width).To(BeNumerically("<=", 468))
+		}
+	})
+
+	It("should lay out plain text line by line", func() {
+		rendered, err := RenderLetter("Total (USD):\t10\n\nThanks", &LetterConfig{Format: FormatText, Font: FontCourier})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rendered)).To(ContainSubstring("BT /F1 11 Tf 72 709 Td (Total \\(USD\\): 10) Tj ET"))
+		// The blank line is kept
+		Expect(string(rendered)).To(ContainSubstring("BT /F1 11 Tf 72 680.4 Td (Thanks) Tj ET"))
+	})
+
+	It("should print the letterhead and lay out the page", func() {
+		rendered, err := RenderLetter("Hello", &LetterConfig{
+			Font:            FontTimes,
+			FontSize:        10,
+			Margin:          50,
+			PageSize:        PageSizeA4,
+			Letterhead:      []string{"Acme Corp", "1 Infinite Loop"},
+			LetterheadAlign: AlignRight,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rendered)).To(ContainSubstring("/MediaBox [0 0 595.28 841.89]"))
+
+		// The first line is larger and bold and both end at the right margin
+		right := a4Width - 50
+		Expect(string(rendered)).To(ContainSubstring(
+			"BT /F1 14 Tf " + formatNumber(right-timesBold.width("Acme Corp", 14)) + " 777.89 Td (Acme Corp) Tj ET"))
+		Expect(string(rendered)).To(ContainSubstring(
+			"BT /F2 9 Tf " + formatNumber(right-timesRoman.width("1 Infinite Loop", 9)) + " "))
+		Expect(string(rendered)).To(ContainSubstring(" re f\n"))
+		Expect(string(rendered)).To(ContainSubstring("/BaseFont /Times-Roman "))
+		Expect(string(rendered)).To(ContainSubstring("Td (Hello) Tj"))
+	})
+
+	It("should reject unknown options", func() {
+		_, err := RenderLetter("Hello", &LetterConfig{Format: "RTF"})
+		Expect(err).To(MatchError(ErrUnknownFormat))
+
+		_, err = RenderLetter("Hello", &LetterConfig{Font: "Comic Sans"})
+		Expect(err).To(MatchError(ErrUnknownFont))
+
+		_, err = RenderLetter("Hello", &LetterConfig{PageSize: "Legal"})
+		Expect(err).To(MatchError(ErrUnknownPageSize))
+
+		_, err = RenderLetter("Hello", &LetterConfig{Margin: 250})
+		Expect(err).To(MatchError(ErrInvalidLayout))
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+})
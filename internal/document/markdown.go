@@ -0,0 +1,190 @@
+package document
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bullet is the marker of unordered list items.
+const bullet = "•"
+
+var (
+	headingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	itemPattern     = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	rulePattern     = regexp.MustCompile(`^ {0,3}((-[ \t]*){3,}|(\*[ \t]*){3,}|(_[ \t]*){3,})$`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]*)\)`)
+	autoLinkPattern = regexp.MustCompile(`<((https?://|mailto:)[^>\s]+)>`)
+)
+
+// markdownParser splits Markdown into blocks line by line.
+type markdownParser struct {
+	blocks []block
+	// open is the paragraph or list item that following lines are added to.
+	open *block
+	// lines are the lines of the open block.
+	lines []string
+	// fence is the fence of the open code block.
+	fence string
+}
+
+// parseMarkdown splits Markdown into blocks. Headings, paragraphs, emphasis, code, links, lists and rules are supported.
+func parseMarkdown(content string) []block {
+	p := &markdownParser{}
+	content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\t", "    ")
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if p.fence != "" {
+			if strings.HasPrefix(trimmed, p.fence) {
+				p.fence = ""
+				continue
+			}
+			p.blocks = append(p.blocks, block{kind: blockCode, spans: []span{{text: line, style: styleCode}}})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			p.flush()
+			p.fence = trimmed[:3]
+		case trimmed == "":
+			p.flush()
+		case rulePattern.MatchString(line):
+			p.flush()
+			p.blocks = append(p.blocks, block{kind: blockRule})
+		case headingPattern.MatchString(line):
+			p.flush()
+			match := headingPattern.FindStringSubmatch(line)
+			p.blocks = append(p.blocks, block{kind: blockHeading, level: len(match[1]), spans: parseInline(match[2])})
+		case itemPattern.MatchString(line):
+			p.flush()
+			match := itemPattern.FindStringSubmatch(line)
+			marker := match[2]
+			if strings.ContainsAny(marker, "-*+") {
+				marker = bullet
+			}
+			// Nested items are indented by two or more spaces
+			p.open = &block{kind: blockItem, level: len(match[1]) / 2, marker: marker}
+			p.lines = []string{match[3]}
+		case p.open != nil:
+			p.lines = append(p.lines, line)
+		default:
+			p.open = &block{kind: blockParagraph}
+			p.lines = []string{line}
+		}
+	}
+	p.flush()
+
+	return p.blocks
+}
+
+// flush adds the open block, joining its lines. Lines ending in two spaces or a backslash break the line.
+func (p *markdownParser) flush() {
+	if p.open == nil {
+		return
+	}
+
+	var b strings.Builder
+	for i, line := range p.lines {
+		hardBreak := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+		b.WriteString(strings.TrimSuffix(strings.TrimSpace(line), "\\"))
+		switch {
+		case i == len(p.lines)-1:
+		case hardBreak:
+			b.WriteString("\n")
+		default:
+			b.WriteString(" ")
+		}
+	}
+
+	p.open.spans = parseInline(b.String())
+	p.blocks = append(p.blocks, *p.open)
+	p.open, p.lines = nil, nil
+}
+
+// parseInline splits Markdown text into spans of emphasis and code. Links are printed with their URL after the text.
+// Newlines in text break the line.
+func parseInline(text string) []span {
+	text = autoLinkPattern.ReplaceAllString(text, "$1")
+	text = linkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		match := linkPattern.FindStringSubmatch(link)
+		if match[1] == "" || match[1] == match[2] {
+			return match[2]
+		}
+		return match[1] + " (" + match[2] + ")"
+	})
+
+	spans := []span{}
+	var b strings.Builder
+	var s style
+	emit := func() {
+		if b.Len() > 0 {
+			spans = append(spans, span{text: b.String(), style: s})
+			b.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune("\\`*_{}[]()#+-.!<>|~", runes[i+1]):
+			i++
+			b.WriteRune(runes[i])
+		case r == '\n':
+			emit()
+			spans = append(spans, span{text: "\n"})
+		case r == '`':
+			end := strings.IndexRune(string(runes[i+1:]), '`')
+			if end < 0 {
+				b.WriteRune(r)
+				continue
+			}
+			code := []rune(string(runes[i+1:])[:end])
+			emit()
+			spans = append(spans, span{text: string(code), style: s | styleCode})
+			i += len(code) + 1
+		case r == '*' || r == '_':
+			n := 1
+			if i+1 < len(runes) && runes[i+1] == r {
+				n = 2
+			}
+			toggle := styleItalic
+			if n == 2 {
+				toggle = styleBold
+			}
+
+			delimiter := string(runes[i : i+n])
+			opening := s&toggle == 0
+			// Underscores inside words like snake_case aren't emphasis
+			intraword := r == '_' && i > 0 && isWordRune(runes[i-1]) && i+n < len(runes) && isWordRune(runes[i+n])
+			// Emphasis starts before and ends after text, so delimiters next to spaces are printed as they are
+			flanking := i+n < len(runes) && !isWordSpace(runes[i+n])
+			if !opening {
+				flanking = i > 0 && !isWordSpace(runes[i-1])
+			}
+			// Delimiters that are never closed are printed as they are too
+			unclosed := opening && !strings.Contains(string(runes[i+n:]), delimiter)
+			if !flanking || intraword || unclosed {
+				b.WriteString(delimiter)
+				i += n - 1
+				continue
+			}
+
+			emit()
+			s ^= toggle
+			i += n - 1
+		default:
+			b.WriteRune(r)
+		}
+	}
+	emit()
+
+	return spans
+}
+
+// isWordRune reports whether r is part of a word rather than punctuation or space.
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7f && !isWordSpace(r)
+}
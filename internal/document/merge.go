@@ -0,0 +1,212 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrEncrypted is returned for encrypted PDFs, which can't be merged.
+	ErrEncrypted = fmt.Errorf("%w: PDF is encrypted", ErrInvalid)
+	// ErrNoDocuments is returned when there's nothing to merge.
+	ErrNoDocuments = fmt.Errorf("%w: no documents to merge", ErrInvalid)
+
+	// trailerEncrypt matches the encryption dictionary reference in a trailer.
+	trailerEncrypt = regexp.MustCompile(`/Encrypt\s+\d+\s+\d+\s+R`)
+
+	// inheritedPageKeys are the page attributes that can be set on a parent in the page tree instead of the page.
+	inheritedPageKeys = []pdfName{"Resources", "MediaBox", "CropBox", "Rotate"}
+	// letterMediaBox is the page size used when a document doesn't set one.
+	letterMediaBox = pdfArray{int64(0), int64(0), int64(612), int64(792)}
+)
+
+// Part describes one of the documents in a merged document.
+type Part struct {
+	// Pages is the number of pages from the document.
+	Pages int
+	// BlankPages is the number of blank pages added after the document.
+	BlankPages int
+}
+
+// MergeConfig is the configuration used to merge documents.
+type MergeConfig struct {
+	// Duplex adds a blank page after documents with an odd number of pages,
+	// so each document starts on a new sheet when printed double-sided.
+	Duplex bool
+}
+
+// Merge concatenates PDFs into a single PDF in order.
+// Only the pages and what they use are copied, so document level features such as outlines and forms are dropped.
+func Merge(docs [][]byte, c *MergeConfig) ([]byte, []Part, error) {
+	if len(docs) == 0 {
+		return nil, nil, ErrNoDocuments
+	}
+
+	if c == nil {
+		c = &MergeConfig{}
+	}
+
+	w := &pdfWriter{}
+	pagesRef := w.reserve()
+	kids := pdfArray{}
+	parts := make([]Part, 0, len(docs))
+
+	for i, data := range docs {
+		if trailerEncrypt.Match(data) {
+			return nil, nil, fmt.Errorf("document %d: %w", i+1, ErrEncrypted)
+		}
+
+		f, err := parsePDF(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+
+		pages := f.pages()
+		if len(pages) == 0 {
+			return nil, nil, fmt.Errorf("document %d: %w", i+1, ErrNoPages)
+		}
+
+		copied := f.copyPages(w, pages, pagesRef)
+		kids = append(kids, copied...)
+		part := Part{Pages: len(pages)}
+
+		// The last document doesn't need to end on a full sheet
+		if c.Duplex && len(pages)%2 == 1 && i < len(docs)-1 {
+			last := f.pageDict(pages[len(pages)-1])
+			kids = append(kids, w.add(blankPage(pagesRef, last["MediaBox"])))
+			part.BlankPages = 1
+		}
+
+		parts = append(parts, part)
+	}
+
+	w.set(pagesRef, pdfDict{
+		"Type":  pdfName("Pages"),
+		"Kids":  kids,
+		"Count": int64(len(kids)),
+	})
+	root := w.add(pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": pagesRef,
+	})
+
+	return w.bytes(root), parts, nil
+}
+
+// blankPage returns an empty page the size of mediaBox.
+func blankPage(parent pdfRef, mediaBox any) pdfDict {
+	if mediaBox == nil {
+		mediaBox = letterMediaBox
+	}
+
+	return pdfDict{
+		"Type":      pdfName("Page"),
+		"Parent":    parent,
+		"MediaBox":  mediaBox,
+		"Resources": pdfDict{},
+	}
+}
+
+// copyPages copies pages and everything they refer to into w under parent and returns the new page references.
+func (f *pdfFile) copyPages(w *pdfWriter, pages []pdfRef, parent pdfRef) pdfArray {
+	copier := &objectCopier{file: f, writer: w, copied: map[int]pdfRef{}}
+
+	// Reserve every page first so links between pages point to the copies instead of copying pages twice
+	refs := make(pdfArray, 0, len(pages))
+	for _, page := range pages {
+		ref := w.reserve()
+		copier.copied[page.num] = ref
+		refs = append(refs, ref)
+	}
+
+	for i, page := range pages {
+		dict := pdfDict{}
+		for key, value := range f.pageDict(page) {
+			dict[key] = copier.copy(value)
+		}
+		dict["Parent"] = parent
+		if dict["MediaBox"] == nil {
+			dict["MediaBox"] = letterMediaBox
+		}
+		w.set(refs[i].(pdfRef), dict)
+	}
+
+	return refs
+}
+
+// pageDict returns a page's dictionary including the attributes it inherits from the page tree.
+func (f *pdfFile) pageDict(page pdfRef) pdfDict {
+	dict, _ := f.resolve(page).(pdfDict)
+	merged := pdfDict{}
+	for key, value := range dict {
+		if key != "Parent" {
+			merged[key] = value
+		}
+	}
+
+	parent := dict["Parent"]
+	for range 32 {
+		node, ok := f.resolve(parent).(pdfDict)
+		if !ok {
+			break
+		}
+
+		for _, key := range inheritedPageKeys {
+			if _, set := merged[key]; !set && node[key] != nil {
+				merged[key] = node[key]
+			}
+		}
+		parent = node["Parent"]
+	}
+
+	return merged
+}
+
+// objectCopier copies objects from a parsed PDF into a writer, renumbering references.
+type objectCopier struct {
+	file   *pdfFile
+	writer *pdfWriter
+	// copied maps object numbers in the file to their references in the writer
+	copied map[int]pdfRef
+}
+
+// copy returns a copy of obj whose references point to copies in the writer.
+func (c *objectCopier) copy(obj any) any {
+	switch v := obj.(type) {
+	case pdfRef:
+		if ref, ok := c.copied[v.num]; ok {
+			return ref
+		}
+
+		target, ok := c.file.objects[v.num]
+		if !ok {
+			return nil
+		}
+
+		// Reserve the reference before copying so cycles end here
+		ref := c.writer.reserve()
+		c.copied[v.num] = ref
+		c.writer.set(ref, c.copy(target))
+		return ref
+	case pdfArray:
+		array := make(pdfArray, len(v))
+		for i, item := range v {
+			array[i] = c.copy(item)
+		}
+		return array
+	case pdfDict:
+		dict := pdfDict{}
+		for key, value := range v {
+			dict[key] = c.copy(value)
+		}
+		return dict
+	case *pdfStream:
+		dict := pdfDict{}
+		for key, value := range v.dict {
+			dict[key] = c.copy(value)
+		}
+		return &pdfStream{dict: dict, data: v.data}
+	}
+
+	return obj
+}
@@ -0,0 +1,129 @@
+package document
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge", func() {
+	It("should concatenate documents in order", func() {
+		merged, parts, err := Merge([][]byte{testPDF(2), objectStreamPDF(), testPDF(1)}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]Part{{Pages: 2}, {Pages: 2}, {Pages: 1}}))
+
+		info, err := Inspect(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(5))
+	})
+
+	It("should keep page attributes inherited from the page tree", func() {
+		pdf := strings.Join([]string{
+			"%PDF-1.4",
+			"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj",
+			"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> >> endobj",
+			"3 0 obj << /Type /Page /Parent 2 0 R /Contents 5 0 R >> endobj",
+			"4 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> endobj",
+			"5 0 obj << /Length 44 >>",
+			"stream",
+			"BT /F1 12 Tf 72 720 Td (Inherited) Tj ET",
+			"endstream",
+			"endobj",
+			"trailer << /Root 1 0 R >>",
+			"%%EOF",
+		}, "\n")
+
+		merged, _, err := Merge([][]byte{[]byte(pdf)}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		f, err := parsePDF(merged)
+		Expect(err).NotTo(HaveOccurred())
+		pages := f.pages()
+		Expect(pages).To(HaveLen(1))
+
+		page := f.resolve(pages[0]).(pdfDict)
+		Expect(page["MediaBox"]).To(Equal(pdfArray{int64(0), int64(0), int64(595), int64(842)}))
+		fonts := f.resolve(page["Resources"]).(pdfDict)["Font"].(pdfDict)
+		Expect(f.resolve(fonts["F1"]).(pdfDict)["BaseFont"]).To(Equal(pdfName("Helvetica")))
+
+		contents := f.resolve(page["Contents"]).(*pdfStream)
+		Expect(string(contents.data)).To(ContainSubstring("(Inherited) Tj"))
+	})
+
+	It("should add blank pages so each document starts on a new sheet when duplex", func() {
+		merged, parts, err := Merge([][]byte{testPDF(1), testPDF(2), testPDF(3), testPDF(1)}, &MergeConfig{Duplex: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]Part{
+			{Pages: 1, BlankPages: 1},
+			{Pages: 2},
+			{Pages: 3, BlankPages: 1},
+			// The last document doesn't need padding
+			{Pages: 1},
+		}))
+
+		info, err := Inspect(merged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(9))
+	})
+
+	It("should always write the same document for the same input", func() {
+		first, _, err := Merge([][]byte{testPDF(2), objectStreamPDF()}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		second, _, err := Merge([][]byte{testPDF(2), objectStreamPDF()}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bytes.Equal(first, second)).To(BeTrue())
+	})
+
+	It("should reject invalid documents", func() {
+		_, _, err := Merge(nil, nil)
+		Expect(err).To(MatchError(ErrNoDocuments))
+
+		_, _, err = Merge([][]byte{testPDF(1), []byte("<html></html>")}, nil)
+		Expect(err).To(MatchError(ErrNotPDF))
+		Expect(err).To(MatchError(ContainSubstring("document 2")))
+
+		encrypted := append(testPDF(1), []byte("trailer << /Root 1 0 R /Encrypt 9 0 R >>\n")...)
+		_, _, err = Merge([][]byte{encrypted}, nil)
+		Expect(err).To(MatchError(ErrEncrypted))
+	})
+})
+
+var _ = Describe("RenderText", func() {
+	It("should wrap long lines and start new pages", func() {
+		text := strings.Repeat("All work and no play makes Jack a dull boy. ", 1000)
+
+		info, err := Inspect(RenderText(text, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(BeNumerically(">", 1))
+
+		for _, line := range wrapText(helvetica, 11, 468, text) {
+			Expect(helvetica.width(line, 11)).To(BeNumerically("<=", 468))
+		}
+	})
+
+	It("should break words longer than a line", func() {
+		lines := wrapText(helvetica, 11, 100, strings.Repeat("W", 50))
+		Expect(len(lines)).To(BeNumerically(">", 1))
+		Expect(strings.Join(lines, "")).To(Equal(strings.Repeat("W", 50)))
+	})
+
+	It("should escape text", func() {
+		rendered := RenderText("Total (USD): 10\\20 café €", nil)
+		Expect(string(rendered)).To(ContainSubstring("(Total \\(USD\\): 10\\\\20 caf\xe9 ?) Tj"))
+	})
+
+	It("should render templates with data", func() {
+		rendered, err := RenderTemplate("Dear {{ .Name }},", map[string]string{"Name": "Jane"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rendered)).To(ContainSubstring("(Dear Jane,) Tj"))
+
+		_, err = RenderTemplate("Dear {{ .Missing }},", map[string]string{}, nil)
+		Expect(err).To(MatchError(ErrInvalidTemplate))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		_, err = RenderTemplate("Dear {{ .Name ", nil, nil)
+		Expect(err).To(MatchError(ErrInvalidTemplate))
+	})
+})
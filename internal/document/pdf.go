@@ -0,0 +1,655 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// maxObjectStreamSize limits how much a single compressed object stream may inflate to.
+const maxObjectStreamSize = 64 << 20
+
+var (
+	// ErrNotPDF is returned for documents that aren't PDFs, such as HTML error pages.
+	ErrNotPDF = fmt.Errorf("%w: not a PDF", ErrInvalid)
+	// ErrNoPages is returned for PDFs without any pages, usually because they're truncated or corrupt.
+	ErrNoPages = fmt.Errorf("%w: PDF has no pages", ErrInvalid)
+
+	// objHeader matches the start of an indirect object, e.g. "12 0 obj".
+	objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	// trailerRoot matches the document catalog reference in a trailer or cross-reference stream.
+	trailerRoot = regexp.MustCompile(`/Root\s+(\d+)\s+(\d+)\s+R`)
+)
+
+// pdfName is a PDF name object without the leading slash, e.g. Type.
+type pdfName string
+
+// pdfRef is a reference to an indirect object, e.g. 12 0 R.
+type pdfRef struct {
+	num int
+	gen int
+}
+
+// pdfDict is a PDF dictionary.
+type pdfDict map[pdfName]any
+
+// pdfArray is a PDF array.
+type pdfArray []any
+
+// pdfString is a literal or hex PDF string.
+type pdfString []byte
+
+// pdfStream is a stream object with its still-encoded data.
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+// pdfFile is a parsed PDF. Only what's needed to inspect documents is parsed.
+type pdfFile struct {
+	objects map[int]any
+	root    pdfRef
+}
+
+// parsePDF parses the objects in a PDF.
+// Objects are found by scanning for them instead of using the cross-reference table
+// so files with broken offsets, which viewers and printers accept, can still be read.
+func parsePDF(data []byte) (*pdfFile, error) {
+	header := data[:min(len(data), 1024)]
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	f := &pdfFile{objects: map[int]any{}}
+
+	end := 0
+	for _, match := range objHeader.FindAllSubmatchIndex(data, -1) {
+		// Skip matches inside streams and strings of the previous object
+		if match[0] < end {
+			continue
+		}
+
+		num, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		p := &pdfParser{data: data, pos: match[1]}
+		obj, err := p.parseObject()
+		if err != nil {
+			continue
+		}
+
+		// Later objects replace earlier ones, as they do in incrementally updated files
+		f.objects[num] = obj
+		end = p.pos
+	}
+
+	if len(f.objects) == 0 {
+		return nil, fmt.Errorf("%w: no objects found", ErrNotPDF)
+	}
+
+	f.expandObjectStreams()
+
+	// The last trailer wins in incrementally updated files
+	roots := trailerRoot.FindAllSubmatch(data, -1)
+	if len(roots) > 0 {
+		last := roots[len(roots)-1]
+		f.root.num, _ = strconv.Atoi(string(last[1]))
+		f.root.gen, _ = strconv.Atoi(string(last[2]))
+	}
+
+	if _, ok := f.resolve(f.root).(pdfDict); !ok {
+		f.root = f.findCatalog()
+	}
+
+	return f, nil
+}
+
+// expandObjectStreams adds the objects compressed into object streams.
+func (f *pdfFile) expandObjectStreams() {
+	for _, obj := range f.objects {
+		stream, ok := obj.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+
+		data, err := f.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+
+		n, _ := f.resolve(stream.dict["N"]).(int64)
+		first, _ := f.resolve(stream.dict["First"]).(int64)
+		if first <= 0 || int(first) > len(data) {
+			continue
+		}
+
+		// The header is pairs of object numbers and offsets relative to First
+		header := &pdfParser{data: data[:first]}
+		for range n {
+			num, err := header.parseObject()
+			if err != nil {
+				break
+			}
+			offset, err := header.parseObject()
+			if err != nil {
+				break
+			}
+
+			objNum, ok1 := num.(int64)
+			objOffset, ok2 := offset.(int64)
+			if !ok1 || !ok2 || int(first+objOffset) >= len(data) {
+				continue
+			}
+
+			// Objects written directly in the file take precedence
+			if _, exists := f.objects[int(objNum)]; exists {
+				continue
+			}
+
+			p := &pdfParser{data: data, pos: int(first + objOffset)}
+			obj, err := p.parseObject()
+			if err != nil {
+				continue
+			}
+			f.objects[int(objNum)] = obj
+		}
+	}
+}
+
+// findCatalog returns the first document catalog found when the trailer doesn't point to one.
+func (f *pdfFile) findCatalog() pdfRef {
+	for num, obj := range f.objects {
+		dict, ok := obj.(pdfDict)
+		if ok && dict["Type"] == pdfName("Catalog") {
+			return pdfRef{num: num}
+		}
+	}
+
+	return pdfRef{}
+}
+
+// resolve follows references until it finds a direct object.
+func (f *pdfFile) resolve(obj any) any {
+	for range 32 {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.objects[ref.num]
+	}
+
+	return nil
+}
+
+// decodeStream returns the decoded data of a stream. Only FlateDecode is supported.
+func (f *pdfFile) decodeStream(stream *pdfStream) ([]byte, error) {
+	filters := []any{}
+	switch filter := f.resolve(stream.dict["Filter"]).(type) {
+	case nil:
+		return stream.data, nil
+	case pdfName:
+		filters = append(filters, filter)
+	case pdfArray:
+		filters = append(filters, filter...)
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		if f.resolve(filter) != pdfName("FlateDecode") {
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = io.ReadAll(io.LimitReader(r, maxObjectStreamSize))
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// pages returns the references of the document's pages in order.
+func (f *pdfFile) pages() []pdfRef {
+	catalog, ok := f.resolve(f.root).(pdfDict)
+	if !ok {
+		return nil
+	}
+
+	var pages []pdfRef
+	visited := map[int]bool{}
+
+	var walk func(node any)
+	walk = func(node any) {
+		ref, ok := node.(pdfRef)
+		if !ok || visited[ref.num] {
+			return
+		}
+		visited[ref.num] = true
+
+		dict, ok := f.resolve(ref).(pdfDict)
+		if !ok {
+			return
+		}
+
+		kids, ok := f.resolve(dict["Kids"]).(pdfArray)
+		if !ok {
+			// Leaf nodes are pages even if their type is missing
+			if dict["Type"] != pdfName("Pages") {
+				pages = append(pages, ref)
+			}
+			return
+		}
+
+		for _, kid := range kids {
+			walk(kid)
+		}
+	}
+	walk(catalog["Pages"])
+
+	return pages
+}
+
+// pdfParser parses PDF objects from data starting at pos.
+type pdfParser struct {
+	data []byte
+	pos  int
+}
+
+// errUnexpectedEnd is returned when an object is cut off by the end of the data.
+var errUnexpectedEnd = errors.New("unexpected end of PDF data")
+
+// parseObject parses the next object, including streams.
+func (p *pdfParser) parseObject() (any, error) {
+	obj, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj, nil
+	}
+
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		return dict, nil
+	}
+
+	return p.parseStreamData(dict)
+}
+
+// parseStreamData reads the data of a stream whose dictionary was just parsed.
+func (p *pdfParser) parseStreamData(dict pdfDict) (*pdfStream, error) {
+	p.pos += len("stream")
+	// The keyword is followed by CRLF or LF
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	// Use the length if it's direct and correct, otherwise look for the end of the stream
+	if length, ok := dict["Length"].(int64); ok && length >= 0 && start+int(length) <= len(p.data) {
+		rest := bytes.TrimLeft(p.data[start+int(length):], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			p.pos = start + int(length)
+			p.skipKeyword("endstream")
+			return &pdfStream{dict: dict, data: p.data[start : start+int(length)]}, nil
+		}
+	}
+
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, errUnexpectedEnd
+	}
+
+	data := p.data[start : start+end]
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+
+	p.pos = start + end
+	p.skipKeyword("endstream")
+
+	return &pdfStream{dict: dict, data: data}, nil
+}
+
+// parseValue parses the next direct object or reference.
+func (p *pdfParser) parseValue() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errUnexpectedEnd
+	}
+
+	c := p.data[p.pos]
+	switch {
+	case c == '/':
+		return p.parseName(), nil
+	case c == '<' && p.peek(1) == '<':
+		return p.parseDict()
+	case c == '<':
+		return p.parseHexString()
+	case c == '(':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '+' || c == '-' || c == '.' || isDigit(c):
+		return p.parseNumberOrRef()
+	}
+
+	keyword := p.parseKeyword()
+	switch keyword {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q at offset %d", keyword, p.pos)
+}
+
+// parseName parses a name such as /Type, decoding #xx escapes.
+func (p *pdfParser) parseName() pdfName {
+	p.pos++
+	var name []byte
+	for p.pos < len(p.data) && !isDelimiter(p.data[p.pos]) && !isSpace(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if b, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(b))
+				p.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		p.pos++
+	}
+
+	return pdfName(name)
+}
+
+// parseDict parses a dictionary such as << /Type /Page >>.
+func (p *pdfParser) parseDict() (pdfDict, error) {
+	p.pos += 2
+	dict := pdfDict{}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, errUnexpectedEnd
+		}
+
+		if p.data[p.pos] == '>' && p.peek(1) == '>' {
+			p.pos += 2
+			return dict, nil
+		}
+
+		if p.data[p.pos] != '/' {
+			return nil, fmt.Errorf("expected dictionary key at offset %d", p.pos)
+		}
+		key := p.parseName()
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+// parseArray parses an array such as [0 0 612 792].
+func (p *pdfParser) parseArray() (pdfArray, error) {
+	p.pos++
+	array := pdfArray{}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, errUnexpectedEnd
+		}
+
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+	}
+}
+
+// parseHexString parses a string such as <48656C6C6F>.
+func (p *pdfParser) parseHexString() (pdfString, error) {
+	p.pos++
+	end := bytes.IndexByte(p.data[p.pos:], '>')
+	if end < 0 {
+		return nil, errUnexpectedEnd
+	}
+
+	var digits []byte
+	for _, c := range p.data[p.pos : p.pos+end] {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	// A missing final digit is treated as 0
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	p.pos += end + 1
+
+	s := make(pdfString, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		b, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string at offset %d", p.pos)
+		}
+		s = append(s, byte(b))
+	}
+
+	return s, nil
+}
+
+// parseLiteralString parses a string such as (Hello), handling nested parentheses and escapes.
+func (p *pdfParser) parseLiteralString() (pdfString, error) {
+	p.pos++
+	var s pdfString
+	depth := 1
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, errUnexpectedEnd
+			}
+			var ok bool
+			c, ok = p.parseEscape()
+			if !ok {
+				// Escaped line breaks continue the string on the next line
+				continue
+			}
+		}
+
+		s = append(s, c)
+	}
+
+	return nil, errUnexpectedEnd
+}
+
+// parseEscape parses the escape sequence after a backslash in a literal string.
+// Returns false for escaped line breaks, which aren't part of the string.
+func (p *pdfParser) parseEscape() (byte, bool) {
+	c := p.data[p.pos]
+	p.pos++
+
+	switch c {
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case 'b':
+		return '\b', true
+	case 'f':
+		return '\f', true
+	case '\r':
+		if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+			p.pos++
+		}
+		return 0, false
+	case '\n':
+		return 0, false
+	}
+
+	if c >= '0' && c <= '7' {
+		value := int(c - '0')
+		for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+			value = value*8 + int(p.data[p.pos]-'0')
+			p.pos++
+		}
+		return byte(value), true
+	}
+
+	return c, true
+}
+
+// parseNumberOrRef parses a number, or a reference such as 12 0 R.
+func (p *pdfParser) parseNumberOrRef() (any, error) {
+	number, isInt, err := p.parseNumber()
+	if err != nil || !isInt {
+		return number, err
+	}
+
+	// Look ahead for "gen R" without consuming it unless it's a reference
+	start := p.pos
+	p.skipSpace()
+	if p.pos < len(p.data) && isDigit(p.data[p.pos]) {
+		gen, genIsInt, err := p.parseNumber()
+		if err == nil && genIsInt {
+			p.skipSpace()
+			if p.pos < len(p.data) && p.data[p.pos] == 'R' && (p.pos+1 >= len(p.data) || !isRegular(p.data[p.pos+1])) {
+				p.pos++
+				return pdfRef{num: int(number.(int64)), gen: int(gen.(int64))}, nil
+			}
+		}
+	}
+	p.pos = start
+
+	return number, nil
+}
+
+// parseNumber parses an integer or real number. Integers are int64 and reals are float64.
+func (p *pdfParser) parseNumber() (any, bool, error) {
+	start := p.pos
+	if p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+		p.pos++
+	}
+	for p.pos < len(p.data) && (isDigit(p.data[p.pos]) || p.data[p.pos] == '.') {
+		p.pos++
+	}
+
+	text := string(p.data[start:p.pos])
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, true, nil
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid number %q at offset %d", text, start)
+	}
+
+	return f, false, nil
+}
+
+// parseKeyword parses a bare keyword such as true or endobj.
+func (p *pdfParser) parseKeyword() string {
+	start := p.pos
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		p.pos++
+	}
+
+	// Always make progress so unexpected delimiters don't loop forever
+	if p.pos == start {
+		p.pos++
+	}
+
+	return string(p.data[start:p.pos])
+}
+
+// skipKeyword skips the keyword if it's next.
+func (p *pdfParser) skipKeyword(keyword string) {
+	p.skipSpace()
+	if bytes.HasPrefix(p.data[p.pos:], []byte(keyword)) {
+		p.pos += len(keyword)
+	}
+}
+
+// skipSpace skips whitespace and comments.
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the byte n bytes ahead, or 0 past the end of the data.
+func (p *pdfParser) peek(n int) byte {
+	if p.pos+n >= len(p.data) {
+		return 0
+	}
+
+	return p.data[p.pos+n]
+}
+
+// isSpace reports whether c is PDF whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+// isDelimiter reports whether c is a PDF delimiter.
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// isRegular reports whether c is a regular character that can be part of a keyword.
+func isRegular(c byte) bool {
+	return !isSpace(c) && !isDelimiter(c)
+}
+
+// isDigit reports whether c is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
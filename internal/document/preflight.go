@@ -0,0 +1,182 @@
+package document
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the largest document that's downloaded.
+	DefaultMaxSize = 25 << 20
+	// DefaultTimeout is how long a document download may take.
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	// ErrInvalid is wrapped by every error caused by the document itself rather than failing to fetch it.
+	ErrInvalid = errors.New("invalid document")
+	// ErrTooLarge is returned for documents larger than the max size.
+	ErrTooLarge = fmt.Errorf("%w: document is too large", ErrInvalid)
+	// ErrTooManyPages is returned for documents with more pages than the max pages.
+	ErrTooManyPages = fmt.Errorf("%w: document has too many pages", ErrInvalid)
+	// ErrChecksumMismatch is returned for documents that don't match their pinned SHA-256.
+	ErrChecksumMismatch = fmt.Errorf("%w: document doesn't match its SHA-256", ErrInvalid)
+	// ErrNilPreflightConfig is returned when no config is provided to NewPreflight.
+	ErrNilPreflightConfig = errors.New("preflight config cannot be nil")
+)
+
+// Info describes a document that passed preflight.
+type Info struct {
+	// SHA256 is the hex encoded SHA-256 of the document.
+	SHA256 string
+	Pages  int
+	// Size is the size of the document in bytes.
+	Size int64
+}
+
+// PreflightConfig is the configuration used to create a Preflight.
+type PreflightConfig struct {
+	// HTTPClient defaults to a client with Timeout.
+	HTTPClient *http.Client
+	// MaxSize defaults to DefaultMaxSize.
+	MaxSize int64
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxPages is the most pages a document may have. Zero allows any number of pages.
+	MaxPages int
+}
+
+// Preflight downloads and checks documents before they're ordered,
+// so broken links, error pages and unexpectedly long documents are caught before paying for them.
+type Preflight struct {
+	httpClient *http.Client
+	maxSize    int64
+	timeout    time.Duration
+	maxPages   int
+}
+
+// NewPreflight returns a new Preflight.
+func NewPreflight(c *PreflightConfig) (*Preflight, error) {
+	if c == nil {
+		return nil, ErrNilPreflightConfig
+	}
+
+	p := &Preflight{
+		maxSize:  DefaultMaxSize,
+		timeout:  DefaultTimeout,
+		maxPages: c.MaxPages,
+	}
+
+	if c.MaxSize > 0 {
+		p.maxSize = c.MaxSize
+	}
+
+	if c.Timeout > 0 {
+		p.timeout = c.Timeout
+	}
+
+	p.httpClient = c.HTTPClient
+	if p.httpClient == nil {
+		p.httpClient = &http.Client{Timeout: p.timeout}
+	}
+
+	return p, nil
+}
+
+// Fetch downloads the document at url.
+// Errors wrap ErrInvalid if the server says the document doesn't exist or it's too large.
+func (p *Preflight) Fetch(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("fetching document: unexpected status %s", resp.Status)
+		// Server errors and rate limiting may go away on their own
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	if resp.ContentLength > p.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes is more than %d", ErrTooLarge, resp.ContentLength, p.maxSize)
+	}
+
+	// Read one byte more than allowed to tell if the document was too large
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+
+	if int64(len(data)) > p.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, p.maxSize)
+	}
+
+	return data, nil
+}
+
+// Inspect verifies the document is a PDF within the limits and describes it.
+func (p *Preflight) Inspect(data []byte) (*Info, error) {
+	if int64(len(data)) > p.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes is more than %d", ErrTooLarge, len(data), p.maxSize)
+	}
+
+	info, err := Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.maxPages > 0 && info.Pages > p.maxPages {
+		return nil, fmt.Errorf("%w: %d pages is more than %d", ErrTooManyPages, info.Pages, p.maxPages)
+	}
+
+	return info, nil
+}
+
+// Inspect verifies the document is a PDF and describes it.
+func Inspect(data []byte) (*Info, error) {
+	f, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := len(f.pages())
+	if pages == 0 {
+		return nil, ErrNoPages
+	}
+
+	sum := sha256.Sum256(data)
+
+	return &Info{
+		SHA256: hex.EncodeToString(sum[:]),
+		Pages:  pages,
+		Size:   int64(len(data)),
+	}, nil
+}
+
+// Verify ensures the document has the expected hex encoded SHA-256.
+func (i *Info) Verify(sha256 string) error {
+	if !strings.EqualFold(i.SHA256, sha256) {
+		return fmt.Errorf("%w: expected %s but got %s", ErrChecksumMismatch, strings.ToLower(sha256), i.SHA256)
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testPDF returns a minimal PDF with the number of pages.
+func testPDF(pages int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", i+3))
+	}
+	fmt.Fprintf(&b, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), pages)
+
+	for i := range pages {
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>\nendobj\n", i+3)
+	}
+
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\n%%%%EOF\n", pages+3)
+
+	return b.Bytes()
+}
+
+// objectStreamPDF returns a PDF with its page tree compressed into an object stream, as newer PDFs are written.
+func objectStreamPDF() []byte {
+	objects := []string{
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	}
+
+	header := ""
+	body := ""
+	for i, obj := range objects {
+		header += fmt.Sprintf("%d %d ", i+2, len(body))
+		body += obj + "\n"
+	}
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write([]byte(header + body)) // nolint:errcheck
+	w.Close()                      // nolint:errcheck
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "5 0 obj\n<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>\nstream\n",
+		len(objects), len(header), compressed.Len())
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream\nendobj\n")
+	b.WriteString("6 0 obj\n<< /Type /XRef /Root 1 0 R /Size 7 >>\nstream\n\nendstream\nendobj\n")
+	b.WriteString("startxref\n0\n%%EOF\n")
+
+	return b.Bytes()
+}
+
+var _ = Describe("Preflight", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/document.pdf", func(w http.ResponseWriter, _ *http.Request) {
+			w.Write(testPDF(3)) // nolint:errcheck
+		})
+		mux.HandleFunc("/error.html", func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("<html><body>Something went wrong</body></html>")) // nolint:errcheck
+		})
+		mux.HandleFunc("/unavailable.pdf", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		mux.HandleFunc("/slow.pdf", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return an error for nil config", func() {
+		_, err := NewPreflight(nil)
+		Expect(err).To(MatchError(ErrNilPreflightConfig))
+	})
+
+	It("should fetch and inspect a PDF", func() {
+		preflight, err := NewPreflight(&PreflightConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := preflight.Fetch(context.Background(), server.URL+"/document.pdf")
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := preflight.Inspect(data)
+		Expect(err).NotTo(HaveOccurred())
+
+		sum := sha256.Sum256(testPDF(3))
+		Expect(info.SHA256).To(Equal(hex.EncodeToString(sum[:])))
+		Expect(info.Pages).To(Equal(3))
+		Expect(info.Size).To(Equal(int64(len(testPDF(3)))))
+	})
+
+	It("should reject documents that don't exist", func() {
+		preflight, err := NewPreflight(&PreflightConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Fetch(context.Background(), server.URL+"/missing.pdf")
+		Expect(err).To(MatchError(ErrInvalid))
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("should not reject documents when the server is unavailable", func() {
+		preflight, err := NewPreflight(&PreflightConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Fetch(context.Background(), server.URL+"/unavailable.pdf")
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(ErrInvalid))
+	})
+
+	It("should time out slow downloads", func() {
+		preflight, err := NewPreflight(&PreflightConfig{Timeout: 100 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Fetch(context.Background(), server.URL+"/slow.pdf")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(err).NotTo(MatchError(ErrInvalid))
+	})
+
+	It("should reject documents that are too large", func() {
+		preflight, err := NewPreflight(&PreflightConfig{MaxSize: 64})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Fetch(context.Background(), server.URL+"/document.pdf")
+		Expect(err).To(MatchError(ErrTooLarge))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		_, err = preflight.Inspect(testPDF(1))
+		Expect(err).To(MatchError(ErrTooLarge))
+	})
+
+	It("should reject documents that aren't PDFs", func() {
+		preflight, err := NewPreflight(&PreflightConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := preflight.Fetch(context.Background(), server.URL+"/error.html")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Inspect(data)
+		Expect(err).To(MatchError(ErrNotPDF))
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+
+	It("should reject documents with too many pages", func() {
+		preflight, err := NewPreflight(&PreflightConfig{MaxPages: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = preflight.Inspect(testPDF(3))
+		Expect(err).To(MatchError(ErrTooManyPages))
+
+		_, err = preflight.Inspect(testPDF(2))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("Inspect", func() {
+	It("should count the pages of nested page trees", func() {
+		pdf := strings.Join([]string{
+			"%PDF-1.4",
+			"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj",
+			"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 >> endobj",
+			"3 0 obj << /Type /Pages /Kids [5 0 R 6 0 R] /Count 2 >> endobj",
+			"4 0 obj << /Type /Page /Contents (a string with 7 0 obj inside) >> endobj",
+			"5 0 obj << /Type /Page >> endobj",
+			"6 0 obj << /Type /Page >> endobj",
+			"trailer << /Root 1 0 R >>",
+			"%%EOF",
+		}, "\n")
+
+		info, err := Inspect([]byte(pdf))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(3))
+	})
+
+	It("should count pages stored in object streams", func() {
+		info, err := Inspect(objectStreamPDF())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Pages).To(Equal(2))
+	})
+
+	It("should reject PDFs without pages", func() {
+		_, err := Inspect(testPDF(0))
+		Expect(err).To(MatchError(ErrNoPages))
+	})
+
+	It("should reject truncated PDFs", func() {
+		_, err := Inspect([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R"))
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+
+	It("should verify pinned documents", func() {
+		info, err := Inspect(testPDF(1))
+		Expect(err).NotTo(HaveOccurred())
+
+		sum := sha256.Sum256(testPDF(1))
+		Expect(info.Verify(strings.ToUpper(hex.EncodeToString(sum[:])))).To(Succeed())
+
+		sum = sha256.Sum256(testPDF(2))
+		err = info.Verify(hex.EncodeToString(sum[:]))
+		Expect(err).To(MatchError(ErrChecksumMismatch))
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+
+	It("should reject empty documents", func() {
+		_, err := Inspect(nil)
+		Expect(err).To(MatchError(ErrNotPDF))
+	})
+})
@@ -0,0 +1,607 @@
+package document
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+const (
+	// DefaultPreviewPages is how many pages are previewed by default.
+	DefaultPreviewPages = 2
+	// DefaultPreviewResolution is the default resolution of previews in pixels per inch.
+	DefaultPreviewResolution = 72
+
+	// previewMargin is the space in pixels around and between pages.
+	previewMargin = 16
+	// maxPreviewPageSize limits the width and height of each page in pixels so huge pages don't use up memory.
+	maxPreviewPageSize = 2000
+	// maxPreviewOperations limits how many operators are drawn for each page so huge pages don't take forever.
+	maxPreviewOperations = 1 << 20
+	// maxFormDepth limits how deeply forms drawn inside forms are followed.
+	maxFormDepth = 8
+	// curveSegments is how many lines each curve is drawn with.
+	curveSegments = 8
+)
+
+var (
+	// backgroundColor is around the pages.
+	backgroundColor = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+	// paperColor is the color of the pages.
+	paperColor = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	// imageColor stands in for images, which aren't decoded.
+	imageColor = color.RGBA{R: 0xc8, G: 0xc8, B: 0xc8, A: 0xff}
+	// windowColor outlines the envelope windows.
+	windowColor = color.RGBA{R: 0xd3, G: 0x2f, B: 0x2f, A: 0xff}
+	// windowTint shades what shows through the envelope windows.
+	windowTint = color.RGBA{R: 0xd3, G: 0x2f, B: 0x2f, A: 0x20}
+	// addressColor is the color of the addresses drawn in the windows.
+	addressColor = color.RGBA{R: 0x15, G: 0x65, B: 0xc0, A: 0xff}
+)
+
+// PreviewConfig is the configuration used to render previews.
+type PreviewConfig struct {
+	// Pages is how many pages to preview. Defaults to DefaultPreviewPages.
+	Pages int
+	// Resolution is in pixels per inch. Defaults to DefaultPreviewResolution.
+	Resolution float64
+	// Envelope is the envelope whose windows are outlined on the first page. Defaults to EnvelopeUS10.
+	Envelope Envelope
+	// From and To are drawn in the envelope's windows, for documents that don't have their own address page.
+	From []string
+	To   []string
+}
+
+// RenderPreview renders the first pages of a PDF side by side as a PNG, with the envelope's windows outlined on the first page.
+// Returns the PNG and how many pages it shows.
+//
+// Previews show the layout of a document rather than exactly what will print. Paths and text are drawn,
+// but text is drawn in a built in bitmap font instead of the document's fonts and images are drawn as gray boxes.
+func RenderPreview(data []byte, c *PreviewConfig) ([]byte, int, error) {
+	if c == nil {
+		c = &PreviewConfig{}
+	}
+
+	pages := c.Pages
+	if pages <= 0 {
+		pages = DefaultPreviewPages
+	}
+	resolution := c.Resolution
+	if resolution <= 0 {
+		resolution = DefaultPreviewResolution
+	}
+
+	layout, err := findLayout(c.Envelope)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if trailerEncrypt.Match(data) {
+		return nil, 0, ErrEncrypted
+	}
+
+	f, err := parsePDF(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	refs := f.pages()
+	if len(refs) == 0 {
+		return nil, 0, ErrNoPages
+	}
+	refs = refs[:min(pages, len(refs))]
+
+	// Lay the pages out in a row, each at the same resolution unless it's huge
+	boxes := make([]pageBox, len(refs))
+	width, height := previewMargin, 0
+	for i, ref := range refs {
+		boxes[i] = f.newPageBox(f.pageDict(ref), resolution)
+		boxes[i].left = float64(width)
+		width += int(math.Ceil(boxes[i].width*boxes[i].scale)) + previewMargin
+		height = max(height, int(math.Ceil(boxes[i].height*boxes[i].scale)))
+	}
+
+	cv := newCanvas(width, height+2*previewMargin, backgroundColor)
+	for i, ref := range refs {
+		box := boxes[i]
+		cv.fillRect(box.left, previewMargin, box.width*box.scale, box.height*box.scale, paperColor)
+
+		dict := f.pageDict(ref)
+		resources, _ := f.resolve(dict["Resources"]).(pdfDict)
+		r := &renderer{file: f, canvas: cv}
+		r.state = newGraphicsState(box.deviceMatrix())
+		r.run(f.pageContent(dict["Contents"]), resources)
+	}
+
+	drawWindows(cv, layout, boxes[0], c.From, c.To)
+
+	var b bytes.Buffer
+	err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&b, cv.img)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return b.Bytes(), len(refs), nil
+}
+
+// pageBox is where a page is drawn on the preview.
+type pageBox struct {
+	// x, y, width and height are the visible area of the page in points.
+	x, y, width, height float64
+	// rotate is how far the page is turned clockwise when it's shown, in degrees.
+	rotate int
+	// scale is how many pixels there are per point.
+	scale float64
+	// left is how far from the left of the preview the page is, in pixels.
+	left float64
+}
+
+// newPageBox returns the box of a page at the resolution.
+func (f *pdfFile) newPageBox(dict pdfDict, resolution float64) pageBox {
+	box := pageBox{width: letterWidth, height: letterHeight, scale: resolution / pointsPerInch}
+
+	// The crop box is what's shown, and defaults to the media box
+	for _, key := range []pdfName{"CropBox", "MediaBox"} {
+		rect, ok := f.resolve(dict[key]).(pdfArray)
+		if !ok || len(rect) != 4 {
+			continue
+		}
+		x0, y0, x1, y1 := f.number(rect[0]), f.number(rect[1]), f.number(rect[2]), f.number(rect[3])
+		if x1 == x0 || y1 == y0 {
+			continue
+		}
+		box.x, box.y = min(x0, x1), min(y0, y1)
+		box.width, box.height = math.Abs(x1-x0), math.Abs(y1-y0)
+		break
+	}
+
+	box.rotate = ((int(f.number(dict["Rotate"]))%360 + 360) % 360) / 90 * 90
+	if box.rotate == 90 || box.rotate == 270 {
+		box.width, box.height = box.height, box.width
+	}
+
+	box.scale = min(box.scale, maxPreviewPageSize/box.width, maxPreviewPageSize/box.height)
+
+	return box
+}
+
+// deviceMatrix returns the matrix from the page's user space to pixels on the preview.
+func (b pageBox) deviceMatrix() matrix {
+	// Turn the page around its bottom left corner, then move it back into view.
+	// The width and height are already swapped for pages turned on their side.
+	var rotate matrix
+	switch b.rotate {
+	case 90:
+		rotate = matrix{0, -1, 1, 0, 0, b.height}
+	case 180:
+		rotate = matrix{-1, 0, 0, -1, b.width, b.height}
+	case 270:
+		rotate = matrix{0, 1, -1, 0, b.width, 0}
+	default:
+		rotate = identity
+	}
+
+	return translate(-b.x, -b.y).multiply(rotate).multiply(b.shownMatrix())
+}
+
+// shownMatrix returns the matrix from points on the page as it's shown, from its bottom left corner, to pixels.
+func (b pageBox) shownMatrix() matrix {
+	return matrix{b.scale, 0, 0, -b.scale, b.left, previewMargin + b.height*b.scale}
+}
+
+// drawWindows shades and outlines the envelope's windows on the page and draws the addresses in them.
+func drawWindows(cv *canvas, layout envelopeLayout, box pageBox, from, to []string) {
+	toPixels := box.shownMatrix()
+	for _, w := range layout.windows {
+		topLeft := toPixels.apply(w.x, box.height-w.top)
+		bottomRight := toPixels.apply(w.x+w.width, box.height-w.top-w.height)
+		cv.fillRect(topLeft.x, topLeft.y, bottomRight.x-topLeft.x, bottomRight.y-topLeft.y, windowTint)
+		cv.stroke([]point{topLeft, {bottomRight.x, topLeft.y}, bottomRight, {topLeft.x, bottomRight.y}}, true, 2, windowColor)
+	}
+
+	if len(from) == 0 && len(to) == 0 {
+		return
+	}
+
+	// Addresses are drawn with the same layout as cover pages, measured from the top of the page
+	p := newPage(layout.width, layout.height)
+	drawAddresses(p, layout, from, to)
+
+	fonts := pdfDict{}
+	for i, f := range p.fonts {
+		fonts[fontName(i)] = pdfDict{"BaseFont": pdfName(f.name)}
+	}
+
+	r := &renderer{canvas: cv, color: &addressColor}
+	r.state = newGraphicsState(translate(0, box.height-layout.height).multiply(toPixels))
+	r.run(p.content.Bytes(), pdfDict{"Font": fonts})
+}
+
+// pageContent returns a page's decoded content, which can be split across several streams.
+// Streams that can't be decoded are left out.
+func (f *pdfFile) pageContent(contents any) []byte {
+	streams := pdfArray{contents}
+	if array, ok := f.resolve(contents).(pdfArray); ok {
+		streams = array
+	}
+
+	var content []byte
+	for _, obj := range streams {
+		stream, ok := f.resolve(obj).(*pdfStream)
+		if !ok {
+			continue
+		}
+
+		data, err := f.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+
+		// Operators can't span streams, so separate them
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+
+	return content
+}
+
+// graphicsState is the state saved and restored by the q and Q operators.
+type graphicsState struct {
+	ctm         matrix
+	fill        color.RGBA
+	stroke      color.RGBA
+	lineWidth   float64
+	font        *previewFont
+	fontSize    float64
+	charSpacing float64
+	wordSpacing float64
+	// horizontalScale is a fraction rather than a percentage.
+	horizontalScale float64
+	leading         float64
+	rise            float64
+	renderMode      int
+}
+
+// newGraphicsState returns the initial graphics state of a page.
+func newGraphicsState(ctm matrix) graphicsState {
+	black := color.RGBA{A: 0xff}
+	return graphicsState{ctm: ctm, fill: black, stroke: black, lineWidth: 1, horizontalScale: 1}
+}
+
+// subpath is part of a path in pixels.
+type subpath struct {
+	points []point
+	closed bool
+}
+
+// renderer draws content streams on a canvas.
+type renderer struct {
+	// file is where resources are resolved from. Resources are direct objects when nil.
+	file   *pdfFile
+	canvas *canvas
+	// color optionally draws everything in one color.
+	color *color.RGBA
+
+	state graphicsState
+	saved []graphicsState
+	path  []subpath
+	// textMatrix and lineMatrix are the text matrix and text line matrix.
+	textMatrix matrix
+	lineMatrix matrix
+	depth      int
+	operations int
+}
+
+// resolve resolves references if there's a file.
+func (r *renderer) resolve(obj any) any {
+	if r.file == nil {
+		return obj
+	}
+
+	return r.file.resolve(obj)
+}
+
+// run draws a content stream using its resources.
+func (r *renderer) run(content []byte, resources pdfDict) {
+	p := &pdfParser{data: content}
+	operands := []any{}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) || r.operations >= maxPreviewOperations {
+			return
+		}
+
+		c := p.data[p.pos]
+		if c == '/' || c == '<' || c == '(' || c == '[' || c == '+' || c == '-' || c == '.' || isDigit(c) {
+			value, err := p.parseValue()
+			if err != nil {
+				return
+			}
+			operands = append(operands, value)
+			continue
+		}
+
+		operator := p.parseKeyword()
+		switch operator {
+		case "true":
+			operands = append(operands, true)
+			continue
+		case "false":
+			operands = append(operands, false)
+			continue
+		case "null":
+			operands = append(operands, nil)
+			continue
+		case "BI":
+			// Inline images are skipped over and drawn as a box like other images
+			end := bytes.Index(p.data[p.pos:], []byte("EI"))
+			if end < 0 {
+				return
+			}
+			p.pos += end + len("EI")
+			r.fillUnitSquare(imageColor)
+		default:
+			r.operations++
+			r.do(operator, operands, resources)
+		}
+		operands = operands[:0]
+	}
+}
+
+// do runs an operator. Operators that don't change what's drawn are ignored, including clipping.
+func (r *renderer) do(operator string, operands []any, resources pdfDict) {
+	n := func(i int) float64 {
+		if i >= len(operands) {
+			return 0
+		}
+		return number(operands[i])
+	}
+
+	switch operator {
+	case "q":
+		r.saved = append(r.saved, r.state)
+	case "Q":
+		if len(r.saved) > 0 {
+			r.state = r.saved[len(r.saved)-1]
+			r.saved = r.saved[:len(r.saved)-1]
+		}
+	case "cm":
+		r.state.ctm = matrix{n(0), n(1), n(2), n(3), n(4), n(5)}.multiply(r.state.ctm)
+	case "w":
+		r.state.lineWidth = n(0)
+	case "g", "rg", "k", "sc", "scn":
+		r.state.fill = colorOf(operands)
+	case "G", "RG", "K", "SC", "SCN":
+		r.state.stroke = colorOf(operands)
+	case "cs":
+		r.state.fill = color.RGBA{A: 0xff}
+	case "CS":
+		r.state.stroke = color.RGBA{A: 0xff}
+	case "Do":
+		name, _ := lastOperand(operands).(pdfName)
+		r.drawXObject(name, resources)
+	default:
+		if !r.doPath(operator, n) {
+			r.doText(operator, operands, n, resources)
+		}
+	}
+}
+
+// doPath runs path construction and painting operators. Returns false for other operators.
+func (r *renderer) doPath(operator string, n func(int) float64) bool {
+	switch operator {
+	case "m":
+		r.path = append(r.path, subpath{points: []point{r.state.ctm.apply(n(0), n(1))}})
+	case "l":
+		r.lineTo(r.state.ctm.apply(n(0), n(1)))
+	case "c":
+		r.curveTo(r.state.ctm.apply(n(0), n(1)), r.state.ctm.apply(n(2), n(3)), r.state.ctm.apply(n(4), n(5)))
+	case "v":
+		r.curveTo(r.currentPoint(), r.state.ctm.apply(n(0), n(1)), r.state.ctm.apply(n(2), n(3)))
+	case "y":
+		end := r.state.ctm.apply(n(2), n(3))
+		r.curveTo(r.state.ctm.apply(n(0), n(1)), end, end)
+	case "h":
+		if len(r.path) > 0 {
+			r.path[len(r.path)-1].closed = true
+		}
+	case "re":
+		x, y, width, height := n(0), n(1), n(2), n(3)
+		r.path = append(r.path, subpath{closed: true, points: []point{
+			r.state.ctm.apply(x, y),
+			r.state.ctm.apply(x+width, y),
+			r.state.ctm.apply(x+width, y+height),
+			r.state.ctm.apply(x, y+height),
+		}})
+	case "f", "F", "f*":
+		r.fillPath(operator == "f*")
+		r.path = nil
+	case "S", "s":
+		r.strokePath(operator == "s")
+		r.path = nil
+	case "B", "B*", "b", "b*":
+		r.fillPath(operator == "B*" || operator == "b*")
+		r.strokePath(operator == "b" || operator == "b*")
+		r.path = nil
+	case "n":
+		r.path = nil
+	default:
+		return false
+	}
+
+	return true
+}
+
+// currentPoint returns the end of the current path.
+func (r *renderer) currentPoint() point {
+	if len(r.path) == 0 || len(r.path[len(r.path)-1].points) == 0 {
+		return r.state.ctm.apply(0, 0)
+	}
+
+	points := r.path[len(r.path)-1].points
+	return points[len(points)-1]
+}
+
+// lineTo adds a line from the current point to p.
+func (r *renderer) lineTo(p point) {
+	if len(r.path) == 0 {
+		r.path = append(r.path, subpath{points: []point{p}})
+		return
+	}
+
+	last := &r.path[len(r.path)-1]
+	last.points = append(last.points, p)
+}
+
+// curveTo adds a Bézier curve from the current point to end, drawn as lines.
+func (r *renderer) curveTo(control1, control2, end point) {
+	start := r.currentPoint()
+	for i := 1; i <= curveSegments; i++ {
+		t := float64(i) / curveSegments
+		u := 1 - t
+		r.lineTo(point{
+			x: u*u*u*start.x + 3*u*u*t*control1.x + 3*u*t*t*control2.x + t*t*t*end.x,
+			y: u*u*u*start.y + 3*u*u*t*control1.y + 3*u*t*t*control2.y + t*t*t*end.y,
+		})
+	}
+}
+
+// fillPath fills the current path.
+func (r *renderer) fillPath(evenOdd bool) {
+	polygons := make([][]point, 0, len(r.path))
+	for _, sub := range r.path {
+		if len(sub.points) > 2 {
+			polygons = append(polygons, sub.points)
+		}
+	}
+
+	r.canvas.fill(polygons, r.paint(r.state.fill), evenOdd)
+}
+
+// strokePath draws the lines of the current path, closing the last subpath first if close is set.
+func (r *renderer) strokePath(close bool) {
+	width := r.state.lineWidth * r.state.ctm.scale()
+	for i, sub := range r.path {
+		closed := sub.closed || (close && i == len(r.path)-1)
+		r.canvas.stroke(sub.points, closed, width, r.paint(r.state.stroke))
+	}
+}
+
+// fillUnitSquare fills the unit square of user space, which is where images are drawn.
+func (r *renderer) fillUnitSquare(col color.RGBA) {
+	ctm := r.state.ctm
+	r.canvas.fill([][]point{{ctm.apply(0, 0), ctm.apply(1, 0), ctm.apply(1, 1), ctm.apply(0, 1)}}, r.paint(col), false)
+}
+
+// drawXObject draws an image as a box or runs a form's content.
+func (r *renderer) drawXObject(name pdfName, resources pdfDict) {
+	xobjects, _ := r.resolve(resources["XObject"]).(pdfDict)
+	stream, ok := r.resolve(xobjects[name]).(*pdfStream)
+	if !ok {
+		return
+	}
+
+	switch r.resolve(stream.dict["Subtype"]) {
+	case pdfName("Image"):
+		col := imageColor
+		// Image masks are painted with the fill color
+		if r.resolve(stream.dict["ImageMask"]) == true {
+			col = r.state.fill
+		}
+		r.fillUnitSquare(col)
+	case pdfName("Form"):
+		if r.file == nil || r.depth >= maxFormDepth {
+			return
+		}
+
+		content, err := r.file.decodeStream(stream)
+		if err != nil {
+			return
+		}
+
+		formResources, ok := r.resolve(stream.dict["Resources"]).(pdfDict)
+		if !ok {
+			formResources = resources
+		}
+
+		saved := r.state
+		if m, ok := r.resolve(stream.dict["Matrix"]).(pdfArray); ok && len(m) == 6 {
+			r.state.ctm = matrix{number(m[0]), number(m[1]), number(m[2]), number(m[3]), number(m[4]), number(m[5])}.
+				multiply(r.state.ctm)
+		}
+		r.depth++
+		r.run(content, formResources)
+		r.depth--
+		r.state = saved
+	}
+}
+
+// paint returns the color to draw with.
+func (r *renderer) paint(col color.RGBA) color.RGBA {
+	if r.color != nil {
+		return *r.color
+	}
+
+	return col
+}
+
+// colorOf converts gray, RGB or CMYK operands to a color. Patterns and other color spaces are drawn in gray.
+func colorOf(operands []any) color.RGBA {
+	values := []float64{}
+	for _, operand := range operands {
+		switch operand.(type) {
+		case int64, float64:
+			values = append(values, min(max(number(operand), 0), 1))
+		}
+	}
+
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(v * 0xff))
+	}
+
+	switch len(values) {
+	case 1:
+		gray := channel(values[0])
+		return color.RGBA{R: gray, G: gray, B: gray, A: 0xff}
+	case 3:
+		return color.RGBA{R: channel(values[0]), G: channel(values[1]), B: channel(values[2]), A: 0xff}
+	case 4:
+		black := 1 - values[3]
+		return color.RGBA{
+			R: channel((1 - values[0]) * black),
+			G: channel((1 - values[1]) * black),
+			B: channel((1 - values[2]) * black),
+			A: 0xff,
+		}
+	}
+
+	return color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+}
+
+// lastOperand returns the last operand, or nil if there aren't any.
+func lastOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+
+	return operands[len(operands)-1]
+}
+
+// number resolves obj and returns it as a float64, or zero if it isn't a number.
+func (f *pdfFile) number(obj any) float64 {
+	return number(f.resolve(obj))
+}
+
+// number returns a PDF number as a float64, or zero for anything else.
+func number(obj any) float64 {
+	switch v := obj.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+
+	return 0
+}
@@ -0,0 +1,128 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// decodePreview decodes a rendered preview.
+func decodePreview(b []byte) image.Image {
+	img, err := png.Decode(bytes.NewReader(b))
+	Expect(err).NotTo(HaveOccurred())
+	return img
+}
+
+// pixelAt returns the color of a pixel on a page of the preview, in points from the top left of the page at 72 DPI.
+func pixelAt(img image.Image, x, y int) color.RGBA {
+	r, g, b, a := img.At(previewMargin+x, previewMargin+y).RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+}
+
+// contentPDF returns a one page PDF with the content and page attributes.
+func contentPDF(content, attributes string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	fmt.Fprintf(&b, "3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R %s >>\nendobj\n", attributes)
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+
+	return b.Bytes()
+}
+
+var _ = Describe("RenderPreview", func() {
+	black := color.RGBA{A: 0xff}
+
+	It("should draw the first pages side by side", func() {
+		rendered, pages, err := RenderPreview(testPDF(3), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal(2))
+
+		img := decodePreview(rendered)
+		Expect(img.Bounds().Dx()).To(Equal(2*612 + 3*previewMargin))
+		Expect(img.Bounds().Dy()).To(Equal(792 + 2*previewMargin))
+		Expect(pixelAt(img, 300, 700)).To(Equal(paperColor))
+		Expect(pixelAt(img, 612+previewMargin/2, 700)).To(Equal(backgroundColor))
+	})
+
+	It("should draw paths and text", func() {
+		p := newPage(letterWidth, letterHeight)
+		p.fillRect(72, 72, 144, 72)
+		p.text(helvetica, 40, 300, 100, "IIII")
+
+		rendered, _, err := RenderPreview(writePages([]*page{p}), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		img := decodePreview(rendered)
+		// PDF coordinates start at the bottom left of the page
+		Expect(pixelAt(img, 100, 700)).To(Equal(black))
+		Expect(pixelAt(img, 250, 700)).To(Equal(paperColor))
+
+		// The stem of the first I is in the middle of its width
+		stem := 300 + int(helvetica.width("I", 40)/2)
+		Expect(pixelAt(img, stem, 792-110)).To(Equal(black))
+		Expect(pixelAt(img, stem, 792-130)).To(Equal(paperColor))
+	})
+
+	It("should turn rotated pages", func() {
+		rendered, _, err := RenderPreview(contentPDF("0 0 100 50 re f", "/Rotate 90"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		img := decodePreview(rendered)
+		Expect(img.Bounds().Dx()).To(Equal(792 + 2*previewMargin))
+		// The bottom left corner is turned to the top left
+		Expect(pixelAt(img, 25, 50)).To(Equal(black))
+		Expect(pixelAt(img, 25, 150)).To(Equal(paperColor))
+	})
+
+	It("should outline the envelope windows and draw the addresses in them", func() {
+		without, _, err := RenderPreview(testPDF(1), &PreviewConfig{Pages: 1})
+		Expect(err).NotTo(HaveOccurred())
+		img := decodePreview(without)
+
+		// The recipient window of a US #10 envelope starts 0.75in from the left and 1.875in from the top
+		Expect(pixelAt(img, 54, 200)).To(Equal(windowColor))
+		Expect(pixelAt(img, 100, 200)).To(Equal(blend(paperColor, windowTint)))
+
+		with, _, err := RenderPreview(testPDF(1), &PreviewConfig{Pages: 1, To: []string{"IIIIIIIIIIIIIIIIIIII"}})
+		Expect(err).NotTo(HaveOccurred())
+		img = decodePreview(with)
+
+		addressed := 0
+		for x := 63; x < 63+150; x++ {
+			if pixelAt(img, x, 150) == addressColor {
+				addressed++
+			}
+		}
+		Expect(addressed).To(BeNumerically(">", 0))
+	})
+
+	It("should draw images as boxes and skip invisible text", func() {
+		content := "q 100 0 0 100 0 0 cm /Im1 Do Q BT 3 Tr /F1 40 Tf 300 100 Td (IIII) Tj ET"
+		image := "5 0 obj\n<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /Length 1 >>\nstream\n\x00\nendstream\nendobj\n"
+		pdf := bytes.Replace(contentPDF(content, "/Resources << /XObject << /Im1 5 0 R >> >>"),
+			[]byte("trailer"), []byte(image+"trailer"), 1)
+
+		rendered, _, err := RenderPreview(pdf, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		img := decodePreview(rendered)
+		Expect(pixelAt(img, 50, 792-50)).To(Equal(imageColor))
+		Expect(pixelAt(img, 300+int(helvetica.width("I", 40)/2), 792-110)).To(Equal(paperColor))
+	})
+
+	It("should reject documents it can't preview", func() {
+		_, _, err := RenderPreview([]byte("<html></html>"), nil)
+		Expect(err).To(MatchError(ErrNotPDF))
+
+		_, _, err = RenderPreview(testPDF(1), &PreviewConfig{Envelope: "B4"})
+		Expect(err).To(MatchError(ErrUnknownEnvelope))
+	})
+})
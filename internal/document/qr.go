@@ -0,0 +1,468 @@
+package document
+
+import (
+	"fmt"
+)
+
+// ErrQRCodeTooLong is returned for QR code contents that don't fit the largest supported QR code.
+var ErrQRCodeTooLong = fmt.Errorf("%w: too long for a QR code", ErrInvalid)
+
+// qrVersion is the layout of a QR code version at error correction level M,
+// which can be read with up to 15% of the code damaged.
+type qrVersion struct {
+	// ecCodewords is the number of error correction codewords in each block.
+	ecCodewords int
+	// blocks is the number of data codewords in each block.
+	blocks []int
+	// alignment is the row and column centers of the alignment patterns.
+	alignment []int
+	// remainderBits is the number of unused modules after the codewords.
+	remainderBits int
+}
+
+// qrVersions are versions 1 to 10, which are plenty for identifiers. Index 0 is version 1.
+var qrVersions = []qrVersion{
+	{10, []int{16}, nil, 0},
+	{16, []int{28}, []int{6, 18}, 7},
+	{26, []int{44}, []int{6, 22}, 7},
+	{18, []int{32, 32}, []int{6, 26}, 7},
+	{24, []int{43, 43}, []int{6, 30}, 7},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}, 7},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}, 0},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}, 0},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}, 0},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}, 0},
+}
+
+// qrCode is an encoded QR code. Modules are true when dark.
+type qrCode struct {
+	size    int
+	modules [][]bool
+	// function marks the modules of the finder, timing, alignment and format patterns, which aren't masked.
+	function [][]bool
+}
+
+// encodeQR encodes data in byte mode with error correction level M in the smallest version it fits.
+func encodeQR(data []byte) (*qrCode, error) {
+	for i, v := range qrVersions {
+		version := i + 1
+		capacity := 0
+		for _, block := range v.blocks {
+			capacity += block
+		}
+
+		// Byte mode has a 4 bit mode indicator and an 8 bit count until version 10 where the count is 16 bits
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > capacity*8 {
+			continue
+		}
+
+		codewords := qrDataCodewords(data, countBits, capacity)
+		q := newQRCode(version)
+		q.drawCodewords(qrInterleave(codewords, v))
+		q.applyBestMask()
+
+		return q, nil
+	}
+
+	return nil, fmt.Errorf("%w: %d bytes", ErrQRCodeTooLong, len(data))
+}
+
+// qrDataCodewords returns the data as byte mode codewords padded to capacity.
+func qrDataCodewords(data []byte, countBits, capacity int) []byte {
+	bits := &bitBuffer{}
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	// Terminate with up to four zeros and pad to a whole codeword
+	bits.append(0, min(4, capacity*8-bits.len))
+	bits.append(0, (8-bits.len%8)%8)
+
+	codewords := bits.bytes()
+	for i := 0; len(codewords) < capacity; i++ {
+		codewords = append(codewords, []byte{0xec, 0x11}[i%2])
+	}
+
+	return codewords
+}
+
+// qrInterleave splits the data codewords into blocks, adds error correction to each block
+// and interleaves the blocks so damage to one area of the code is spread across blocks.
+func qrInterleave(data []byte, v qrVersion) []byte {
+	generator := reedSolomonGenerator(v.ecCodewords)
+
+	blocks := [][]byte{}
+	ecBlocks := [][]byte{}
+	longest := 0
+	for _, length := range v.blocks {
+		block := data[:length]
+		data = data[length:]
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, generator))
+		longest = max(longest, length)
+	}
+
+	result := []byte{}
+	for i := range longest {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range v.ecCodewords {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+// newQRCode returns a QR code of the version with its function patterns drawn.
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	q := &qrCode{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range size {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := range size {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns in three corners with their separators
+	for _, corner := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				distance := max(abs(dx), abs(dy))
+				q.setFunction(x, y, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	// Alignment patterns everywhere except over the finder patterns
+	alignment := qrVersions[version-1].alignment
+	for i, cy := range alignment {
+		for j, cx := range alignment {
+			last := len(alignment) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas until the mask is chosen
+	q.drawFormat(0)
+
+	if version >= 7 {
+		q.drawVersion(version)
+	}
+
+	return q
+}
+
+// setFunction sets a function pattern module at column x and row y.
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFormat draws both copies of the format information for level M and the mask.
+func (q *qrCode) drawFormat(mask int) {
+	// Level M is 00 followed by the three mask bits, protected by a BCH code
+	data := mask
+	remainder := data
+	for range 10 {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	// The dark module is always dark
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawVersion draws both copies of the version information used from version 7.
+func (q *qrCode) drawVersion(version int) {
+	remainder := version
+	for range 12 {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1f25)
+	}
+	bits := version<<12 | remainder
+
+	for i := range 18 {
+		dark := (bits>>i)&1 != 0
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a time from the bottom right,
+// skipping the function patterns.
+func (q *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		// The vertical timing pattern is skipped entirely
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+
+		for vertical := range q.size {
+			y := vertical
+			if upward {
+				y = q.size - 1 - vertical
+			}
+
+			for j := range 2 {
+				x := right - j
+				if q.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// qrMask reports whether mask inverts the module at column x and row y.
+func qrMask(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask inverts the data modules selected by mask. Applying the same mask again removes it.
+func (q *qrCode) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			if !q.function[y][x] && qrMask(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask that makes the code easiest to read, as scored by the QR code penalty rules.
+func (q *qrCode) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		penalty := q.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask)
+	}
+
+	q.applyMask(best)
+	q.drawFormat(best)
+}
+
+// penalty scores how hard the code is to read. Lower is better.
+func (q *qrCode) penalty() int {
+	penalty := 0
+	dark := 0
+
+	for i := range q.size {
+		row := make([]bool, q.size)
+		column := make([]bool, q.size)
+		for j := range q.size {
+			row[j] = q.modules[i][j]
+			column[j] = q.modules[j][i]
+			if q.modules[i][j] {
+				dark++
+			}
+		}
+		penalty += linePenalty(row) + linePenalty(column)
+	}
+
+	// Blocks of the same color
+	for y := range q.size - 1 {
+		for x := range q.size - 1 {
+			c := q.modules[y][x]
+			if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+
+	// Imbalance between dark and light modules
+	percent := dark * 100 / (q.size * q.size)
+	penalty += abs(percent-50) / 5 * 10
+
+	return penalty
+}
+
+// qrFinderLike are sequences that look like part of a finder pattern.
+var qrFinderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores runs of the same color and finder-like patterns in a row or column.
+func linePenalty(line []bool) int {
+	penalty := 0
+
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range qrFinderLike {
+			matches := true
+			for j, c := range pattern {
+				if line[i+j] != c {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				penalty += 40
+			}
+		}
+	}
+
+	return penalty
+}
+
+// reedSolomonGenerator returns the generator polynomial for degree error correction codewords,
+// without its leading coefficient, which is always 1.
+func reedSolomonGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords for data.
+func reedSolomonRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range generator {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+
+	return result
+}
+
+// gfMultiply multiplies in the Galois field GF(2^8) used by QR codes.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11d)
+		z ^= int((y>>i)&1) * int(x)
+	}
+
+	return byte(z)
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// bitBuffer builds a sequence of bits.
+type bitBuffer struct {
+	data []byte
+	len  int
+}
+
+// append appends the lowest n bits of value, most significant first.
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if b.len%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		if (value>>i)&1 != 0 {
+			b.data[b.len/8] |= 0x80 >> (b.len % 8)
+		}
+		b.len++
+	}
+}
+
+// bytes returns the bits as bytes. The last byte is padded with zeros.
+func (b *bitBuffer) bytes() []byte {
+	return b.data
+}
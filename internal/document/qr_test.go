@@ -0,0 +1,195 @@
+package document
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// readQR reads the data back out of a QR code, checking its format and error correction along the way.
+func readQR(q *qrCode) []byte {
+	version := (q.size - 17) / 4
+	v := qrVersions[version-1]
+
+	// Read the first copy of the format information and check it against the second
+	format := 0
+	formatBit := func(x, y, i int) {
+		if q.modules[y][x] {
+			format |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		formatBit(8, i, i)
+	}
+	formatBit(8, 7, 6)
+	formatBit(8, 8, 7)
+	formatBit(7, 8, 8)
+	for i := 9; i < 15; i++ {
+		formatBit(14-i, 8, i)
+	}
+	first := format
+
+	format = 0
+	for i := range 8 {
+		formatBit(q.size-1-i, 8, i)
+	}
+	for i := 8; i < 15; i++ {
+		formatBit(8, q.size-15+i, i)
+	}
+	Expect(format).To(Equal(first))
+
+	format ^= 0x5412
+	Expect(format>>13).To(Equal(0), "error correction level should be M")
+	mask := (format >> 10) & 0b111
+
+	// Remove the mask and read the codewords in the zigzag order
+	q.applyMask(mask)
+	defer q.applyMask(mask)
+
+	total := len(v.blocks) * v.ecCodewords
+	for _, block := range v.blocks {
+		total += block
+	}
+	codewords := make([]byte, total)
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := range q.size {
+			y := vertical
+			if (right+1)&2 == 0 {
+				y = q.size - 1 - vertical
+			}
+			for j := range 2 {
+				x := right - j
+				if q.function[y][x] || i >= total*8 {
+					continue
+				}
+				if q.modules[y][x] {
+					codewords[i/8] |= 0x80 >> (i % 8)
+				}
+				i++
+			}
+		}
+	}
+	Expect(i).To(Equal(total * 8))
+
+	// Undo the interleaving and check each block's error correction
+	blocks := make([][]byte, len(v.blocks))
+	ecBlocks := make([][]byte, len(v.blocks))
+	longest := v.blocks[len(v.blocks)-1]
+	for column := range longest {
+		for b, length := range v.blocks {
+			if column < length {
+				blocks[b] = append(blocks[b], codewords[0])
+				codewords = codewords[1:]
+			}
+		}
+	}
+	for range v.ecCodewords {
+		for b := range v.blocks {
+			ecBlocks[b] = append(ecBlocks[b], codewords[0])
+			codewords = codewords[1:]
+		}
+	}
+
+	data := []byte{}
+	generator := reedSolomonGenerator(v.ecCodewords)
+	for b, block := range blocks {
+		Expect(reedSolomonRemainder(block, generator)).To(Equal(ecBlocks[b]))
+		data = append(data, block...)
+	}
+
+	// Byte mode, then the count and the bytes
+	Expect(data[0] >> 4).To(Equal(byte(0b0100)))
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	bitAt := func(i int) int { return int(data[i/8]>>(7-i%8)) & 1 }
+	read := func(offset, n int) int {
+		value := 0
+		for i := range n {
+			value = value<<1 | bitAt(offset+i)
+		}
+		return value
+	}
+	count := read(4, countBits)
+	result := make([]byte, count)
+	for i := range count {
+		result[i] = byte(read(4+countBits+8*i, 8))
+	}
+
+	return result
+}
+
+var _ = Describe("QR codes", func() {
+	It("should encode data that can be read back", func() {
+		for _, data := range []string{
+			"a",
+			"d9b2d63d-a233-4123-847a-8c2b0c5cba1a",
+			"d9b2d63d-a233-4123-847a-8c2b0c5cba1a\ninvoice-2024-000123",
+			strings.Repeat("x", 100),
+			strings.Repeat("y", 213),
+		} {
+			q, err := encodeQR([]byte(data))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(readQR(q))).To(Equal(data))
+		}
+	})
+
+	It("should compute error correction like the QR code specification's example", func() {
+		// The data codewords of 01234567 at version 1-M
+		data := []byte{16, 32, 12, 86, 97, 128, 236, 17, 236, 17, 236, 17, 236, 17, 236, 17}
+		Expect(reedSolomonRemainder(data, reedSolomonGenerator(10))).To(Equal(
+			[]byte{165, 36, 212, 193, 237, 54, 199, 135, 44, 85},
+		))
+	})
+
+	It("should use the smallest version the data fits in", func() {
+		q, err := encodeQR([]byte(strings.Repeat("x", 14)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q.size).To(Equal(21))
+
+		q, err = encodeQR([]byte(strings.Repeat("x", 15)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q.size).To(Equal(25))
+	})
+
+	It("should draw the finder patterns in three corners", func() {
+		q, err := encodeQR([]byte("finder"))
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, corner := range [][2]int{{0, 0}, {q.size - 7, 0}, {0, q.size - 7}} {
+			for i := range 7 {
+				// The outer ring is dark and the ring inside it is light
+				Expect(q.modules[corner[1]][corner[0]+i]).To(BeTrue())
+				Expect(q.modules[corner[1]+6][corner[0]+i]).To(BeTrue())
+			}
+			Expect(q.modules[corner[1]+1][corner[0]+1]).To(BeFalse())
+			Expect(q.modules[corner[1]+3][corner[0]+3]).To(BeTrue())
+		}
+	})
+
+	It("should encode the version in large codes", func() {
+		q, err := encodeQR([]byte(strings.Repeat("x", 110)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q.size).To(Equal(7*4 + 17))
+
+		// Version 7 is 000111 followed by its BCH code 110010010100
+		bits := 0
+		for i := range 18 {
+			if q.modules[i/3][q.size-11+i%3] {
+				bits |= 1 << i
+			}
+		}
+		Expect(bits).To(Equal(0b000111110010010100))
+	})
+
+	It("should reject data that is too long", func() {
+		_, err := encodeQR([]byte(strings.Repeat("x", 214)))
+		Expect(err).To(MatchError(ErrQRCodeTooLong))
+	})
+})
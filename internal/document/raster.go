@@ -0,0 +1,192 @@
+package document
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+)
+
+// matrix is a PDF transformation matrix [a b c d e f], mapping x, y to ax+cy+e, bx+dy+f.
+type matrix [6]float64
+
+// identity is the matrix that leaves points where they are.
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// translate returns a matrix that moves points by x, y.
+func translate(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// multiply returns the matrix that applies m and then n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// apply returns x, y transformed by m.
+func (m matrix) apply(x, y float64) point {
+	return point{x: m[0]*x + m[2]*y + m[4], y: m[1]*x + m[3]*y + m[5]}
+}
+
+// scale returns how much m scales lengths on average.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// point is a position in device space, in pixels from the top left of the canvas.
+type point struct {
+	x, y float64
+}
+
+// canvas is an image that polygons are filled on.
+type canvas struct {
+	img *image.RGBA
+}
+
+// newCanvas returns a canvas of the size in pixels filled with background.
+func newCanvas(width, height int, background color.RGBA) *canvas {
+	c := &canvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	c.fillRect(0, 0, float64(width), float64(height), background)
+
+	return c
+}
+
+// fillRect fills the rectangle with its top left corner at x, y.
+func (c *canvas) fillRect(x, y, width, height float64, col color.RGBA) {
+	c.fill([][]point{{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}}}, col, false)
+}
+
+// crossing is where an edge of a polygon crosses a row of pixels.
+type crossing struct {
+	x float64
+	// winding is 1 for edges going down and -1 for edges going up.
+	winding int
+}
+
+// fill fills the polygons, which are closed implicitly, using the nonzero or even-odd rule.
+// Pixels are filled when their centers are inside, so there's no anti-aliasing.
+func (c *canvas) fill(polygons [][]point, col color.RGBA, evenOdd bool) {
+	bounds := c.img.Bounds()
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			// Broken matrices can put points at infinity, which can't be drawn
+			if !finite(p.x) || !finite(p.y) {
+				return
+			}
+			top, bottom = min(top, p.y), max(bottom, p.y)
+		}
+	}
+	if math.IsInf(top, 0) {
+		return
+	}
+
+	// Clamp before converting so huge coordinates don't overflow
+	firstRow := int(math.Floor(max(top, float64(bounds.Min.Y))))
+	lastRow := int(math.Ceil(min(bottom, float64(bounds.Max.Y-1))))
+
+	crossings := []crossing{}
+	for row := firstRow; row <= lastRow; row++ {
+		y := float64(row) + 0.5
+		crossings = crossings[:0]
+
+		for _, polygon := range polygons {
+			for i, from := range polygon {
+				to := polygon[(i+1)%len(polygon)]
+				switch {
+				case from.y <= y && to.y > y:
+					crossings = append(crossings, crossing{x: intersect(from, to, y), winding: 1})
+				case to.y <= y && from.y > y:
+					crossings = append(crossings, crossing{x: intersect(from, to, y), winding: -1})
+				}
+			}
+		}
+
+		slices.SortFunc(crossings, func(a, b crossing) int {
+			return cmp.Compare(a.x, b.x)
+		})
+
+		winding := 0
+		for i := 0; i < len(crossings)-1; i++ {
+			winding += crossings[i].winding
+			inside := winding != 0
+			if evenOdd {
+				inside = winding%2 != 0
+			}
+			if inside {
+				c.fillSpan(row, crossings[i].x, crossings[i+1].x, col)
+			}
+		}
+	}
+}
+
+// fillSpan fills the pixels in row whose centers are between x0 and x1.
+func (c *canvas) fillSpan(row int, x0, x1 float64, col color.RGBA) {
+	bounds := c.img.Bounds()
+	first := int(math.Ceil(max(x0-0.5, float64(bounds.Min.X))))
+	last := int(math.Ceil(min(x1-0.5, float64(bounds.Max.X)))) - 1
+
+	for x := first; x <= last; x++ {
+		if col.A == 0xff {
+			c.img.SetRGBA(x, row, col)
+			continue
+		}
+		c.img.SetRGBA(x, row, blend(c.img.RGBAAt(x, row), col))
+	}
+}
+
+// stroke draws lines between the points, closing the line if closed.
+// Lines are at least a pixel wide so hairlines don't disappear.
+func (c *canvas) stroke(points []point, closed bool, width float64, col color.RGBA) {
+	width = max(width, 1)
+
+	segments := len(points) - 1
+	if closed {
+		segments = len(points)
+	}
+
+	for i := range segments {
+		from, to := points[i], points[(i+1)%len(points)]
+		length := math.Hypot(to.x-from.x, to.y-from.y)
+		if length == 0 {
+			continue
+		}
+
+		// Offset both ends by half the width perpendicular to the line, extended to cover the joins
+		dx, dy := (to.x-from.x)/length*width/2, (to.y-from.y)/length*width/2
+		c.fill([][]point{{
+			{from.x - dx + dy, from.y - dy - dx},
+			{to.x + dx + dy, to.y + dy - dx},
+			{to.x + dx - dy, to.y + dy + dx},
+			{from.x - dx - dy, from.y - dy + dx},
+		}}, col, false)
+	}
+}
+
+// intersect returns the x where the line from a to b crosses y.
+func intersect(a, b point, y float64) float64 {
+	return a.x + (y-a.y)*(b.x-a.x)/(b.y-a.y)
+}
+
+// finite reports whether f is a number other than infinity.
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// blend draws col over dst using col's alpha.
+func blend(dst, col color.RGBA) color.RGBA {
+	alpha := uint32(col.A)
+	mix := func(d, s uint8) uint8 {
+		return uint8((uint32(d)*(0xff-alpha) + uint32(s)*alpha) / 0xff)
+	}
+
+	return color.RGBA{R: mix(dst.R, col.R), G: mix(dst.G, col.G), B: mix(dst.B, col.B), A: 0xff}
+}
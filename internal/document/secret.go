@@ -0,0 +1,26 @@
+package document
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadSecret returns the document stored under the selected key of a Secret.
+func ReadSecret(ctx context.Context, reader client.Reader, namespace string, selector *corev1.SecretKeySelector) ([]byte, error) {
+	secret := &corev1.Secret{}
+
+	err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: selector.Name}, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := secret.Data[selector.Key]; ok {
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("key %q not found in secret %s/%s", selector.Key, namespace, selector.Name)
+}
@@ -0,0 +1,272 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+const (
+	// letterWidth and letterHeight are the size of a US Letter page in points.
+	letterWidth  = 612
+	letterHeight = 792
+)
+
+// ErrInvalidTemplate is returned for templates that can't be parsed or executed.
+var ErrInvalidTemplate = fmt.Errorf("%w: invalid template", ErrInvalid)
+
+// font is one of the standard PDF fonts, which every PDF reader has so they don't need to be embedded.
+type font struct {
+	// name is the PostScript name of the font, e.g. Helvetica.
+	name string
+	// widths are the widths of the printable ASCII characters in thousandths of the font size.
+	widths [95]int
+	// defaultWidth is used for characters outside of printable ASCII.
+	defaultWidth int
+}
+
+// helvetica is the font text is rendered in.
+var helvetica = &font{
+	name: "Helvetica",
+	widths: [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+	},
+	defaultWidth: 556,
+}
+
+// width returns the width of s in points at size.
+func (f *font) width(s string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += f.widths[r-' ']
+			continue
+		}
+		total += f.defaultWidth
+	}
+
+	return float64(total) * size / 1000
+}
+
+// page is a page being drawn with the standard fonts.
+type page struct {
+	width, height float64
+	content       bytes.Buffer
+	// fonts are the fonts used on the page. Font n has the resource name Fn+1.
+	fonts []*font
+}
+
+// newPage returns a blank page of the size in points.
+func newPage(width, height float64) *page {
+	return &page{width: width, height: height}
+}
+
+// text draws s with its baseline starting at x, y.
+func (p *page) text(f *font, size, x, y float64, s string) {
+	resource := p.fontResource(f)
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td ", resource, formatNumber(size), formatNumber(x), formatNumber(y))
+	writeString(&p.content, pdfString(encodeWinAnsi(s)))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// fillRect draws a black rectangle with its bottom left corner at x, y.
+func (p *page) fillRect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", formatNumber(x), formatNumber(y), formatNumber(width), formatNumber(height))
+}
+
+// fontResource returns the resource name of the font on the page, adding it if it's new.
+func (p *page) fontResource(f *font) string {
+	for i, used := range p.fonts {
+		if used == f {
+			return string(fontName(i))
+		}
+	}
+
+	p.fonts = append(p.fonts, f)
+	return string(fontName(len(p.fonts) - 1))
+}
+
+// fontName returns the resource name of the page's font at index i.
+func fontName(i int) pdfName {
+	return pdfName(fmt.Sprintf("F%d", i+1))
+}
+
+// formatNumber formats a number of points without unneeded decimals.
+func formatNumber(n float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", n), "0"), ".")
+}
+
+// winAnsiPunctuation are the typographic punctuation characters word processors substitute,
+// which WinAnsiEncoding has outside of Latin-1.
+var winAnsiPunctuation = map[rune]byte{
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '…': 0x85,
+}
+
+// encodeWinAnsi encodes s for the standard fonts.
+// Latin-1 characters and typographic punctuation are kept and anything else is replaced.
+func encodeWinAnsi(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		code, punctuation := winAnsiPunctuation[r]
+		switch {
+		case r == utf8.RuneError, r < ' ':
+			b = append(b, '?')
+		case r <= '~', r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		case punctuation:
+			b = append(b, code)
+		default:
+			b = append(b, '?')
+		}
+	}
+
+	return b
+}
+
+// writePages writes pages drawn with the standard fonts as a PDF.
+func writePages(pages []*page) []byte {
+	w := &pdfWriter{}
+	pagesRef := w.reserve()
+	fonts := map[*font]pdfRef{}
+
+	kids := pdfArray{}
+	for _, p := range pages {
+		resources := pdfDict{}
+		for i, f := range p.fonts {
+			ref, ok := fonts[f]
+			if !ok {
+				ref = w.add(pdfDict{
+					"Type":     pdfName("Font"),
+					"Subtype":  pdfName("Type1"),
+					"BaseFont": pdfName(f.name),
+					"Encoding": pdfName("WinAnsiEncoding"),
+				})
+				fonts[f] = ref
+			}
+			resources[fontName(i)] = ref
+		}
+
+		contents := w.add(&pdfStream{dict: pdfDict{}, data: p.content.Bytes()})
+		kids = append(kids, w.add(pdfDict{
+			"Type":      pdfName("Page"),
+			"Parent":    pagesRef,
+			"MediaBox":  pdfArray{int64(0), int64(0), p.width, p.height},
+			"Resources": pdfDict{"Font": resources},
+			"Contents":  contents,
+		}))
+	}
+
+	w.set(pagesRef, pdfDict{
+		"Type":  pdfName("Pages"),
+		"Kids":  kids,
+		"Count": int64(len(kids)),
+	})
+	root := w.add(pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": pagesRef,
+	})
+
+	return w.bytes(root)
+}
+
+// TextConfig is the layout of rendered text.
+type TextConfig struct {
+	// FontSize defaults to 11 points.
+	FontSize float64
+	// Margin defaults to 72 points, one inch, on every side.
+	Margin float64
+}
+
+// RenderText renders plain text onto US Letter pages, wrapping long lines and starting new pages as needed.
+func RenderText(text string, c *TextConfig) []byte {
+	size, margin := 11.0, 72.0
+	if c != nil && c.FontSize > 0 {
+		size = c.FontSize
+	}
+	if c != nil && c.Margin > 0 {
+		margin = c.Margin
+	}
+
+	leading := size * 1.3
+	maxWidth := letterWidth - 2*margin
+
+	pages := []*page{}
+	var current *page
+	y := 0.0
+	for _, line := range wrapText(helvetica, size, maxWidth, text) {
+		if current == nil || y < margin {
+			current = newPage(letterWidth, letterHeight)
+			pages = append(pages, current)
+			y = letterHeight - margin - size
+		}
+		if line != "" {
+			current.text(helvetica, size, margin, y, line)
+		}
+		y -= leading
+	}
+
+	return writePages(pages)
+}
+
+// wrapText splits text into lines no wider than maxWidth, breaking between words where possible.
+func wrapText(f *font, size, maxWidth float64, text string) []string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\t", "    ")
+
+	lines := []string{}
+	for _, paragraph := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+
+			if f.width(candidate, size) <= maxWidth {
+				line = candidate
+				continue
+			}
+
+			if line != "" {
+				lines = append(lines, line)
+			}
+
+			// Words wider than the line are broken wherever they run out of space
+			runes := []rune(word)
+			for f.width(string(runes), size) > maxWidth {
+				cut := 1
+				for cut < len(runes) && f.width(string(runes[:cut+1]), size) <= maxWidth {
+					cut++
+				}
+				lines = append(lines, string(runes[:cut]))
+				runes = runes[cut:]
+			}
+			line = string(runes)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// RenderTemplate executes a Go template with data and renders the result as plain text.
+func RenderTemplate(text string, data any, c *TextConfig) ([]byte, error) {
+	tmpl, err := template.New("document").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	return RenderText(b.String(), c), nil
+}
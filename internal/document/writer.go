@@ -0,0 +1,151 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// pdfWriter collects objects and writes them out as a new PDF.
+type pdfWriter struct {
+	// objects holds object n at index n-1
+	objects []any
+}
+
+// reserve allocates an object number to be set later, so objects can refer to each other.
+func (w *pdfWriter) reserve() pdfRef {
+	w.objects = append(w.objects, nil)
+	return pdfRef{num: len(w.objects)}
+}
+
+// set sets the object for a reserved reference.
+func (w *pdfWriter) set(ref pdfRef, obj any) {
+	w.objects[ref.num-1] = obj
+}
+
+// add adds an object and returns its reference.
+func (w *pdfWriter) add(obj any) pdfRef {
+	ref := w.reserve()
+	w.set(ref, obj)
+	return ref
+}
+
+// bytes writes the PDF with root as its document catalog.
+func (w *pdfWriter) bytes(root pdfRef) []byte {
+	var b bytes.Buffer
+	// The binary comment tells transfer programs the file isn't text
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(w.objects))
+	for i, obj := range w.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		writeObject(&b, obj)
+		b.WriteString("\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objects)+1, root.num, xref)
+
+	return b.Bytes()
+}
+
+// writeObject writes the PDF syntax for obj.
+func writeObject(b *bytes.Buffer, obj any) {
+	switch v := obj.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case pdfName:
+		writeName(b, v)
+	case pdfString:
+		writeString(b, v)
+	case pdfRef:
+		fmt.Fprintf(b, "%d %d R", v.num, v.gen)
+	case pdfArray:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeObject(b, item)
+		}
+		b.WriteByte(']')
+	case pdfDict:
+		writeDict(b, v)
+	case *pdfStream:
+		dict := pdfDict{}
+		for key, value := range v.dict {
+			dict[key] = value
+		}
+		dict["Length"] = int64(len(v.data))
+		writeDict(b, dict)
+		b.WriteString("\nstream\n")
+		b.Write(v.data)
+		b.WriteString("\nendstream")
+	default:
+		// Only the types produced by the parser and page builders are written
+		b.WriteString("null")
+	}
+}
+
+// writeDict writes a dictionary with its keys sorted so the same document is always written the same way.
+func writeDict(b *bytes.Buffer, dict pdfDict) {
+	keys := make([]pdfName, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	b.WriteString("<<")
+	for _, key := range keys {
+		b.WriteByte(' ')
+		writeName(b, key)
+		b.WriteByte(' ')
+		writeObject(b, dict[key])
+	}
+	b.WriteString(" >>")
+}
+
+// writeName writes a name, escaping characters that can't appear in it as #xx.
+func writeName(b *bytes.Buffer, name pdfName) {
+	b.WriteByte('/')
+	for _, c := range []byte(name) {
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(b, "#%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+}
+
+// writeString writes a literal string, escaping parentheses, backslashes and line breaks.
+func writeString(b *bytes.Buffer, s pdfString) {
+	b.WriteByte('(')
+	for _, c := range []byte(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+}
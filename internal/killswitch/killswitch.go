@@ -0,0 +1,103 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultName is the default name of the kill switch ConfigMap.
+	DefaultName = "postk8s-kill-switch"
+	// HaltedKey is the ConfigMap key that halts new orders while set to true.
+	HaltedKey = "halted"
+	// ReasonKey is the ConfigMap key that explains why new orders are halted.
+	ReasonKey = "reason"
+	// defaultReason is used when no reason is given.
+	defaultReason = "New orders are halted by the kill switch"
+)
+
+var (
+	// ErrNilConfig is returned when no config is provided to New.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrNilClient is returned when no kubernetes client is provided to New.
+	ErrNilClient = errors.New("client cannot be nil")
+	// ErrEmptyNamespace is returned when no namespace is provided to New.
+	ErrEmptyNamespace = errors.New("namespace cannot be empty")
+)
+
+// Config is the configuration used to create a Switch.
+type Config struct {
+	// Client should be backed by the manager's cache so the ConfigMap is watched instead of fetched every time.
+	Client    client.Reader
+	Namespace string
+	// Name defaults to DefaultName.
+	Name string
+}
+
+// Switch halts new orders across the cluster while its ConfigMap sets halted to true.
+// A missing ConfigMap means orders aren't halted.
+type Switch struct {
+	client client.Reader
+	key    types.NamespacedName
+}
+
+// New returns a new kill Switch.
+func New(c *Config) (*Switch, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Client == nil {
+		return nil, ErrNilClient
+	}
+
+	if c.Namespace == "" {
+		return nil, ErrEmptyNamespace
+	}
+
+	name := DefaultName
+	if c.Name != "" {
+		name = c.Name
+	}
+
+	return &Switch{
+		client: c.Client,
+		key:    types.NamespacedName{Namespace: c.Namespace, Name: name},
+	}, nil
+}
+
+// Halted reports whether new orders are halted and why.
+func (s *Switch) Halted(ctx context.Context) (bool, string, error) {
+	configMap := &corev1.ConfigMap{}
+
+	err := s.client.Get(ctx, s.key, configMap)
+	if apierrors.IsNotFound(err) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	halted, _ := strconv.ParseBool(configMap.Data[HaltedKey])
+	if !halted {
+		return false, "", nil
+	}
+
+	reason := configMap.Data[ReasonKey]
+	if reason == "" {
+		reason = defaultReason
+	}
+
+	return true, reason, nil
+}
+
+// Key returns the namespace and name of the kill switch ConfigMap.
+func (s *Switch) Key() types.NamespacedName {
+	return s.key
+}
@@ -0,0 +1,82 @@
+package killswitch
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newConfigMap returns a kill switch ConfigMap with the data.
+func newConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "postk8s-system", Name: DefaultName},
+		Data:       data,
+	}
+}
+
+var _ = Describe("Switch", func() {
+	ctx := context.Background()
+
+	It("should return errors for invalid config", func() {
+		_, err := New(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = New(&Config{Namespace: "postk8s-system"})
+		Expect(err).To(MatchError(ErrNilClient))
+
+		_, err = New(&Config{Client: fake.NewClientBuilder().Build()})
+		Expect(err).To(MatchError(ErrEmptyNamespace))
+	})
+
+	It("should use the default name", func() {
+		s, err := New(&Config{Client: fake.NewClientBuilder().Build(), Namespace: "postk8s-system"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Key()).To(Equal(types.NamespacedName{Namespace: "postk8s-system", Name: DefaultName}))
+	})
+
+	It("should not halt orders without a configmap", func() {
+		s, err := New(&Config{Client: fake.NewClientBuilder().Build(), Namespace: "postk8s-system"})
+		Expect(err).NotTo(HaveOccurred())
+
+		halted, reason, err := s.Halted(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(halted).To(BeFalse())
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("should not halt orders unless halted is true", func() {
+		configMap := newConfigMap(map[string]string{HaltedKey: "false", ReasonKey: "drill"})
+		s, err := New(&Config{Client: fake.NewClientBuilder().WithObjects(configMap).Build(), Namespace: "postk8s-system"})
+		Expect(err).NotTo(HaveOccurred())
+
+		halted, _, err := s.Halted(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(halted).To(BeFalse())
+	})
+
+	It("should halt orders with the reason", func() {
+		configMap := newConfigMap(map[string]string{HaltedKey: "true", ReasonKey: "bad template"})
+		s, err := New(&Config{Client: fake.NewClientBuilder().WithObjects(configMap).Build(), Namespace: "postk8s-system"})
+		Expect(err).NotTo(HaveOccurred())
+
+		halted, reason, err := s.Halted(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(halted).To(BeTrue())
+		Expect(reason).To(Equal("bad template"))
+	})
+
+	It("should halt orders with a default reason", func() {
+		configMap := newConfigMap(map[string]string{HaltedKey: "true"})
+		s, err := New(&Config{Client: fake.NewClientBuilder().WithObjects(configMap).Build(), Namespace: "postk8s-system"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, reason, err := s.Halted(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal(defaultReason))
+	})
+})
@@ -0,0 +1,171 @@
+// Package orderinput builds and validates Mailform orders from Mails.
+// It is shared by the controller, which creates the orders, and kubectl-mail, which validates Mails before creating them.
+package orderinput
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	mailform "github.com/circa10a/go-mailform"
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/provider"
+)
+
+// mailReferencePrefix starts the customer reference of orders created for Mail without their own reference, so the
+// orders postk8s created can be told apart from orders placed from the Mailform dashboard or by other systems.
+const mailReferencePrefix = "postk8s:"
+
+// Build builds the external API order input from the Mail spec.
+func Build(mail *mailformv1alpha1.Mail) mailform.OrderInput {
+	to, from := mail.Spec.To, mail.Spec.From
+	if to == nil {
+		to = &mailformv1alpha1.Address{}
+	}
+	if from == nil {
+		from = &mailformv1alpha1.Address{}
+	}
+
+	orderInput := mailform.OrderInput{
+		FilePath:          mail.Spec.FilePath,
+		URL:               mail.Spec.URL,
+		CustomerReference: CustomerReference(mail),
+		Service:           string(mail.Spec.Service),
+		Webhook:           mail.Spec.Webhook,
+		Company:           mail.Spec.Company,
+		Simplex:           mail.Spec.Simplex,
+		Color:             mail.Spec.Color,
+		Flat:              mail.Spec.Flat,
+		Stamp:             mail.Spec.Stamp,
+		Message:           mail.Spec.Message,
+		ToName:            to.Name,
+		ToOrganization:    to.Organization,
+		ToAddress1:        to.Address1,
+		ToAddress2:        to.Address2,
+		ToCity:            to.City,
+		ToState:           to.State,
+		ToPostcode:        to.Postcode,
+		ToCountry:         to.Country,
+		FromName:          from.Name,
+		FromOrganization:  from.Organization,
+		FromAddress1:      from.Address1,
+		FromAddress2:      from.Address2,
+		FromCity:          from.City,
+		FromState:         from.State,
+		FromPostcode:      from.Postcode,
+		FromCountry:       from.Country,
+	}
+
+	if check := mail.Spec.Check; check != nil {
+		orderInput.BankAccount = check.BankAccount
+		orderInput.Amount = int(check.AmountCents)
+		orderInput.CheckName = check.Name
+		orderInput.CheckNumber = int(check.Number)
+		orderInput.CheckMemo = check.Memo
+	}
+
+	// Documents stored in ConfigMaps, pinned and built documents are written to this path when the order is created
+	if mail.Spec.ConfigMapRef != nil || mail.Spec.DocumentSHA256 != "" || BuildsDocument(mail) {
+		orderInput.FilePath = DocumentPath(mail)
+		orderInput.URL = ""
+	}
+
+	return orderInput
+}
+
+// CustomerReference returns the customer reference of the mail's order.
+// Mail without their own reference get one naming the mail's UID, once it has one.
+func CustomerReference(mail *mailformv1alpha1.Mail) string {
+	if mail.Spec.CustomerReference != "" || mail.UID == "" {
+		return mail.Spec.CustomerReference
+	}
+
+	return mailReferencePrefix + string(mail.UID)
+}
+
+// IsMailReference reports whether an order's customer reference is one CustomerReference made for a Mail,
+// i.e. the order was created by postk8s.
+func IsMailReference(reference string) bool {
+	return strings.HasPrefix(reference, mailReferencePrefix)
+}
+
+// DocumentPath is the local path a ConfigMap, pinned or built document is written to before uploading it.
+func DocumentPath(mail *mailformv1alpha1.Mail) string {
+	return filepath.Join(os.TempDir(), "postk8s", string(mail.UID)+".pdf")
+}
+
+// Validate ensures a single document source is set, that the order input is valid and that the service
+// delivers to the recipient's country.
+func Validate(mail *mailformv1alpha1.Mail, orderInput *mailform.OrderInput) error {
+	if mail.Spec.ConfigMapRef != nil && (mail.Spec.FilePath != "" || mail.Spec.URL != "") {
+		return errors.New("configMapRef cannot be provided with filePath or url; only one may be specified")
+	}
+
+	if len(mail.Spec.Documents) > 0 && (mail.Spec.ConfigMapRef != nil || mail.Spec.FilePath != "" || mail.Spec.URL != "") {
+		return errors.New("documents cannot be provided with configMapRef, filePath or url; only one may be specified")
+	}
+
+	otherSource := len(mail.Spec.Documents) > 0 || mail.Spec.ConfigMapRef != nil || mail.Spec.FilePath != "" || mail.Spec.URL != ""
+	if mail.Spec.Body != nil && otherSource {
+		return errors.New("body cannot be provided with documents, configMapRef, filePath or url; only one may be specified")
+	}
+
+	err := orderInput.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = provider.MailformServices.CheckDestination(orderInput.Service, orderInput.ToCountry)
+	if err != nil {
+		return err
+	}
+
+	return validateCheck(orderInput)
+}
+
+// validateCheck ensures the check fields of the order input are complete when a check is included.
+// Errors name the OrderInput fields the same way the order input's own validation does.
+func validateCheck(orderInput *mailform.OrderInput) error {
+	if orderInput.BankAccount == "" && orderInput.Amount == 0 && orderInput.CheckName == "" &&
+		orderInput.CheckNumber == 0 && orderInput.CheckMemo == "" {
+		return nil
+	}
+
+	service, _ := provider.MailformServices.Lookup(orderInput.Service)
+	if service.Postcard {
+		return errors.New("checks cannot be mailed with postcards")
+	}
+
+	required := []struct {
+		field    string
+		provided bool
+	}{
+		{"BankAccount", orderInput.BankAccount != ""},
+		{"Amount", orderInput.Amount != 0},
+		{"CheckName", orderInput.CheckName != ""},
+		{"CheckNumber", orderInput.CheckNumber != 0},
+	}
+	for _, r := range required {
+		if !r.provided {
+			return fmt.Errorf("%s not provided, but is required for checks", r.field)
+		}
+	}
+
+	if orderInput.Amount < 0 || orderInput.CheckNumber < 0 {
+		return errors.New("check amount and number must be positive")
+	}
+
+	return nil
+}
+
+// UploadsDocument reports whether the mail's document is uploaded with its order rather than fetched by Mailform.
+func UploadsDocument(mail *mailformv1alpha1.Mail) bool {
+	return mail.Spec.DocumentSHA256 != "" || mail.Spec.ConfigMapRef != nil || BuildsDocument(mail)
+}
+
+// BuildsDocument reports whether the mail's document is built by merging documents, adding a cover page or rendering a body.
+func BuildsDocument(mail *mailformv1alpha1.Mail) bool {
+	return len(mail.Spec.Documents) > 0 || mail.Spec.CoverPage != nil || mail.Spec.Body != nil
+}
@@ -0,0 +1,14 @@
+package orderinput
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrderInput(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Order Input Suite")
+}
@@ -0,0 +1,123 @@
+package orderinput
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+)
+
+// newMail returns a Mail with a valid order.
+func newMail() *mailformv1alpha1.Mail {
+	address := &mailformv1alpha1.Address{
+		Name:     "Alice",
+		Address1: "1 Main St",
+		City:     "Springfield",
+		State:    "IL",
+		Postcode: "62701",
+		Country:  "US",
+	}
+
+	return &mailformv1alpha1.Mail{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail", UID: "uid"},
+		Spec: mailformv1alpha1.MailSpec{
+			URL:     "https://example.com/letter.pdf",
+			Service: mailformv1alpha1.ServiceUSPSStandard,
+			To:      address,
+			From:    address.DeepCopy(),
+		},
+	}
+}
+
+var _ = Describe("Order input", func() {
+	It("should build the order input from the mail", func() {
+		mail := newMail()
+		mail.Spec.Check = &mailformv1alpha1.Check{BankAccount: "bank", AmountCents: 1250, Name: "Bob", Number: 1001}
+
+		orderInput := Build(mail)
+		Expect(orderInput.URL).To(Equal(mail.Spec.URL))
+		Expect(orderInput.Service).To(Equal("USPS_STANDARD"))
+		Expect(orderInput.ToName).To(Equal("Alice"))
+		Expect(orderInput.FromCountry).To(Equal("US"))
+		Expect(orderInput.Amount).To(Equal(1250))
+		Expect(orderInput.CheckNumber).To(Equal(1001))
+		Expect(UploadsDocument(mail)).To(BeFalse())
+	})
+
+	It("should set a customer reference naming the mail unless it has one", func() {
+		mail := newMail()
+		Expect(Build(mail).CustomerReference).To(Equal("postk8s:uid"))
+		Expect(IsMailReference(Build(mail).CustomerReference)).To(BeTrue())
+
+		mail.Spec.CustomerReference = "invoice-42"
+		Expect(Build(mail).CustomerReference).To(Equal("invoice-42"))
+		Expect(IsMailReference(Build(mail).CustomerReference)).To(BeFalse())
+
+		mail.Spec.CustomerReference = ""
+		mail.UID = ""
+		Expect(Build(mail).CustomerReference).To(BeEmpty())
+	})
+
+	It("should build the order input without addresses", func() {
+		mail := newMail()
+		mail.Spec.To, mail.Spec.From = nil, nil
+
+		Expect(Build(mail).ToName).To(BeEmpty())
+	})
+
+	DescribeTable("should upload documents that Mailform can't fetch itself",
+		func(update func(*mailformv1alpha1.Mail), builds bool) {
+			mail := newMail()
+			update(mail)
+
+			orderInput := Build(mail)
+			Expect(orderInput.FilePath).To(Equal(DocumentPath(mail)))
+			Expect(orderInput.URL).To(BeEmpty())
+			Expect(UploadsDocument(mail)).To(BeTrue())
+			Expect(BuildsDocument(mail)).To(Equal(builds))
+		},
+		Entry("pinned document", func(m *mailformv1alpha1.Mail) { m.Spec.DocumentSHA256 = "abc" }, false),
+		Entry("ConfigMap", func(m *mailformv1alpha1.Mail) {
+			m.Spec.URL = ""
+			m.Spec.ConfigMapRef = &corev1.ConfigMapKeySelector{}
+		}, false),
+		Entry("body", func(m *mailformv1alpha1.Mail) {
+			m.Spec.URL = ""
+			m.Spec.Body = &mailformv1alpha1.Body{}
+		}, true),
+	)
+
+	DescribeTable("should validate the order input",
+		func(update func(*mailformv1alpha1.Mail), expected string) {
+			mail := newMail()
+			update(mail)
+
+			orderInput := Build(mail)
+			err := Validate(mail, &orderInput)
+			if expected == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry("valid", func(*mailformv1alpha1.Mail) {}, ""),
+		Entry("ConfigMap with a URL", func(m *mailformv1alpha1.Mail) {
+			m.Spec.ConfigMapRef = &corev1.ConfigMapKeySelector{}
+		}, "configMapRef cannot be provided with filePath or url"),
+		Entry("body with a URL", func(m *mailformv1alpha1.Mail) {
+			m.Spec.Body = &mailformv1alpha1.Body{}
+		}, "body cannot be provided with documents"),
+		Entry("missing recipient", func(m *mailformv1alpha1.Mail) { m.Spec.To.Address1 = "" }, "ToAddress1"),
+		Entry("domestic service abroad", func(m *mailformv1alpha1.Mail) { m.Spec.To.Country = "DE" },
+			"service USPS_STANDARD only delivers within the US, not to DE"),
+		Entry("incomplete check", func(m *mailformv1alpha1.Mail) {
+			m.Spec.Check = &mailformv1alpha1.Check{BankAccount: "bank"}
+		}, "Amount not provided, but is required for checks"),
+		Entry("check with a postcard", func(m *mailformv1alpha1.Mail) {
+			m.Spec.Service = mailformv1alpha1.ServiceUSPSPostcard
+			m.Spec.Check = &mailformv1alpha1.Check{BankAccount: "bank", AmountCents: 1, Name: "Bob", Number: 1}
+		}, "checks cannot be mailed with postcards"),
+	)
+})
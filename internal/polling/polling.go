@@ -0,0 +1,125 @@
+package polling
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+const (
+	// DefaultAgeFactor polls orders roughly ten times over their current age.
+	DefaultAgeFactor = 0.1
+	// DefaultJitter randomly adds or removes up to 10% of each interval.
+	DefaultJitter = 0.1
+)
+
+var (
+	// ErrNilConfig is returned when no config is provided to New.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrInvalidInterval is returned when the min interval isn't positive or is greater than the max interval.
+	ErrInvalidInterval = errors.New("min interval must be greater than 0 and not greater than max interval")
+	// ErrInvalidJitter is returned when the jitter isn't between 0 and 1.
+	ErrInvalidJitter = errors.New("jitter must be at least 0 and less than 1")
+)
+
+// stateMultipliers slow down polling for states that take longer to change.
+// Queued orders can be printed or cancelled at any moment, printed orders take days to be delivered.
+var stateMultipliers = map[string]float64{
+	mailform.StatusQueued:              1,
+	mailform.StatusAwaitingFulfillment: 2,
+}
+
+// Config is the configuration used to create a Policy.
+type Config struct {
+	// MinInterval is how often new orders are polled.
+	MinInterval time.Duration
+	// MaxInterval is how often old orders are polled.
+	MaxInterval time.Duration
+	// AgeFactor is the fraction of an order's age to wait between polls. Defaults to DefaultAgeFactor.
+	AgeFactor float64
+	// Jitter is the fraction of each interval randomly added or removed. Zero disables jitter.
+	Jitter float64
+}
+
+// Policy decides how long to wait before polling an order again.
+// Orders are polled frequently right after they're created and less often as they age.
+type Policy struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+	ageFactor   float64
+	jitter      float64
+	random      func() float64
+}
+
+// New returns a new polling Policy.
+func New(c *Config) (*Policy, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.MinInterval <= 0 || c.MinInterval > c.MaxInterval {
+		return nil, ErrInvalidInterval
+	}
+
+	if c.Jitter < 0 || c.Jitter >= 1 {
+		return nil, ErrInvalidJitter
+	}
+
+	ageFactor := DefaultAgeFactor
+	if c.AgeFactor > 0 {
+		ageFactor = c.AgeFactor
+	}
+
+	return &Policy{
+		minInterval: c.MinInterval,
+		maxInterval: c.MaxInterval,
+		ageFactor:   ageFactor,
+		jitter:      c.Jitter,
+		random:      rand.Float64,
+	}, nil
+}
+
+// Interval returns how long to wait before polling an order in the state that's the given age.
+// The key, such as an object's UID, keeps the jitter stable for each order.
+func (p *Policy) Interval(state string, age time.Duration, key string) time.Duration {
+	multiplier, ok := stateMultipliers[state]
+	if !ok {
+		multiplier = 1
+	}
+
+	interval := time.Duration(float64(age) * p.ageFactor * multiplier)
+	interval = min(max(interval, p.minInterval), p.maxInterval)
+
+	return p.Jitter(interval, key)
+}
+
+// Jitter adds or removes up to the policy's jitter fraction of the interval.
+// The same key is always jittered by the same fraction so polls for many orders stay evenly spread
+// instead of bunching up by chance. Empty keys are jittered randomly.
+func (p *Policy) Jitter(interval time.Duration, key string) time.Duration {
+	if p.jitter == 0 {
+		return interval
+	}
+
+	fraction := p.random()
+	if key != "" {
+		fraction = StableFraction(key)
+	}
+
+	// Scale the fraction in [0, 1) to [-jitter, jitter)
+	offset := (fraction*2 - 1) * p.jitter
+
+	return time.Duration(float64(interval) * (1 + offset))
+}
+
+// StableFraction returns a number in [0, 1) derived from the key.
+func StableFraction(key string) float64 {
+	sum := sha256.Sum256([]byte(key))
+
+	// Use the top 53 bits so every value is exactly representable as a float64
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
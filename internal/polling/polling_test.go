@@ -0,0 +1,84 @@
+package polling
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+var _ = Describe("Policy", func() {
+	It("should return errors for invalid config", func() {
+		_, err := New(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = New(&Config{MaxInterval: time.Hour})
+		Expect(err).To(MatchError(ErrInvalidInterval))
+
+		_, err = New(&Config{MinInterval: 2 * time.Hour, MaxInterval: time.Hour})
+		Expect(err).To(MatchError(ErrInvalidInterval))
+
+		_, err = New(&Config{MinInterval: time.Minute, MaxInterval: time.Hour, Jitter: 1})
+		Expect(err).To(MatchError(ErrInvalidJitter))
+	})
+
+	It("should poll new orders often and old orders rarely", func() {
+		policy, err := New(&Config{MinInterval: 5 * time.Minute, MaxInterval: 12 * time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(policy.Interval(mailform.StatusQueued, 0, "")).To(Equal(5 * time.Minute))
+		Expect(policy.Interval(mailform.StatusQueued, 10*time.Hour, "")).To(Equal(time.Hour))
+		Expect(policy.Interval(mailform.StatusQueued, 30*24*time.Hour, "")).To(Equal(12 * time.Hour))
+	})
+
+	It("should poll states that change slowly less often", func() {
+		policy, err := New(&Config{MinInterval: 5 * time.Minute, MaxInterval: 12 * time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(policy.Interval(mailform.StatusAwaitingFulfillment, 10*time.Hour, "")).To(Equal(2 * time.Hour))
+		Expect(policy.Interval("", 10*time.Hour, "")).To(Equal(time.Hour))
+	})
+
+	It("should jitter intervals within bounds", func() {
+		policy, err := New(&Config{MinInterval: time.Hour, MaxInterval: time.Hour, Jitter: 0.1})
+		Expect(err).NotTo(HaveOccurred())
+
+		policy.random = func() float64 { return 0 }
+		Expect(policy.Interval(mailform.StatusQueued, 0, "")).To(Equal(54 * time.Minute))
+
+		policy.random = func() float64 { return 0.5 }
+		Expect(policy.Interval(mailform.StatusQueued, 0, "")).To(Equal(time.Hour))
+
+		policy.random = func() float64 { return 0.999 }
+		Expect(policy.Interval(mailform.StatusQueued, 0, "")).To(BeNumerically("<", 66*time.Minute))
+	})
+
+	It("should jitter the same key by the same amount", func() {
+		policy, err := New(&Config{MinInterval: time.Hour, MaxInterval: time.Hour, Jitter: 0.1})
+		Expect(err).NotTo(HaveOccurred())
+
+		interval := policy.Interval(mailform.StatusQueued, 0, "uid-1")
+		Expect(interval).To(BeNumerically(">=", 54*time.Minute))
+		Expect(interval).To(BeNumerically("<", 66*time.Minute))
+		Expect(policy.Interval(mailform.StatusQueued, 0, "uid-1")).To(Equal(interval))
+		Expect(policy.Jitter(time.Hour, "uid-1")).To(Equal(interval))
+		Expect(policy.Jitter(time.Hour, "uid-2")).NotTo(Equal(interval))
+	})
+
+	It("should spread stable fractions across the range", func() {
+		buckets := make([]int, 10)
+		for i := range 1000 {
+			fraction := StableFraction(fmt.Sprintf("uid-%d", i))
+			Expect(fraction).To(BeNumerically(">=", 0))
+			Expect(fraction).To(BeNumerically("<", 1))
+			buckets[int(fraction*10)]++
+		}
+
+		for _, count := range buckets {
+			Expect(count).To(BeNumerically(">", 50))
+		}
+	})
+})
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single trial request through to check if the provider recovered.
+	BreakerHalfOpen
+	// BreakerOpen rejects all requests until the open timeout passes.
+	BreakerOpen
+)
+
+// String returns the name of the breaker state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+
+	return "closed"
+}
+
+// Breaker is a circuit breaker that opens after consecutive failures.
+type Breaker struct {
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	trialInFlight    bool
+	failureThreshold int
+	openTimeout      time.Duration
+	onStateChange    func(BreakerState)
+	now              func() time.Time
+}
+
+// NewBreaker returns a closed circuit breaker.
+// It opens after failureThreshold consecutive failures and lets a trial request through after openTimeout.
+func NewBreaker(failureThreshold int, openTimeout time.Duration, onStateChange func(BreakerState)) *Breaker {
+	return &Breaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		onStateChange:    onStateChange,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a request may be made.
+// Every allowed request must be followed by a call to Record.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		// Only one trial request at a time
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	}
+
+	return true
+}
+
+// Record records the result of an allowed request.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		b.trialInFlight = false
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.trialInFlight = false
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// RetryAfter returns how long until an open breaker lets a trial request through.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return 0
+	}
+
+	return max(b.openTimeout-b.now().Sub(b.openedAt), 0)
+}
+
+// setState changes the state and notifies the listener. Must be called with the lock held.
+func (b *Breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(state)
+	}
+}
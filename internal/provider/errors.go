@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// ErrorClass is the kind of failure returned by a mail provider.
+type ErrorClass string
+
+const (
+	// ClassNone is returned for nil errors.
+	ClassNone ErrorClass = ""
+	// ClassNotFound means the order doesn't exist, e.g. it was deleted or the ID is invalid.
+	ClassNotFound ErrorClass = "NotFound"
+	// ClassAuth means the API token is missing, invalid or revoked.
+	ClassAuth ErrorClass = "Auth"
+	// ClassRateLimit means too many requests have been made.
+	ClassRateLimit ErrorClass = "RateLimit"
+	// ClassTransient means the request may succeed if retried, e.g. timeouts and server errors.
+	ClassTransient ErrorClass = "Transient"
+	// ClassValidation means the order was rejected and retrying won't help until it changes.
+	ClassValidation ErrorClass = "Validation"
+	// ClassUnavailable means the request wasn't made because the circuit breaker is open.
+	ClassUnavailable ErrorClass = "Unavailable"
+	// ClassUnknown is returned for errors that can't be classified.
+	ClassUnknown ErrorClass = "Unknown"
+)
+
+// ErrOrderNotFound can be returned by providers when an order doesn't exist.
+var ErrOrderNotFound = errors.New("order not found")
+
+// Classify returns the class of an error returned by a mail provider.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassNone
+	}
+
+	if errors.Is(err, ErrOrderNotFound) {
+		return ClassNotFound
+	}
+
+	if errors.Is(err, ErrRateLimited) {
+		return ClassRateLimit
+	}
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return ClassUnavailable
+	}
+
+	orderInvalidErr := &mailform.ErrOrderInvalid{}
+	if errors.As(err, &orderInvalidErr) {
+		return ClassValidation
+	}
+
+	mailformErr := &mailform.ErrMailform{}
+	if errors.As(err, &mailformErr) {
+		return classifyMailformError(mailformErr)
+	}
+
+	if isTransient(err) {
+		return ClassTransient
+	}
+
+	return ClassUnknown
+}
+
+// Retryable reports whether a request that failed with the class may succeed if retried unchanged.
+func Retryable(class ErrorClass) bool {
+	switch class {
+	case ClassRateLimit, ClassTransient, ClassUnavailable, ClassUnknown:
+		return true
+	}
+
+	return false
+}
+
+// classifyMailformError classifies Mailform API errors by their status code, falling back to their message.
+// go-mailform only sets the status code for errors from CreateOrder and 401 responses, StatusClient adds it to errors
+// from GetOrder and CancelOrder. Orders are only ClassNotFound with a 404 or 410 status, since a lost order may be
+// placed again and a message that happens to say "not found" isn't enough to do that. Messages that aren't recognized
+// are ClassUnknown and retried.
+func classifyMailformError(err *mailform.ErrMailform) ErrorClass {
+	code, convErr := strconv.Atoi(err.Err.Code)
+	if convErr == nil {
+		switch {
+		case code == 401 || code == 403:
+			return ClassAuth
+		case code == 404 || code == 410:
+			return ClassNotFound
+		case code == 429:
+			return ClassRateLimit
+		case code == 400 || code == 422:
+			return ClassValidation
+		case code >= 500:
+			return ClassTransient
+		}
+	}
+
+	message := strings.ToLower(strings.Join([]string{err.Err.Code, err.Err.Message, err.Detail}, " "))
+
+	switch {
+	case containsAny(message, "unauthorized", "unauthenticated", "forbidden", "token"):
+		return ClassAuth
+	case containsAny(message, "rate limit", "rate_limit", "too many requests", "throttl"):
+		return ClassRateLimit
+	case containsAny(message, "invalid", "required", "validation", "not supported"):
+		return ClassValidation
+	case containsAny(message, "timeout", "unavailable", "internal server error", "try again"):
+		return ClassTransient
+	}
+
+	return ClassUnknown
+}
+
+// isTransient reports whether the error is a network failure or timeout.
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// newMailformError returns a Mailform API error with the code and message.
+func newMailformError(code, message string) error {
+	err := &mailform.ErrMailform{}
+	err.Err.Code = code
+	err.Err.Message = message
+	return err
+}
+
+var _ = Describe("Classify", func() {
+	DescribeTable("should classify provider errors",
+		func(err error, expected ErrorClass) {
+			Expect(Classify(err)).To(Equal(expected))
+		},
+		Entry("nil", nil, ClassNone),
+		Entry("order not found sentinel", fmt.Errorf("fetching order: %w", ErrOrderNotFound), ClassNotFound),
+		Entry("invalid order", (&mailform.OrderInput{}).Validate(), ClassValidation),
+		Entry("401", newMailformError("401", "unauthorized"), ClassAuth),
+		Entry("403", newMailformError("403", ""), ClassAuth),
+		Entry("404", newMailformError("404", ""), ClassNotFound),
+		Entry("410", newMailformError("410", ""), ClassNotFound),
+		Entry("429", newMailformError("429", ""), ClassRateLimit),
+		Entry("422", newMailformError("422", ""), ClassValidation),
+		Entry("503", newMailformError("503", ""), ClassTransient),
+		Entry("not found message", newMailformError("order_not_found", "Order not found"), ClassUnknown),
+		Entry("rate limit message", newMailformError("", "Too many requests"), ClassRateLimit),
+		Entry("invalid message", newMailformError("", "Invalid postal code"), ClassValidation),
+		Entry("unrecognized message", newMailformError("", "something happened"), ClassUnknown),
+		Entry("deadline exceeded", fmt.Errorf("request: %w", context.DeadlineExceeded), ClassTransient),
+		Entry("network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ClassTransient),
+		Entry("other", errors.New("boom"), ClassUnknown),
+	)
+
+	It("should classify errors from the Mailform client by status code only where it's set", func() {
+		var status int
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body)) // nolint:errcheck
+		}))
+		defer server.Close()
+
+		client, err := mailform.New(&mailform.Config{Token: "token", APIBaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The status code of GetOrder errors isn't available, and the message isn't enough to say the order is lost
+		status, body = http.StatusNotFound, `{"error": {"message": "Order not found"}}`
+		_, err = client.GetOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassUnknown))
+
+		status, body = http.StatusNotFound, `{"error": {"message": "No luck"}}`
+		_, err = client.GetOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassUnknown))
+
+		status, body = http.StatusUnauthorized, ""
+		_, err = client.GetOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassAuth))
+
+		status, body = http.StatusUnprocessableEntity, `{"error": {"message": "No luck"}}`
+		_, err = client.CreateOrder(mailform.OrderInput{
+			URL: "https://example.com/document.pdf", Service: "USPS_STANDARD",
+			ToName: "to", ToAddress1: "1 Main St", ToCity: "City", ToState: "CA", ToPostcode: "12345", ToCountry: "US",
+			FromName: "from", FromAddress1: "2 Main St", FromCity: "City", FromState: "CA", FromPostcode: "12345",
+			FromCountry: "US",
+		})
+		Expect(err).To(MatchError("No luck"))
+		Expect(Classify(err)).To(Equal(ClassValidation))
+	})
+
+	It("should only retry classes that may succeed unchanged", func() {
+		Expect(Retryable(ClassTransient)).To(BeTrue())
+		Expect(Retryable(ClassRateLimit)).To(BeTrue())
+		Expect(Retryable(ClassUnknown)).To(BeTrue())
+		Expect(Retryable(ClassNotFound)).To(BeFalse())
+		Expect(Retryable(ClassAuth)).To(BeFalse())
+		Expect(Retryable(ClassValidation)).To(BeFalse())
+		Expect(Retryable(ClassNone)).To(BeFalse())
+	})
+})
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	mailform "github.com/circa10a/go-mailform"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultMaxWait is how long a request waits for the rate limiter before giving up.
+	DefaultMaxWait = 30 * time.Second
+	// DefaultOpenTimeout is how long the circuit stays open before a trial request is let through.
+	DefaultOpenTimeout = time.Minute
+)
+
+var (
+	// ErrNilConfig is returned when no config is provided to NewGuardedClient.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrNilClient is returned when no client is provided to NewGuardedClient.
+	ErrNilClient = errors.New("client cannot be nil")
+	// ErrRateLimited is returned when a request would wait longer than the max wait for the rate limiter.
+	ErrRateLimited = errors.New("mailform client rate limit exceeded")
+	// ErrCircuitOpen is matched by CircuitOpenError using errors.Is.
+	ErrCircuitOpen = errors.New("mailform circuit breaker is open")
+)
+
+// Client is an interface to Create/Get/Cancel orders from Mailform.
+type Client interface {
+	CreateOrder(o mailform.OrderInput) (*mailform.Order, error)
+	GetOrder(o string) (*mailform.Order, error)
+	CancelOrder(o string) error
+}
+
+// CircuitOpenError is returned for requests rejected while the circuit breaker is open.
+type CircuitOpenError struct {
+	// RetryAfter is how long until a trial request is let through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrCircuitOpen, e.RetryAfter)
+}
+
+// Is reports whether the target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// GuardedClientConfig is the configuration used to create a GuardedClient.
+type GuardedClientConfig struct {
+	Client Client
+	// RequestsPerSecond is the rate requests are made to Mailform. Zero disables rate limiting.
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be made at once. Defaults to 1.
+	Burst int
+	// MaxWait defaults to DefaultMaxWait.
+	MaxWait time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the circuit. Zero disables the breaker.
+	FailureThreshold int
+	// OpenTimeout defaults to DefaultOpenTimeout.
+	OpenTimeout time.Duration
+}
+
+// GuardedClient rate limits requests to Mailform and stops making them while Mailform is failing.
+// It is safe to share between controllers.
+type GuardedClient struct {
+	client  Client
+	limiter *rate.Limiter
+	maxWait time.Duration
+	breaker *Breaker
+}
+
+// NewGuardedClient returns a new GuardedClient.
+func NewGuardedClient(c *GuardedClientConfig) (*GuardedClient, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Client == nil {
+		return nil, ErrNilClient
+	}
+
+	g := &GuardedClient{
+		client:  c.Client,
+		limiter: rate.NewLimiter(rate.Inf, 1),
+		maxWait: DefaultMaxWait,
+	}
+
+	if c.MaxWait > 0 {
+		g.maxWait = c.MaxWait
+	}
+
+	g.SetRateLimit(c.RequestsPerSecond, c.Burst)
+
+	if c.FailureThreshold > 0 {
+		openTimeout := DefaultOpenTimeout
+		if c.OpenTimeout > 0 {
+			openTimeout = c.OpenTimeout
+		}
+		g.breaker = NewBreaker(c.FailureThreshold, openTimeout, recordBreakerState)
+	}
+
+	return g, nil
+}
+
+// CreateOrder creates an order once allowed by the rate limiter and circuit breaker.
+func (g *GuardedClient) CreateOrder(o mailform.OrderInput) (*mailform.Order, error) {
+	var order *mailform.Order
+	err := g.do("CreateOrder", func() error {
+		var err error
+		order, err = g.client.CreateOrder(o)
+		return err
+	})
+
+	return order, err
+}
+
+// GetOrder fetches an order once allowed by the rate limiter and circuit breaker.
+func (g *GuardedClient) GetOrder(o string) (*mailform.Order, error) {
+	var order *mailform.Order
+	err := g.do("GetOrder", func() error {
+		var err error
+		order, err = g.client.GetOrder(o)
+		return err
+	})
+
+	return order, err
+}
+
+// CancelOrder cancels an order once allowed by the rate limiter and circuit breaker.
+func (g *GuardedClient) CancelOrder(o string) error {
+	return g.do("CancelOrder", func() error {
+		return g.client.CancelOrder(o)
+	})
+}
+
+// SetRateLimit changes the rate and burst of requests. Zero requests per second disables rate limiting.
+func (g *GuardedClient) SetRateLimit(requestsPerSecond float64, burst int) {
+	limit := rate.Inf
+	if requestsPerSecond > 0 {
+		limit = rate.Limit(requestsPerSecond)
+	}
+
+	g.limiter.SetBurst(max(burst, 1))
+	g.limiter.SetLimit(limit)
+}
+
+// BreakerState returns the state of the circuit breaker. Always closed if the breaker is disabled.
+func (g *GuardedClient) BreakerState() BreakerState {
+	if g.breaker == nil {
+		return BreakerClosed
+	}
+
+	return g.breaker.State()
+}
+
+// do makes the request if allowed and records the result.
+func (g *GuardedClient) do(operation string, request func() error) error {
+	err := g.wait()
+	if err != nil {
+		requestsTotal.WithLabelValues(operation, string(Classify(err))).Inc()
+		return err
+	}
+
+	err = request()
+	class := Classify(err)
+
+	if g.breaker != nil {
+		// Orders that don't exist or are invalid mean Mailform is responding normally
+		g.breaker.Record(!Retryable(class))
+	}
+
+	if class == ClassNone {
+		requestsTotal.WithLabelValues(operation, "Success").Inc()
+	} else {
+		requestsTotal.WithLabelValues(operation, string(class)).Inc()
+	}
+
+	return err
+}
+
+// wait blocks until the request is allowed by the rate limiter and circuit breaker.
+func (g *GuardedClient) wait() error {
+	ctx, cancel := context.WithTimeout(context.Background(), g.maxWait)
+	defer cancel()
+
+	// Wait returns immediately if the wait would exceed the deadline
+	err := g.limiter.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+
+	if g.breaker != nil && !g.breaker.Allow() {
+		return &CircuitOpenError{RetryAfter: g.breaker.RetryAfter()}
+	}
+
+	return nil
+}
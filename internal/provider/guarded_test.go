@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// mockClient returns mockErr for every request and counts the requests made.
+type mockClient struct {
+	mockErr  error
+	requests int
+}
+
+// CreateOrder is for creating mock orders. Will return mockErr if not nil
+func (m *mockClient) CreateOrder(o mailform.OrderInput) (*mailform.Order, error) {
+	m.requests++
+	return &mailform.Order{}, m.mockErr
+}
+
+// GetOrder is for fetching mock orders. Will return mockErr if not nil
+func (m *mockClient) GetOrder(o string) (*mailform.Order, error) {
+	m.requests++
+	return &mailform.Order{}, m.mockErr
+}
+
+// CancelOrder is for cancelling mock orders. Will return mockErr if not nil
+func (m *mockClient) CancelOrder(o string) error {
+	m.requests++
+	return m.mockErr
+}
+
+var _ = Describe("Breaker", func() {
+	var now time.Time
+	var states []BreakerState
+	var breaker *Breaker
+
+	BeforeEach(func() {
+		now = time.Now()
+		states = nil
+		breaker = NewBreaker(2, time.Minute, func(state BreakerState) {
+			states = append(states, state)
+		})
+		breaker.now = func() time.Time { return now }
+	})
+
+	It("should open after consecutive failures", func() {
+		Expect(breaker.Allow()).To(BeTrue())
+		breaker.Record(false)
+		Expect(breaker.State()).To(Equal(BreakerClosed))
+
+		Expect(breaker.Allow()).To(BeTrue())
+		breaker.Record(false)
+		Expect(breaker.State()).To(Equal(BreakerOpen))
+		Expect(breaker.Allow()).To(BeFalse())
+		Expect(breaker.RetryAfter()).To(Equal(time.Minute))
+	})
+
+	It("should reset failures after a success", func() {
+		breaker.Allow()
+		breaker.Record(false)
+		breaker.Allow()
+		breaker.Record(true)
+		breaker.Allow()
+		breaker.Record(false)
+		Expect(breaker.State()).To(Equal(BreakerClosed))
+	})
+
+	It("should let a single trial request through once the open timeout passes", func() {
+		breaker.Allow()
+		breaker.Record(false)
+		breaker.Allow()
+		breaker.Record(false)
+
+		now = now.Add(time.Minute)
+		Expect(breaker.Allow()).To(BeTrue())
+		Expect(breaker.State()).To(Equal(BreakerHalfOpen))
+		Expect(breaker.Allow()).To(BeFalse())
+
+		// A failed trial opens the circuit again
+		breaker.Record(false)
+		Expect(breaker.State()).To(Equal(BreakerOpen))
+
+		now = now.Add(time.Minute)
+		Expect(breaker.Allow()).To(BeTrue())
+		breaker.Record(true)
+		Expect(breaker.State()).To(Equal(BreakerClosed))
+		Expect(states).To(Equal([]BreakerState{
+			BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed,
+		}))
+	})
+})
+
+var _ = Describe("GuardedClient", func() {
+	It("should return errors for invalid config", func() {
+		_, err := NewGuardedClient(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = NewGuardedClient(&GuardedClientConfig{})
+		Expect(err).To(MatchError(ErrNilClient))
+	})
+
+	It("should pass requests through when limiting and breaking are disabled", func() {
+		client := &mockClient{mockErr: errors.New("boom")}
+		guarded, err := NewGuardedClient(&GuardedClientConfig{Client: client})
+		Expect(err).NotTo(HaveOccurred())
+
+		for range 10 {
+			_, err = guarded.GetOrder("order-1")
+			Expect(err).To(MatchError("boom"))
+		}
+		Expect(client.requests).To(Equal(10))
+		Expect(guarded.BreakerState()).To(Equal(BreakerClosed))
+	})
+
+	It("should stop making requests while the circuit is open", func() {
+		client := &mockClient{mockErr: newMailformError("503", "service unavailable")}
+		guarded, err := NewGuardedClient(&GuardedClientConfig{
+			Client:           client,
+			FailureThreshold: 2,
+			OpenTimeout:      time.Hour,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassTransient))
+		err = guarded.CancelOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassTransient))
+
+		_, err = guarded.CreateOrder(mailform.OrderInput{})
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		Expect(Classify(err)).To(Equal(ClassUnavailable))
+
+		circuitOpenErr := &CircuitOpenError{}
+		Expect(errors.As(err, &circuitOpenErr)).To(BeTrue())
+		Expect(circuitOpenErr.RetryAfter).To(BeNumerically(">", 59*time.Minute))
+
+		Expect(client.requests).To(Equal(2))
+		Expect(guarded.BreakerState()).To(Equal(BreakerOpen))
+	})
+
+	It("should not open the circuit for orders that don't exist", func() {
+		client := &mockClient{mockErr: ErrOrderNotFound}
+		guarded, err := NewGuardedClient(&GuardedClientConfig{Client: client, FailureThreshold: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		for range 3 {
+			_, err = guarded.GetOrder("order-1")
+			Expect(err).To(MatchError(ErrOrderNotFound))
+		}
+		Expect(guarded.BreakerState()).To(Equal(BreakerClosed))
+	})
+
+	It("should reject requests that would wait too long for the rate limiter", func() {
+		client := &mockClient{}
+		guarded, err := NewGuardedClient(&GuardedClientConfig{
+			Client:            client,
+			RequestsPerSecond: 0.001,
+			Burst:             1,
+			MaxWait:           time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(err).To(MatchError(ErrRateLimited))
+		Expect(Classify(err)).To(Equal(ClassRateLimit))
+		Expect(client.requests).To(Equal(1))
+	})
+
+	It("should change the rate limit", func() {
+		client := &mockClient{}
+		guarded, err := NewGuardedClient(&GuardedClientConfig{
+			Client:  client,
+			MaxWait: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		for range 3 {
+			_, err = guarded.GetOrder("order-1")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		guarded.SetRateLimit(0.001, 1)
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(err).To(MatchError(ErrRateLimited))
+
+		guarded.SetRateLimit(0, 1)
+
+		_, err = guarded.GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.requests).To(Equal(5))
+	})
+})
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// breakerStateGauge is the current state of the Mailform circuit breaker.
+	breakerStateGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "postk8s_mailform_circuit_breaker_state",
+		Help: "State of the Mailform circuit breaker. 0 is closed, 1 is half-open and 2 is open.",
+	})
+	// breakerTransitionsTotal counts Mailform circuit breaker state changes.
+	breakerTransitionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postk8s_mailform_circuit_breaker_transitions_total",
+		Help: "Number of times the Mailform circuit breaker changed to each state.",
+	}, []string{"state"})
+	// requestsTotal counts Mailform requests by operation and result.
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postk8s_mailform_requests_total",
+		Help: "Number of Mailform requests by operation and error class. Rejected requests were never sent.",
+	}, []string{"operation", "class"})
+	// credentialsValidGauge is whether Mailform accepted the API token the last time it was checked.
+	credentialsValidGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "postk8s_mailform_credentials_valid",
+		Help: "Whether Mailform accepted the API token the last time it answered the credential probe.",
+	})
+	// credentialProbesTotal counts credential probes by result.
+	credentialProbesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postk8s_mailform_credential_probes_total",
+		Help: "Number of Mailform credential probes by error class.",
+	}, []string{"class"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(breakerStateGauge, breakerTransitionsTotal, requestsTotal,
+		credentialsValidGauge, credentialProbesTotal)
+}
+
+// recordBreakerState exports a circuit breaker state change.
+func recordBreakerState(state BreakerState) {
+	breakerStateGauge.Set(float64(state))
+	breakerTransitionsTotal.WithLabelValues(state.String()).Inc()
+}
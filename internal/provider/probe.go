@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultProbeInterval is how often the credentials are checked.
+	DefaultProbeInterval = 5 * time.Minute
+	// probeOrderID is looked up to check the credentials. It never exists so a valid token gets a not found response.
+	probeOrderID = "postk8s-credential-probe"
+)
+
+var (
+	// ErrNotProbed is returned by Check until the credentials have been checked once.
+	ErrNotProbed = errors.New("mailform credentials haven't been checked yet")
+	// ErrInvalidCredentials is returned by Check when Mailform rejects the API token.
+	ErrInvalidCredentials = errors.New("mailform rejected the API token")
+	// ErrUnreachable is returned by Probe when Mailform can't be reached to check the API token.
+	ErrUnreachable = errors.New("unable to reach mailform")
+)
+
+// ProbeConfig is the configuration used to create a Probe.
+type ProbeConfig struct {
+	Token string
+	// TokenFunc optionally returns the current token for each probe, e.g. when the token is reloaded from a file.
+	// Overrides Token.
+	TokenFunc func() string
+	// APIBaseURL defaults to mailform.DefaultAPIBaseURL.
+	APIBaseURL string
+	HTTPClient *http.Client
+	// Interval defaults to DefaultProbeInterval.
+	Interval time.Duration
+}
+
+// Probe periodically checks that Mailform accepts the API token.
+// It is a readiness check so replicas with bad credentials don't report ready. When Mailform can't be reached the
+// check keeps its last result since the token may still be valid.
+// Mailform is called directly rather than through a Client so the result is decided by the HTTP status, and so probes
+// don't count against the rate limit or trip the circuit breaker of the client used to send mail.
+type Probe struct {
+	lookup   *orderLookup
+	interval time.Duration
+
+	mu  sync.RWMutex
+	err error
+}
+
+// NewProbe returns a new Probe that isn't ready until it has checked the credentials.
+func NewProbe(c *ProbeConfig) (*Probe, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Token == "" && c.TokenFunc == nil {
+		return nil, ErrEmptyAPIToken
+	}
+
+	interval := DefaultProbeInterval
+	if c.Interval > 0 {
+		interval = c.Interval
+	}
+
+	return &Probe{
+		lookup:   newOrderLookup(c.Token, c.TokenFunc, c.APIBaseURL, c.HTTPClient),
+		interval: interval,
+		err:      ErrNotProbed,
+	}, nil
+}
+
+// Start checks the credentials immediately and then every interval until the context is cancelled.
+func (p *Probe) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("credential-probe")
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := p.probe(ctx)
+		if err != nil {
+			log.Error(err, "mailform credential probe failed")
+		}
+	}, p.interval)
+
+	return nil
+}
+
+// NeedLeaderElection ensures every replica checks its own credentials.
+func (p *Probe) NeedLeaderElection() bool {
+	return false
+}
+
+// Probe checks the credentials now and returns the result.
+func (p *Probe) Probe() error {
+	return p.probe(context.Background())
+}
+
+func (p *Probe) probe(ctx context.Context) error {
+	err := p.lookupProbeOrder(ctx)
+	class := Classify(err)
+
+	var result error
+	switch class {
+	case ClassNone, ClassNotFound, ClassValidation:
+		// Mailform answered the request without rejecting it so the token was accepted
+		credentialsValidGauge.Set(1)
+		credentialProbesTotal.WithLabelValues("Success").Inc()
+	case ClassAuth:
+		result = fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+		credentialsValidGauge.Set(0)
+		credentialProbesTotal.WithLabelValues(string(class)).Inc()
+	default:
+		// Whether the token is valid is unknown, so the gauge and the last result are left alone. Only a rejected
+		// token makes the replica unready, a Mailform outage or rate limit doesn't.
+		credentialProbesTotal.WithLabelValues(string(class)).Inc()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if errors.Is(p.err, ErrNotProbed) {
+			p.err = nil
+		}
+
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = result
+
+	return result
+}
+
+// lookupProbeOrder looks up the probe order and returns an error with the HTTP status of the response if it failed.
+func (p *Probe) lookupProbeOrder(ctx context.Context) error {
+	status, body, err := p.lookup.getOrder(ctx, probeOrderID)
+	if err != nil {
+		return err
+	}
+
+	if status >= 200 && status < 300 {
+		// Mailform can respond successfully with an error in the body, which is classified by its message
+		if body.Err.Message != "" {
+			return body
+		}
+
+		return nil
+	}
+
+	body.Err.Code = strconv.Itoa(status)
+	if body.Err.Message == "" {
+		body.Err.Message = strings.ToLower(http.StatusText(status))
+	}
+
+	return body
+}
+
+// Check returns the result of the last probe. It implements healthz.Checker.
+func (p *Probe) Check(_ *http.Request) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.err
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// newProbeServer returns a Mailform API that answers the probe order with the status and body.
+func newProbeServer(status *atomic.Int32, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		Expect(r.URL.Path).To(Equal(ordersEndpoint + "/" + probeOrderID))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(body))
+	}))
+	DeferCleanup(server.Close)
+
+	return server
+}
+
+var _ = Describe("Probe", func() {
+	It("should return errors for invalid config", func() {
+		_, err := NewProbe(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = NewProbe(&ProbeConfig{})
+		Expect(err).To(MatchError(ErrEmptyAPIToken))
+	})
+
+	It("should not be ready until the credentials are checked", func() {
+		probe, err := NewProbe(&ProbeConfig{Token: "token"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(probe.Check(nil)).To(MatchError(ErrNotProbed))
+	})
+
+	DescribeTable("should decide whether the token is accepted by the HTTP status",
+		func(code int, body string, expected error, ready bool) {
+			status := &atomic.Int32{}
+			status.Store(int32(code))
+			server := newProbeServer(status, body)
+
+			probe, err := NewProbe(&ProbeConfig{TokenFunc: func() string { return "token" }, APIBaseURL: server.URL + "/"})
+			Expect(err).NotTo(HaveOccurred())
+
+			if expected == nil {
+				Expect(probe.Probe()).To(Succeed())
+			} else {
+				Expect(probe.Probe()).To(MatchError(expected))
+			}
+
+			if ready {
+				Expect(probe.Check(nil)).To(Succeed())
+			} else {
+				Expect(probe.Check(nil)).To(MatchError(expected))
+			}
+		},
+		Entry("not found", http.StatusNotFound, `{"error":{"code":"404","message":"Order not found"}}`, nil, true),
+		Entry("not found with an unrecognized message", http.StatusNotFound, `{"error":{"message":"No luck"}}`, nil, true),
+		Entry("not found without a body", http.StatusNotFound, "", nil, true),
+		Entry("bad request", http.StatusBadRequest, `{"error":{"message":"No luck"}}`, nil, true),
+		Entry("ok", http.StatusOK, `{"data":{}}`, nil, true),
+		Entry("unauthorized", http.StatusUnauthorized, `{"error":{"message":"No luck"}}`, ErrInvalidCredentials, false),
+		Entry("forbidden", http.StatusForbidden, "", ErrInvalidCredentials, false),
+		Entry("ok with an auth error", http.StatusOK, `{"error":{"message":"Invalid token"}}`, ErrInvalidCredentials, false),
+		Entry("rate limited", http.StatusTooManyRequests, "", ErrUnreachable, true),
+		Entry("server error", http.StatusBadGateway, `{"error":{"message":"Order not found"}}`, ErrUnreachable, true),
+	)
+
+	It("should be ready again once the token is fixed", func() {
+		status := &atomic.Int32{}
+		status.Store(http.StatusUnauthorized)
+		server := newProbeServer(status, "")
+
+		probe, err := NewProbe(&ProbeConfig{Token: "token", APIBaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(probe.Probe()).To(MatchError(ErrInvalidCredentials))
+		Expect(probe.Check(nil)).To(MatchError(ErrInvalidCredentials))
+
+		status.Store(http.StatusNotFound)
+		Expect(probe.Probe()).To(Succeed())
+		Expect(probe.Check(nil)).To(Succeed())
+	})
+
+	It("should be ready when mailform can't be reached", func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		probe, err := NewProbe(&ProbeConfig{Token: "token", APIBaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(probe.Probe()).To(MatchError(ErrUnreachable))
+		Expect(probe.Check(nil)).To(Succeed())
+	})
+
+	It("should keep the last result while mailform can't be reached", func() {
+		status := &atomic.Int32{}
+		status.Store(http.StatusUnauthorized)
+		server := newProbeServer(status, "")
+
+		probe, err := NewProbe(&ProbeConfig{Token: "token", APIBaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(probe.Probe()).To(MatchError(ErrInvalidCredentials))
+		status.Store(http.StatusServiceUnavailable)
+		Expect(probe.Probe()).To(MatchError(ErrUnreachable))
+		Expect(probe.Check(nil)).To(MatchError(ErrInvalidCredentials))
+
+		status.Store(http.StatusNotFound)
+		Expect(probe.Probe()).To(Succeed())
+		status.Store(http.StatusServiceUnavailable)
+		Expect(probe.Probe()).To(MatchError(ErrUnreachable))
+		Expect(probe.Check(nil)).To(Succeed())
+	})
+
+	It("should check the credentials when started", func() {
+		status := &atomic.Int32{}
+		status.Store(http.StatusNotFound)
+		server := newProbeServer(status, "")
+
+		probe, err := NewProbe(&ProbeConfig{Token: "token", APIBaseURL: server.URL, Interval: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(probe.Start(ctx)).To(Succeed())
+		}()
+
+		Eventually(func() error { return probe.Check(nil) }).Should(Succeed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(probe.NeedLeaderElection()).To(BeFalse())
+	})
+})
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PriceClass groups delivery services by roughly what they cost, from cheapest to most expensive.
+type PriceClass string
+
+const (
+	// PriceEconomy is bulk rate mail and postcards.
+	PriceEconomy PriceClass = "Economy"
+	// PriceStandard is first class mail.
+	PriceStandard PriceClass = "Standard"
+	// PricePremium is priority and certified mail.
+	PricePremium PriceClass = "Premium"
+	// PriceExpress is overnight and next day mail.
+	PriceExpress PriceClass = "Express"
+)
+
+// domesticCountry is the country that domestic services deliver within.
+const domesticCountry = "US"
+
+// ErrDomesticOnly is returned when a service that only delivers within the US is used to mail another country.
+var ErrDomesticOnly = errors.New("only delivers within the US")
+
+// Service is a delivery service offered by a mail provider and what it supports.
+type Service struct {
+	// Code is the name of the service in orders, e.g. USPS_STANDARD.
+	Code string
+	// International services deliver outside of the US as well as within it.
+	International bool
+	// Certified services get a signature on delivery.
+	Certified bool
+	// Tracking services can be followed until they're delivered.
+	Tracking bool
+	// Postcard services mail postcards rather than letters.
+	Postcard bool
+	// PriceClass is roughly what the service costs compared to the others.
+	PriceClass PriceClass
+}
+
+// Catalog is the delivery services offered by a mail provider.
+// Providers other than Mailform can list their services in a Catalog of their own.
+type Catalog []Service
+
+// MailformServices are the delivery services offered by Mailform.
+var MailformServices = Catalog{
+	{Code: "FEDEX_OVERNIGHT", Tracking: true, PriceClass: PriceExpress},
+	{Code: "USPS_PRIORITY_EXPRESS", International: true, Tracking: true, PriceClass: PriceExpress},
+	{Code: "USPS_PRIORITY", International: true, Tracking: true, PriceClass: PricePremium},
+	{Code: "USPS_CERTIFIED_PHYSICAL_RECEIPT", Certified: true, Tracking: true, PriceClass: PricePremium},
+	{Code: "USPS_CERTIFIED_RECEIPT", Certified: true, Tracking: true, PriceClass: PricePremium},
+	{Code: "USPS_CERTIFIED", Certified: true, Tracking: true, PriceClass: PricePremium},
+	{Code: "USPS_FIRST_CLASS", International: true, PriceClass: PriceStandard},
+	{Code: "USPS_STANDARD", PriceClass: PriceEconomy},
+	{Code: "USPS_POSTCARD", International: true, Postcard: true, PriceClass: PriceEconomy},
+}
+
+// Lookup returns the service with the code.
+func (c Catalog) Lookup(code string) (Service, bool) {
+	for _, service := range c {
+		if service.Code == code {
+			return service, true
+		}
+	}
+
+	return Service{}, false
+}
+
+// Codes returns the codes of the services in the order they're listed.
+func (c Catalog) Codes() []string {
+	codes := make([]string, 0, len(c))
+	for _, service := range c {
+		codes = append(codes, service.Code)
+	}
+
+	return codes
+}
+
+// CheckDestination returns an error if the service doesn't deliver to the country.
+// Unknown services are left for the provider's own validation to reject.
+func (c Catalog) CheckDestination(code, country string) error {
+	service, ok := c.Lookup(code)
+	if !ok || service.International || strings.EqualFold(country, domesticCountry) {
+		return nil
+	}
+
+	return fmt.Errorf("service %s %w, not to %s", code, ErrDomesticOnly, country)
+}
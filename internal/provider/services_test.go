@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// mailCRD is the generated Mail CRD, whose validation has to agree with MailformServices.
+const mailCRD = "../../config/crd/bases/mailform.circa10a.github.io_mails.yaml"
+
+// mailCRDValidation is the part of the Mail CRD that validates the service.
+type mailCRDValidation struct {
+	Spec struct {
+		Versions []struct {
+			Schema struct {
+				OpenAPIV3Schema struct {
+					Properties struct {
+						Spec struct {
+							Properties struct {
+								Service struct {
+									Enum []string `json:"enum"`
+								} `json:"service"`
+							} `json:"properties"`
+							Validations []struct {
+								Rule string `json:"rule"`
+							} `json:"x-kubernetes-validations"`
+						} `json:"spec"`
+					} `json:"properties"`
+				} `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+var _ = Describe("Catalog", func() {
+	It("should list every service Mailform supports", func() {
+		Expect(MailformServices.Codes()).To(Equal(mailform.ServiceCodes))
+
+		for _, service := range MailformServices {
+			Expect(service.PriceClass).NotTo(BeEmpty(), service.Code)
+		}
+	})
+
+	It("should match the validation of the Mail CRD", func() {
+		data, err := os.ReadFile(mailCRD)
+		Expect(err).NotTo(HaveOccurred())
+
+		crd := &mailCRDValidation{}
+		Expect(yaml.Unmarshal(data, crd)).To(Succeed())
+		Expect(crd.Spec.Versions).NotTo(BeEmpty())
+
+		for _, version := range crd.Spec.Versions {
+			spec := version.Schema.OpenAPIV3Schema.Properties.Spec
+			Expect(spec.Properties.Service.Enum).To(Equal(MailformServices.Codes()))
+
+			// The CEL rule lists the services that only deliver within the US
+			var domestic []string
+			for _, validation := range spec.Validations {
+				match := regexp.MustCompile(`self\.service in \[([^\]]*)\]`).FindStringSubmatch(validation.Rule)
+				if match == nil {
+					continue
+				}
+				Expect(domestic).To(BeNil(), "only one rule should list the domestic services")
+				for _, code := range strings.Split(match[1], ",") {
+					domestic = append(domestic, strings.Trim(strings.TrimSpace(code), "'"))
+				}
+			}
+
+			var expected []string
+			for _, service := range MailformServices {
+				if !service.International {
+					expected = append(expected, service.Code)
+				}
+			}
+			Expect(domestic).To(Equal(expected))
+		}
+	})
+
+	It("should look up services", func() {
+		service, ok := MailformServices.Lookup("USPS_CERTIFIED")
+		Expect(ok).To(BeTrue())
+		Expect(service.Certified).To(BeTrue())
+		Expect(service.Tracking).To(BeTrue())
+
+		_, ok = MailformServices.Lookup("USPS_SNAIL")
+		Expect(ok).To(BeFalse())
+	})
+
+	DescribeTable("should check the destination of services",
+		func(code, country string, allowed bool) {
+			err := MailformServices.CheckDestination(code, country)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ErrDomesticOnly))
+			Expect(err).To(MatchError("service " + code + " only delivers within the US, not to " + country))
+		},
+		Entry("domestic service within the US", "USPS_STANDARD", "us", true),
+		Entry("domestic service abroad", "USPS_STANDARD", "DE", false),
+		Entry("certified service abroad", "USPS_CERTIFIED", "CA", false),
+		Entry("international service abroad", "USPS_FIRST_CLASS", "DE", true),
+		Entry("unknown service", "USPS_SNAIL", "DE", true),
+	)
+})
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// ordersEndpoint is the Mailform endpoint orders are looked up under.
+const ordersEndpoint = "/orders"
+
+// ErrEmptyAPIToken is returned when no API token is provided to NewStatusClient or NewProbe.
+var ErrEmptyAPIToken = errors.New("api token cannot be empty")
+
+// StatusClientConfig is the configuration used to create a StatusClient.
+type StatusClientConfig struct {
+	Client Client
+	Token  string
+	// TokenFunc optionally returns the current token for each request, e.g. when the token is reloaded from a file.
+	// Overrides Token.
+	TokenFunc func() string
+	// APIBaseURL defaults to mailform.DefaultAPIBaseURL.
+	APIBaseURL string
+	HTTPClient *http.Client
+}
+
+// StatusClient adds the HTTP status to errors from GetOrder and CancelOrder.
+// go-mailform only returns the status for errors from CreateOrder, so when getting or cancelling an order fails the
+// order is looked up directly to find out whether it exists. Orders are only ClassNotFound when Mailform responds 404,
+// never because of the wording of an error message.
+type StatusClient struct {
+	client Client
+	lookup *orderLookup
+}
+
+// NewStatusClient returns a new StatusClient.
+func NewStatusClient(c *StatusClientConfig) (*StatusClient, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Client == nil {
+		return nil, ErrNilClient
+	}
+
+	if c.Token == "" && c.TokenFunc == nil {
+		return nil, ErrEmptyAPIToken
+	}
+
+	return &StatusClient{
+		client: c.Client,
+		lookup: newOrderLookup(c.Token, c.TokenFunc, c.APIBaseURL, c.HTTPClient),
+	}, nil
+}
+
+// CreateOrder creates an order. Its errors already have the HTTP status.
+func (c *StatusClient) CreateOrder(o mailform.OrderInput) (*mailform.Order, error) {
+	return c.client.CreateOrder(o)
+}
+
+// GetOrder gets an order, adding the HTTP status to Mailform errors.
+func (c *StatusClient) GetOrder(o string) (*mailform.Order, error) {
+	order, err := c.client.GetOrder(o)
+
+	return order, c.withStatus(o, err)
+}
+
+// CancelOrder cancels an order, adding the HTTP status of looking the order up to Mailform errors.
+func (c *StatusClient) CancelOrder(o string) error {
+	return c.withStatus(o, c.client.CancelOrder(o))
+}
+
+// withStatus returns a copy of a Mailform error with the HTTP status of looking up the order as its code.
+// Unauthorized errors already have their status and other errors are returned as is. If the order can't be looked up
+// the copy has no code and is classified by its message.
+func (c *StatusClient) withStatus(orderID string, err error) error {
+	mailformErr := &mailform.ErrMailform{}
+	if !errors.As(err, &mailformErr) || mailformErr.Err.Code == strconv.Itoa(http.StatusUnauthorized) {
+		return err
+	}
+
+	// The code in the body isn't the status, so it's dropped rather than mistaken for one
+	withStatus := *mailformErr
+	withStatus.Err.Code = ""
+
+	status, _, lookupErr := c.lookup.getOrder(context.Background(), orderID)
+	if lookupErr == nil {
+		withStatus.Err.Code = strconv.Itoa(status)
+	}
+
+	return &withStatus
+}
+
+// orderLookup gets orders from the Mailform API directly so the HTTP status of the response is known.
+type orderLookup struct {
+	token      string
+	tokenFunc  func() string
+	apiBaseURL string
+	httpClient *http.Client
+}
+
+// newOrderLookup returns an orderLookup, defaulting to the Mailform API and timeout.
+func newOrderLookup(token string, tokenFunc func() string, apiBaseURL string, httpClient *http.Client) *orderLookup {
+	if apiBaseURL == "" {
+		apiBaseURL = mailform.DefaultAPIBaseURL
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: mailform.DefaultTimeout}
+	}
+
+	return &orderLookup{
+		token:      token,
+		tokenFunc:  tokenFunc,
+		apiBaseURL: strings.TrimSuffix(apiBaseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// getOrder looks up the order and returns the HTTP status of the response and any error in its body.
+func (l *orderLookup) getOrder(ctx context.Context, orderID string) (int, *mailform.ErrMailform, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.apiBaseURL+ordersEndpoint+"/"+url.PathEscape(orderID), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	token := l.token
+	if l.tokenFunc != nil {
+		token = l.tokenFunc()
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	// The body is only used for its message, the status is what matters
+	body := &mailform.ErrMailform{}
+	_ = json.NewDecoder(resp.Body).Decode(body)
+
+	return resp.StatusCode, body, nil
+}
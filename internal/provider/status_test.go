@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatusClient", func() {
+	var status int
+	var lookups int
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		lookups = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal(ordersEndpoint + "/order-1"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+
+			lookups++
+			w.WriteHeader(status)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newStatusClient := func(err error) *StatusClient {
+		client, newErr := NewStatusClient(&StatusClientConfig{
+			Client:     &mockClient{mockErr: err},
+			Token:      "token",
+			APIBaseURL: server.URL,
+		})
+		Expect(newErr).NotTo(HaveOccurred())
+
+		return client
+	}
+
+	It("should return errors for invalid config", func() {
+		_, err := NewStatusClient(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = NewStatusClient(&StatusClientConfig{Token: "token"})
+		Expect(err).To(MatchError(ErrNilClient))
+
+		_, err = NewStatusClient(&StatusClientConfig{Client: &mockClient{}})
+		Expect(err).To(MatchError(ErrEmptyAPIToken))
+	})
+
+	DescribeTable("should classify errors by the status of looking up the order",
+		func(err error, lookupStatus int, expected ErrorClass) {
+			status = lookupStatus
+			client := newStatusClient(err)
+
+			_, getErr := client.GetOrder("order-1")
+			Expect(Classify(getErr)).To(Equal(expected))
+			Expect(getErr).To(MatchError(err.Error()))
+
+			Expect(Classify(client.CancelOrder("order-1"))).To(Equal(expected))
+			Expect(lookups).To(Equal(2))
+		},
+		Entry("not found", newMailformError("", "Order not found"), http.StatusNotFound, ClassNotFound),
+		Entry("gone", newMailformError("", "No luck"), http.StatusGone, ClassNotFound),
+		Entry("not found message for an order that exists", newMailformError("", "Order not found"), http.StatusOK,
+			ClassUnknown),
+		Entry("code in the body", newMailformError("404", "No luck"), http.StatusBadGateway, ClassTransient),
+	)
+
+	It("should classify errors by their message when the order can't be looked up", func() {
+		server.Close()
+		client := newStatusClient(newMailformError("404", "Order not found"))
+
+		_, err := client.GetOrder("order-1")
+		Expect(Classify(err)).To(Equal(ClassUnknown))
+	})
+
+	It("should return other errors as is", func() {
+		unauthorized := newMailformError("401", "unauthorized")
+		_, err := newStatusClient(unauthorized).GetOrder("order-1")
+		Expect(err).To(BeIdenticalTo(unauthorized))
+
+		_, err = newStatusClient(context.DeadlineExceeded).GetOrder("order-1")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		_, err = newStatusClient(nil).GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lookups).To(BeZero())
+	})
+})
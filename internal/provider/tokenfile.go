@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mailform "github.com/circa10a/go-mailform"
+	"k8s.io/apimachinery/pkg/util/wait"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultTokenFileInterval is how often the token file is checked for a new token.
+const DefaultTokenFileInterval = 10 * time.Second
+
+var (
+	// ErrEmptyTokenFilePath is returned when no token file path is provided to NewTokenFileClient.
+	ErrEmptyTokenFilePath = errors.New("token file path cannot be empty")
+	// ErrEmptyToken is returned when the token file is empty.
+	ErrEmptyToken = errors.New("token file is empty")
+)
+
+// TokenFileClientConfig is the configuration used to create a TokenFileClient.
+type TokenFileClientConfig struct {
+	// Path is the file containing the API token, such as a mounted Secret key.
+	Path string
+	// Interval defaults to DefaultTokenFileInterval.
+	Interval time.Duration
+	// NewClient creates a client for a token. Defaults to a Mailform client.
+	NewClient func(token string) (Client, error)
+	// OnReload is optionally called after a new token is loaded.
+	OnReload func()
+}
+
+// tokenClient is a client and the token it was created with so they're swapped together.
+type tokenClient struct {
+	client Client
+	token  string
+}
+
+// TokenFileClient reads the API token from a file and replaces its client whenever the token changes,
+// so the token can be rotated without restarting the manager.
+// Requests use whichever client is current when they're made.
+type TokenFileClient struct {
+	path      string
+	interval  time.Duration
+	newClient func(token string) (Client, error)
+	onReload  func()
+	current   atomic.Pointer[tokenClient]
+}
+
+// NewTokenFileClient returns a new TokenFileClient using the token currently in the file.
+func NewTokenFileClient(c *TokenFileClientConfig) (*TokenFileClient, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	if c.Path == "" {
+		return nil, ErrEmptyTokenFilePath
+	}
+
+	t := &TokenFileClient{
+		path:      c.Path,
+		interval:  DefaultTokenFileInterval,
+		newClient: newMailformClient,
+		onReload:  c.OnReload,
+	}
+
+	if c.Interval > 0 {
+		t.interval = c.Interval
+	}
+
+	if c.NewClient != nil {
+		t.newClient = c.NewClient
+	}
+
+	_, err := t.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// newMailformClient returns a Mailform client for the token.
+func newMailformClient(token string) (Client, error) {
+	return mailform.New(&mailform.Config{Token: token})
+}
+
+// Start checks the token file every interval until the context is cancelled.
+// A token that can't be read is logged and the previous token is kept.
+func (t *TokenFileClient) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("token-file")
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		reloaded, err := t.Reload()
+		if err != nil {
+			log.Error(err, "unable to reload mailform API token, keeping the previous token", "path", t.path)
+			return
+		}
+
+		if reloaded {
+			log.Info("reloaded mailform API token", "path", t.path)
+		}
+	}, t.interval)
+
+	return nil
+}
+
+// NeedLeaderElection ensures every replica reloads its own token.
+func (t *TokenFileClient) NeedLeaderElection() bool {
+	return false
+}
+
+// Reload reads the token file and replaces the client if the token changed.
+// Returns true if the client was replaced.
+func (t *TokenFileClient) Reload() (bool, error) {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return false, err
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return false, ErrEmptyToken
+	}
+
+	previous := t.current.Load()
+	if previous != nil && previous.token == token {
+		return false, nil
+	}
+
+	client, err := t.newClient(token)
+	if err != nil {
+		return false, err
+	}
+
+	t.current.Store(&tokenClient{client: client, token: token})
+
+	// The first token is loaded by the constructor, not reloaded
+	if previous != nil && t.onReload != nil {
+		t.onReload()
+	}
+
+	return true, nil
+}
+
+// Token returns the current token.
+func (t *TokenFileClient) Token() string {
+	return t.current.Load().token
+}
+
+// CreateOrder creates an order with the current client.
+func (t *TokenFileClient) CreateOrder(o mailform.OrderInput) (*mailform.Order, error) {
+	return t.current.Load().client.CreateOrder(o)
+}
+
+// GetOrder fetches an order with the current client.
+func (t *TokenFileClient) GetOrder(o string) (*mailform.Order, error) {
+	return t.current.Load().client.GetOrder(o)
+}
+
+// CancelOrder cancels an order with the current client.
+func (t *TokenFileClient) CancelOrder(o string) error {
+	return t.current.Load().client.CancelOrder(o)
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	mailform "github.com/circa10a/go-mailform"
+)
+
+// tokenRecordingClient remembers the token it was created with.
+type tokenRecordingClient struct {
+	mockClient
+	token string
+}
+
+// GetOrder returns an order with the client's token as the ID
+func (m *tokenRecordingClient) GetOrder(o string) (*mailform.Order, error) {
+	order := &mailform.Order{}
+	order.Data.ID = m.token
+	return order, nil
+}
+
+var _ = Describe("TokenFileClient", func() {
+	var path string
+
+	newClient := func(token string) (Client, error) {
+		return &tokenRecordingClient{token: token}, nil
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(path, []byte("first-token\n"), 0o600)).To(Succeed())
+	})
+
+	It("should return errors for invalid config", func() {
+		_, err := NewTokenFileClient(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+
+		_, err = NewTokenFileClient(&TokenFileClientConfig{})
+		Expect(err).To(MatchError(ErrEmptyTokenFilePath))
+
+		_, err = NewTokenFileClient(&TokenFileClientConfig{Path: filepath.Join(path, "missing")})
+		Expect(err).To(HaveOccurred())
+
+		Expect(os.WriteFile(path, []byte(" \n"), 0o600)).To(Succeed())
+		_, err = NewTokenFileClient(&TokenFileClientConfig{Path: path})
+		Expect(err).To(MatchError(ErrEmptyToken))
+	})
+
+	It("should use the token from the file", func() {
+		client, err := NewTokenFileClient(&TokenFileClientConfig{Path: path, NewClient: newClient})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Token()).To(Equal("first-token"))
+
+		order, err := client.GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(order.Data.ID).To(Equal("first-token"))
+	})
+
+	It("should swap the client when the token changes", func() {
+		reloads := 0
+		client, err := NewTokenFileClient(&TokenFileClientConfig{
+			Path:      path,
+			NewClient: newClient,
+			OnReload:  func() { reloads++ },
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		reloaded, err := client.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeFalse())
+
+		Expect(os.WriteFile(path, []byte("second-token"), 0o600)).To(Succeed())
+		reloaded, err = client.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeTrue())
+		Expect(reloads).To(Equal(1))
+
+		order, err := client.GetOrder("order-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(order.Data.ID).To(Equal("second-token"))
+	})
+
+	It("should keep the previous token if the file can't be used", func() {
+		client, err := NewTokenFileClient(&TokenFileClientConfig{Path: path, NewClient: newClient})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(path, []byte(""), 0o600)).To(Succeed())
+		_, err = client.Reload()
+		Expect(err).To(MatchError(ErrEmptyToken))
+		Expect(client.Token()).To(Equal("first-token"))
+
+		Expect(os.Remove(path)).To(Succeed())
+		_, err = client.Reload()
+		Expect(err).To(HaveOccurred())
+		Expect(client.Token()).To(Equal("first-token"))
+	})
+
+	It("should reload the token when started", func() {
+		client, err := NewTokenFileClient(&TokenFileClientConfig{
+			Path:      path,
+			Interval:  10 * time.Millisecond,
+			NewClient: newClient,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(client.Start(ctx)).To(Succeed())
+		}()
+
+		Expect(os.WriteFile(path, []byte("second-token"), 0o600)).To(Succeed())
+		Eventually(client.Token).Should(Equal("second-token"))
+	})
+})
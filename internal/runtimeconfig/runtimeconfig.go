@@ -0,0 +1,277 @@
+package runtimeconfig
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/polling"
+)
+
+// DefaultName is the default name of the PostK8sConfig that is applied.
+const DefaultName = "postk8s"
+
+var (
+	// ErrNilConfig is returned when no config is provided to New.
+	ErrNilConfig = errors.New("config cannot be nil")
+	// ErrInvalidSyncInterval is returned when the sync interval isn't positive.
+	ErrInvalidSyncInterval = errors.New("sync interval must be greater than 0")
+	// ErrInvalidRateLimit is returned when the requests per second are negative or the burst isn't positive.
+	ErrInvalidRateLimit = errors.New("requests per second must be at least 0 and burst must be greater than 0")
+)
+
+// Settings are the settings the operator runs with.
+type Settings struct {
+	// SyncInterval is the longest time between checks of an order.
+	SyncInterval time.Duration
+	// MinSyncInterval is how often new orders are checked. Zero disables adaptive polling.
+	MinSyncInterval time.Duration
+	// SyncJitter is the fraction of each interval randomly added or removed.
+	SyncJitter float64
+	// PollPolicy is built from the polling settings. Nil when adaptive polling is disabled.
+	PollPolicy *polling.Policy
+
+	DeletionPolicy   mailformv1alpha1.DeletionPolicy
+	TTLAfterFinished time.Duration
+	OrderLostPolicy  mailformv1alpha1.OrderLostPolicy
+
+	Provider mailformv1alpha1.Provider
+
+	RequestsPerSecond float64
+	Burst             int
+
+	// Archive enables archiving finished orders if an archiver is configured.
+	Archive bool
+	// KillSwitch enables halting new orders with the kill switch.
+	KillSwitch bool
+}
+
+// Config is the configuration used to create a Store.
+type Config struct {
+	// Defaults are the settings from the manager's flags. They're used for anything a PostK8sConfig doesn't set.
+	Defaults Settings
+	// OnChange is optionally called with the new settings every time they're applied or reset.
+	OnChange func(Settings)
+}
+
+// Store holds the settings the operator is currently running with. It is safe for concurrent use.
+type Store struct {
+	mu       sync.RWMutex
+	defaults Settings
+	current  Settings
+	onChange func(Settings)
+}
+
+// New returns a new Store using the defaults until a PostK8sConfig is applied.
+func New(c *Config) (*Store, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	defaults, err := complete(c.Defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		defaults: defaults,
+		current:  defaults,
+		onChange: c.OnChange,
+	}, nil
+}
+
+// Current returns the settings the operator is currently running with.
+func (s *Store) Current() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// Apply replaces the current settings with the defaults overridden by the spec.
+// The current settings are kept if the result is invalid.
+func (s *Store) Apply(spec *mailformv1alpha1.PostK8sConfigSpec) (Settings, error) {
+	settings, err := complete(merge(s.defaults, spec))
+	if err != nil {
+		return s.Current(), err
+	}
+
+	s.set(settings)
+
+	return settings, nil
+}
+
+// Reset goes back to the default settings, e.g. after the PostK8sConfig is deleted.
+func (s *Store) Reset() Settings {
+	s.set(s.defaults)
+
+	return s.defaults
+}
+
+// set changes the current settings and notifies the listener.
+func (s *Store) set(settings Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = settings
+	if s.onChange != nil {
+		s.onChange(settings)
+	}
+}
+
+// merge returns the settings overridden by the fields set in the spec.
+func merge(settings Settings, spec *mailformv1alpha1.PostK8sConfigSpec) Settings {
+	if spec == nil {
+		return settings
+	}
+
+	if p := spec.Polling; p != nil {
+		if p.SyncIntervalSeconds != nil {
+			settings.SyncInterval = seconds(*p.SyncIntervalSeconds)
+		}
+		if p.MinSyncIntervalSeconds != nil {
+			settings.MinSyncInterval = seconds(*p.MinSyncIntervalSeconds)
+		}
+		if p.SyncJitterPercent != nil {
+			settings.SyncJitter = float64(*p.SyncJitterPercent) / 100
+		}
+	}
+
+	if d := spec.Deletion; d != nil {
+		if d.Policy != "" {
+			settings.DeletionPolicy = d.Policy
+		}
+		if d.TTLSecondsAfterFinished != nil {
+			settings.TTLAfterFinished = seconds(*d.TTLSecondsAfterFinished)
+		}
+		if d.OrderLostPolicy != "" {
+			settings.OrderLostPolicy = d.OrderLostPolicy
+		}
+	}
+
+	if p := spec.Provider; p != nil && p.Name != "" {
+		settings.Provider = p.Name
+	}
+
+	if r := spec.RateLimit; r != nil {
+		if r.RequestsPerSecond != nil {
+			settings.RequestsPerSecond = r.RequestsPerSecond.AsApproximateFloat64()
+		}
+		if r.Burst != nil {
+			settings.Burst = int(*r.Burst)
+		}
+	}
+
+	if f := spec.Features; f != nil {
+		if f.Archive != nil {
+			settings.Archive = *f.Archive
+		}
+		if f.KillSwitch != nil {
+			settings.KillSwitch = *f.KillSwitch
+		}
+	}
+
+	return settings
+}
+
+// complete validates the settings, fills in defaults and builds the poll policy.
+func complete(settings Settings) (Settings, error) {
+	if settings.SyncInterval <= 0 {
+		return settings, ErrInvalidSyncInterval
+	}
+
+	if settings.RequestsPerSecond < 0 || settings.Burst < 1 {
+		return settings, ErrInvalidRateLimit
+	}
+
+	if settings.DeletionPolicy == "" {
+		settings.DeletionPolicy = mailformv1alpha1.DeletionPolicyCancel
+	}
+
+	if settings.OrderLostPolicy == "" {
+		settings.OrderLostPolicy = mailformv1alpha1.OrderLostPolicyGiveUp
+	}
+
+	if settings.Provider == "" {
+		settings.Provider = mailformv1alpha1.ProviderMailform
+	}
+
+	switch settings.DeletionPolicy {
+	case mailformv1alpha1.DeletionPolicyCancel, mailformv1alpha1.DeletionPolicyOrphan:
+	default:
+		return settings, fmt.Errorf("unsupported deletion policy: %q", settings.DeletionPolicy)
+	}
+
+	switch settings.OrderLostPolicy {
+	case mailformv1alpha1.OrderLostPolicyGiveUp, mailformv1alpha1.OrderLostPolicyRecreate:
+	default:
+		return settings, fmt.Errorf("unsupported order lost policy: %q", settings.OrderLostPolicy)
+	}
+
+	if settings.Provider != mailformv1alpha1.ProviderMailform {
+		return settings, fmt.Errorf("unsupported provider: %q", settings.Provider)
+	}
+
+	// New orders can't be checked more often than every order already is, so adaptive polling is off
+	if settings.MinSyncInterval >= settings.SyncInterval {
+		settings.MinSyncInterval = 0
+	}
+
+	settings.PollPolicy = nil
+	if settings.MinSyncInterval > 0 {
+		policy, err := polling.New(&polling.Config{
+			MinInterval: settings.MinSyncInterval,
+			MaxInterval: settings.SyncInterval,
+			Jitter:      settings.SyncJitter,
+		})
+		if err != nil {
+			return settings, err
+		}
+		settings.PollPolicy = policy
+	}
+
+	return settings, nil
+}
+
+// ToSpec returns the settings as a PostK8sConfigSpec, e.g. to show the active settings in a status.
+func ToSpec(settings Settings) *mailformv1alpha1.PostK8sConfigSpec {
+	return &mailformv1alpha1.PostK8sConfigSpec{
+		Polling: &mailformv1alpha1.PollingConfig{
+			SyncIntervalSeconds:    ptr.To(toSeconds(settings.SyncInterval)),
+			MinSyncIntervalSeconds: ptr.To(toSeconds(settings.MinSyncInterval)),
+			SyncJitterPercent:      ptr.To(int32(math.Round(settings.SyncJitter * 100))),
+		},
+		Deletion: &mailformv1alpha1.DeletionConfig{
+			Policy:                  settings.DeletionPolicy,
+			TTLSecondsAfterFinished: ptr.To(toSeconds(settings.TTLAfterFinished)),
+			OrderLostPolicy:         settings.OrderLostPolicy,
+		},
+		Provider: &mailformv1alpha1.ProviderConfig{
+			Name: settings.Provider,
+		},
+		RateLimit: &mailformv1alpha1.RateLimitConfig{
+			RequestsPerSecond: resource.NewMilliQuantity(int64(math.Round(settings.RequestsPerSecond*1000)), resource.DecimalSI),
+			Burst:             ptr.To(int32(settings.Burst)),
+		},
+		Features: &mailformv1alpha1.FeatureConfig{
+			Archive:    ptr.To(settings.Archive),
+			KillSwitch: ptr.To(settings.KillSwitch),
+		},
+	}
+}
+
+// seconds converts seconds from a spec to a duration.
+func seconds(s int32) time.Duration {
+	return time.Duration(s) * time.Second
+}
+
+// toSeconds converts a duration to whole seconds for a spec.
+func toSeconds(d time.Duration) int32 {
+	return int32(d / time.Second)
+}
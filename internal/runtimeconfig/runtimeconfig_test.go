@@ -0,0 +1,177 @@
+package runtimeconfig
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	mailformv1alpha1 "github.com/circa10a/postk8s/api/v1alpha1"
+	"github.com/circa10a/postk8s/internal/polling"
+)
+
+// defaults returns settings like the manager's flag defaults.
+func defaults() Settings {
+	return Settings{
+		SyncInterval:      12 * time.Hour,
+		MinSyncInterval:   5 * time.Minute,
+		SyncJitter:        0.1,
+		RequestsPerSecond: 5,
+		Burst:             10,
+		Archive:           true,
+		KillSwitch:        true,
+	}
+}
+
+var _ = Describe("Store", func() {
+	It("should return an error without a config", func() {
+		_, err := New(nil)
+		Expect(err).To(MatchError(ErrNilConfig))
+	})
+
+	It("should reject invalid defaults", func() {
+		settings := defaults()
+		settings.SyncInterval = 0
+		_, err := New(&Config{Defaults: settings})
+		Expect(err).To(MatchError(ErrInvalidSyncInterval))
+	})
+
+	It("should complete the defaults", func() {
+		store, err := New(&Config{Defaults: defaults()})
+		Expect(err).NotTo(HaveOccurred())
+
+		current := store.Current()
+		Expect(current.DeletionPolicy).To(Equal(mailformv1alpha1.DeletionPolicyCancel))
+		Expect(current.OrderLostPolicy).To(Equal(mailformv1alpha1.OrderLostPolicyGiveUp))
+		Expect(current.Provider).To(Equal(mailformv1alpha1.ProviderMailform))
+		Expect(current.PollPolicy).NotTo(BeNil())
+	})
+
+	It("should not build a poll policy without a min sync interval", func() {
+		settings := defaults()
+		settings.MinSyncInterval = 0
+		store, err := New(&Config{Defaults: settings})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Current().PollPolicy).To(BeNil())
+	})
+
+	It("should turn off adaptive polling when the sync interval is below the min sync interval", func() {
+		settings := defaults()
+		settings.SyncInterval = time.Minute
+		store, err := New(&Config{Defaults: settings})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Current().SyncInterval).To(Equal(time.Minute))
+		Expect(store.Current().MinSyncInterval).To(BeZero())
+		Expect(store.Current().PollPolicy).To(BeNil())
+
+		settings, err = store.Apply(&mailformv1alpha1.PostK8sConfigSpec{
+			Polling: &mailformv1alpha1.PollingConfig{
+				SyncIntervalSeconds:    ptr.To(int32(60)),
+				MinSyncIntervalSeconds: ptr.To(int32(300)),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.SyncInterval).To(Equal(time.Minute))
+		Expect(settings.PollPolicy).To(BeNil())
+	})
+
+	It("should override the defaults with the fields set in the spec", func() {
+		var changed []Settings
+		store, err := New(&Config{
+			Defaults: defaults(),
+			OnChange: func(s Settings) { changed = append(changed, s) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		settings, err := store.Apply(&mailformv1alpha1.PostK8sConfigSpec{
+			Polling: &mailformv1alpha1.PollingConfig{
+				SyncIntervalSeconds: ptr.To(int32(3600)),
+			},
+			Deletion: &mailformv1alpha1.DeletionConfig{
+				Policy:          mailformv1alpha1.DeletionPolicyOrphan,
+				OrderLostPolicy: mailformv1alpha1.OrderLostPolicyRecreate,
+			},
+			RateLimit: &mailformv1alpha1.RateLimitConfig{
+				RequestsPerSecond: ptr.To(resource.MustParse("500m")),
+			},
+			Features: &mailformv1alpha1.FeatureConfig{
+				Archive: ptr.To(false),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.SyncInterval).To(Equal(time.Hour))
+		Expect(settings.MinSyncInterval).To(Equal(5 * time.Minute))
+		Expect(settings.DeletionPolicy).To(Equal(mailformv1alpha1.DeletionPolicyOrphan))
+		Expect(settings.OrderLostPolicy).To(Equal(mailformv1alpha1.OrderLostPolicyRecreate))
+		Expect(settings.RequestsPerSecond).To(Equal(0.5))
+		Expect(settings.Burst).To(Equal(10))
+		Expect(settings.Archive).To(BeFalse())
+		Expect(settings.KillSwitch).To(BeTrue())
+		Expect(store.Current()).To(Equal(settings))
+		Expect(changed).To(HaveLen(1))
+
+		// Fields removed from the spec go back to the defaults
+		settings, err = store.Apply(&mailformv1alpha1.PostK8sConfigSpec{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.SyncInterval).To(Equal(12 * time.Hour))
+		Expect(settings.Archive).To(BeTrue())
+		Expect(changed).To(HaveLen(2))
+	})
+
+	It("should keep the current settings if the spec is invalid", func() {
+		store, err := New(&Config{Defaults: defaults()})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.Apply(&mailformv1alpha1.PostK8sConfigSpec{
+			Polling: &mailformv1alpha1.PollingConfig{
+				SyncIntervalSeconds: ptr.To(int32(3600)),
+				SyncJitterPercent:   ptr.To(int32(150)),
+			},
+		})
+		Expect(err).To(MatchError(polling.ErrInvalidJitter))
+		Expect(store.Current().SyncInterval).To(Equal(12 * time.Hour))
+
+		_, err = store.Apply(&mailformv1alpha1.PostK8sConfigSpec{
+			RateLimit: &mailformv1alpha1.RateLimitConfig{
+				RequestsPerSecond: ptr.To(resource.MustParse("-1")),
+			},
+		})
+		Expect(err).To(MatchError(ErrInvalidRateLimit))
+	})
+
+	It("should reset to the defaults", func() {
+		store, err := New(&Config{Defaults: defaults()})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.Apply(&mailformv1alpha1.PostK8sConfigSpec{
+			Features: &mailformv1alpha1.FeatureConfig{KillSwitch: ptr.To(false)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Current().KillSwitch).To(BeFalse())
+
+		Expect(store.Reset().KillSwitch).To(BeTrue())
+		Expect(store.Current().KillSwitch).To(BeTrue())
+	})
+
+	It("should convert the settings to a spec", func() {
+		store, err := New(&Config{Defaults: defaults()})
+		Expect(err).NotTo(HaveOccurred())
+
+		spec := ToSpec(store.Current())
+		Expect(*spec.Polling.SyncIntervalSeconds).To(Equal(int32(43200)))
+		Expect(*spec.Polling.MinSyncIntervalSeconds).To(Equal(int32(300)))
+		Expect(*spec.Polling.SyncJitterPercent).To(Equal(int32(10)))
+		Expect(spec.Deletion.Policy).To(Equal(mailformv1alpha1.DeletionPolicyCancel))
+		Expect(spec.RateLimit.RequestsPerSecond.String()).To(Equal("5"))
+		Expect(*spec.RateLimit.Burst).To(Equal(int32(10)))
+		Expect(*spec.Features.Archive).To(BeTrue())
+
+		// The spec applies back to the same settings
+		settings, err := store.Apply(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.SyncInterval).To(Equal(12 * time.Hour))
+		Expect(settings.RequestsPerSecond).To(Equal(5.0))
+	})
+})